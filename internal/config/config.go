@@ -41,6 +41,77 @@ type Config struct {
     AIYOUEmail       string        `yaml:"aiyou_email"`
     AIYOUPassword    string        `yaml:"aiyou_password"`
     Storage          StorageConfig `yaml:"storage"`
+
+    Mode string `yaml:"mode"`
+
+    Cache          bool   `yaml:"cache"`
+    CacheDirectory string `yaml:"cache_directory"`
+    CacheTTLHours  int    `yaml:"cache_ttl_hours"`
+
+    MergeStrategy  string `yaml:"merge_strategy"`
+    MergeMaxTokens int    `yaml:"merge_max_tokens"`
+
+    Pricing     map[string]ModelPricing `yaml:"pricing"`
+    Currency    string                  `yaml:"currency"`
+    Budget      float64                 `yaml:"budget"`
+    DryRun      bool                    `yaml:"-"` // set by enrich --dry-run: plan the run without any LLM call
+    Resume      string                  `yaml:"-"` // set by enrich --resume: run ID of the interrupted run to resume
+    RetryFailed bool                    `yaml:"-"` // set by enrich --retry-failed: re-run only the segments that failed in the previous run
+
+    OnSegmentError string  `yaml:"on_segment_error"` // fail, skip or retry-N
+    MinCoverage    float64 `yaml:"min_coverage"`     // minimum share of processed segments (0-1) for the run to succeed
+
+    TimeoutSeconds        int `yaml:"timeout_seconds"`         // deadline of an enrich run, 0 for none
+    RequestTimeoutSeconds int `yaml:"request_timeout_seconds"` // deadline of each LLM request including its retries, 0 for none
+
+    MaxRetries                    int                  `yaml:"max_retries"`                      // retries of an LLM request failing with a transient error
+    RetryMaxDelaySeconds          int                  `yaml:"retry_max_delay_seconds"`          // longest backoff between retries, unless the provider sends Retry-After
+    RateLimits                    map[string]RateLimit `yaml:"rate_limits"`                      // limits of each provider, shared by all threads
+    CircuitBreakerFailures        int                  `yaml:"circuit_breaker_failures"`         // consecutive failures after which requests to a provider fail fast, 0 to disable
+    CircuitBreakerCooldownSeconds int                  `yaml:"circuit_breaker_cooldown_seconds"` // delay before a request is let through an open circuit again
+
+    Fallback []string      `yaml:"fallback"` // provider:model links tried in order when the selected LLM fails
+    Routing  []RoutingRule `yaml:"routing"`  // rules sending some requests to another provider:model first
+
+    StructuredOutput        bool `yaml:"structured_output"`
+    StructuredOutputRetries int  `yaml:"structured_output_retries"`
+
+    OutputFormat string `yaml:"output_format"`
+    Database     string `yaml:"database"`
+    Incremental  bool   `yaml:"incremental"`
+    Language     string `yaml:"language"`
+
+    EntityResolution    bool    `yaml:"entity_resolution"`
+    ResolutionThreshold float64 `yaml:"resolution_threshold"`
+
+    SchemaEnforcement bool `yaml:"schema_enforcement"`
+
+    EmbeddingProvider     string  `yaml:"embedding_provider"`
+    EmbeddingModel        string  `yaml:"embedding_model"`
+    EmbeddingAPIURL       string  `yaml:"embedding_api_url"`
+    OllamaEmbeddingAPIURL string  `yaml:"ollama_embedding_api_url"`
+    ClusterThreshold      float64 `yaml:"cluster_threshold"`
+}
+
+// ModelPricing is the price of a model per million tokens
+type ModelPricing struct {
+    Input  float64 `yaml:"input"`
+    Output float64 `yaml:"output"`
+}
+
+// RateLimit is the maximum number of requests and tokens (prompt and output) per minute sent to a provider, 0 for no limit
+type RateLimit struct {
+    RequestsPerMinute int `yaml:"requests_per_minute"`
+    TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+// RoutingRule sends the requests of a task, up to a size, to another model. The first matching rule
+// applies; the selected LLM and the fallback chain are tried next when that model fails.
+type RoutingRule struct {
+    Task      string `yaml:"task"`       // segment or merge, empty for both
+    MaxTokens int    `yaml:"max_tokens"` // largest request matched, in approximate tokens of the segment or merged results; 0 for any size
+    LLM       string `yaml:"llm"`
+    Model     string `yaml:"model"`
 }
 
 // StorageConfig contient la configuration pour le stockage
@@ -78,6 +149,36 @@ func GetConfig() *Config {
             ContextWords:     30,
             AIYOUAssistantID: "asst_q2YbeHKeSxBzNr43KhIESkqj",
             AIYOUAPIURL:      "https://ai.dragonflygroup.fr/api",
+            Mode:             "single",
+            Cache:            true,
+            CacheDirectory:   ".ontology_cache",
+            CacheTTLHours:    720,
+            MergeStrategy:    "db",
+            MergeMaxTokens:   8000,
+            Pricing: map[string]ModelPricing{
+                "claude-3-5-sonnet-20240620": {Input: 3, Output: 15},
+                "claude-3-opus-20240229":     {Input: 15, Output: 75},
+                "claude-3-haiku-20240307":    {Input: 0.25, Output: 1.25},
+                "gpt-4o":                     {Input: 2.5, Output: 10},
+                "gpt-4o-mini":                {Input: 0.15, Output: 0.6},
+            },
+            Currency:         "USD",
+            OnSegmentError:   "skip",
+            MaxRetries:       5,
+            RetryMaxDelaySeconds: 32,
+            RateLimits:       map[string]RateLimit{},
+            CircuitBreakerFailures: 5,
+            CircuitBreakerCooldownSeconds: 60,
+            StructuredOutputRetries: 2,
+            OutputFormat:     "tsv",
+            Language:         "auto",
+            EntityResolution: true,
+            ResolutionThreshold: 0.9,
+            SchemaEnforcement: true,
+            EmbeddingProvider: "ollama",
+            EmbeddingAPIURL:   "https://api.openai.com/v1/embeddings",
+            OllamaEmbeddingAPIURL: "http://localhost:11434/api/embed",
+            ClusterThreshold:  0.85,
             Storage: StorageConfig{
                 Type: "local",
                 LocalPath: ".",
@@ -144,7 +245,7 @@ func (c *Config) loadEnvVariables() {
 
 // ValidateConfig checks if the configuration is valid
 func (c *Config) ValidateConfig() error {
-    if c.OpenAIAPIKey == "" && c.ClaudeAPIKey == "" {
+    if c.OpenAIAPIKey == "" && c.ClaudeAPIKey == "" && !c.DryRun {
         return fmt.Errorf(i18n.GetMessage("ErrNoAPIKeys"))
     }
     if c.Storage.Type != "local" && c.Storage.Type != "s3" {
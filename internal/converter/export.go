@@ -0,0 +1,241 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+)
+
+// Vocabulary IRIs used by the exporters
+const (
+	RDFNamespace  = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	RDFSNamespace = "http://www.w3.org/2000/01/rdf-schema#"
+	OWLNamespace  = "http://www.w3.org/2002/07/owl#"
+	XSDNamespace  = "http://www.w3.org/2001/XMLSchema#"
+	SKOSNamespace = "http://www.w3.org/2004/02/skos/core#"
+
+	// Annotation properties minted under the base URI
+	PositionProperty   = "position"
+	WeightProperty     = "weight"
+	DirectionProperty  = "direction"
+	ProvenanceProperty = "provenance"
+	SourceProperty     = "sourceFile"
+	MentionProperty    = "mention"
+)
+
+// Exporter serializes a computed ontology into an output format
+type Exporter interface {
+	Export(ontology *model.Ontology) ([]byte, error)
+	// Extension returns the file extension, dot included, conventionally used for the format
+	Extension() string
+}
+
+// ExporterFactory creates an Exporter minting IRIs under the given base URI
+type ExporterFactory func(baseURI string) Exporter
+
+// exporters stores the factory of each supported output format
+var exporters = make(map[string]ExporterFactory)
+
+// RegisterExporter registers a new exporter for the given output format
+func RegisterExporter(format string, factory ExporterFactory) {
+	exporters[format] = factory
+}
+
+// GetExporter returns the exporter for the given output format
+func GetExporter(format string, baseURI string) (Exporter, error) {
+	factory, ok := exporters[strings.ToLower(strings.TrimPrefix(format, "."))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+	return factory(baseURI), nil
+}
+
+// SupportedExportFormats returns the registered output formats, sorted
+func SupportedExportFormats() []string {
+	var formats []string
+	for format := range exporters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// NormalizeBaseURI makes sure the base URI ends with a separator so that local names can be appended
+func NormalizeBaseURI(baseURI string) string {
+	if baseURI == "" {
+		baseURI = "http://example.org/ontology/"
+	}
+	if !strings.HasSuffix(baseURI, "/") && !strings.HasSuffix(baseURI, "#") {
+		baseURI += "/"
+	}
+	return baseURI
+}
+
+// MintIRI builds the IRI of an ontology name under the base URI
+func MintIRI(baseURI, name string) string {
+	return NormalizeBaseURI(baseURI) + LocalName(name)
+}
+
+// LocalName turns an ontology name into a local name usable in IRIs and XML qualified names
+func LocalName(name string) string {
+	var builder strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	local := builder.String()
+	if local == "" {
+		return "_"
+	}
+	if first := []rune(local)[0]; !unicode.IsLetter(first) && first != '_' {
+		local = "_" + local
+	}
+	return local
+}
+
+// graphBuilder accumulates the triples describing an ontology
+type graphBuilder struct {
+	baseURI string
+	triples []rdf.Triple
+	err     error
+}
+
+func (g *graphBuilder) iri(value string) rdf.IRI {
+	iri, err := rdf.NewIRI(value)
+	if err != nil && g.err == nil {
+		g.err = fmt.Errorf("invalid IRI %q: %w", value, err)
+	}
+	return iri
+}
+
+func (g *graphBuilder) add(subj rdf.Subject, pred string, obj rdf.Object) {
+	g.triples = append(g.triples, rdf.Triple{Subj: subj, Pred: g.iri(pred), Obj: obj})
+}
+
+func (g *graphBuilder) addLiteral(subj rdf.Subject, pred string, value string) {
+	if value == "" {
+		return
+	}
+	g.add(subj, pred, rdf.NewTypedLiteral(value, g.iri(XSDNamespace+"string")))
+}
+
+func (g *graphBuilder) addInteger(subj rdf.Subject, pred string, value int) {
+	g.add(subj, pred, rdf.NewTypedLiteral(strconv.Itoa(value), g.iri(XSDNamespace+"integer")))
+}
+
+// addProvenance annotates a resource with its source file IDs and its fileID:segment:pass provenance entries
+func (g *graphBuilder) addProvenance(subj rdf.Subject, provenance []model.Provenance) {
+	for _, fileID := range model.ProvenanceFileIDs(provenance) {
+		g.addLiteral(subj, g.baseURI+SourceProperty, fileID)
+	}
+	for _, entry := range provenance {
+		g.addLiteral(subj, g.baseURI+ProvenanceProperty, entry.String())
+	}
+}
+
+// BuildGraph converts the ontology into RDF triples.
+// Element types become owl:Class, elements owl:NamedIndividual, relation types owl:ObjectProperty;
+// relation descriptions, weights, directions and provenance are attached to an owl:Axiom;
+// positions, mentions, source files and provenance of elements are annotations.
+func BuildGraph(ontology *model.Ontology, baseURI string) ([]rdf.Triple, error) {
+	baseURI = NormalizeBaseURI(baseURI)
+	g := &graphBuilder{baseURI: baseURI}
+
+	rdfType := RDFNamespace + "type"
+	label := RDFSNamespace + "label"
+	comment := RDFSNamespace + "comment"
+
+	ontologyIRI := g.iri(strings.TrimRight(baseURI, "/#"))
+	g.add(ontologyIRI, rdfType, g.iri(OWLNamespace+"Ontology"))
+
+	for _, property := range []string{PositionProperty, WeightProperty, DirectionProperty, ProvenanceProperty, SourceProperty, MentionProperty} {
+		g.add(g.iri(baseURI+property), rdfType, g.iri(OWLNamespace+"AnnotationProperty"))
+	}
+
+	declaredClasses := make(map[string]bool)
+	for _, element := range ontology.Elements {
+		if element.Type == "" || declaredClasses[element.Type] {
+			continue
+		}
+		declaredClasses[element.Type] = true
+		class := g.iri(MintIRI(baseURI, element.Type))
+		g.add(class, rdfType, g.iri(OWLNamespace+"Class"))
+		g.addLiteral(class, label, strings.ReplaceAll(element.Type, "_", " "))
+	}
+
+	for _, element := range ontology.Elements {
+		individual := g.iri(MintIRI(baseURI, element.Name))
+		g.add(individual, rdfType, g.iri(OWLNamespace+"NamedIndividual"))
+		if element.Type != "" {
+			g.add(individual, rdfType, g.iri(MintIRI(baseURI, element.Type)))
+		}
+		g.addLiteral(individual, label, strings.ReplaceAll(element.Name, "_", " "))
+		g.addLiteral(individual, comment, element.Description)
+		for _, alias := range element.Aliases {
+			g.addLiteral(individual, SKOSNamespace+"altLabel", alias)
+		}
+		for _, position := range element.Positions {
+			g.addInteger(individual, baseURI+PositionProperty, position)
+		}
+		for _, mention := range element.Mentions {
+			g.addLiteral(individual, baseURI+MentionProperty, mention.String())
+		}
+		g.addProvenance(individual, element.Provenance)
+	}
+
+	declaredProperties := make(map[string]bool)
+	for i, relation := range ontology.Relations {
+		property := MintIRI(baseURI, relation.Type)
+		if !declaredProperties[relation.Type] {
+			declaredProperties[relation.Type] = true
+			g.add(g.iri(property), rdfType, g.iri(OWLNamespace+"ObjectProperty"))
+			g.addLiteral(g.iri(property), label, strings.ReplaceAll(relation.Type, "_", " "))
+		}
+
+		source := g.iri(MintIRI(baseURI, relation.Source))
+		target := g.iri(MintIRI(baseURI, relation.Target))
+		g.add(source, property, target)
+
+		axiom, err := rdf.NewBlank(fmt.Sprintf("axiom%d", i+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create blank node: %w", err)
+		}
+		g.add(axiom, rdfType, g.iri(OWLNamespace+"Axiom"))
+		g.add(axiom, OWLNamespace+"annotatedSource", source)
+		g.add(axiom, OWLNamespace+"annotatedProperty", g.iri(property))
+		g.add(axiom, OWLNamespace+"annotatedTarget", target)
+		g.addLiteral(axiom, comment, relation.Description)
+		g.addInteger(axiom, baseURI+WeightProperty, relation.Weight)
+		if relation.Direction.Valid {
+			g.addLiteral(axiom, baseURI+DirectionProperty, relation.Direction.String)
+		}
+		g.addProvenance(axiom, relation.Provenance)
+	}
+
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.triples, nil
+}
+
+// groupBySubject returns the subjects in first-seen order along with their triples
+func groupBySubject(triples []rdf.Triple) ([]rdf.Subject, map[string][]rdf.Triple) {
+	var subjects []rdf.Subject
+	grouped := make(map[string][]rdf.Triple)
+	for _, triple := range triples {
+		key := triple.Subj.Serialize(rdf.NTriples)
+		if _, ok := grouped[key]; !ok {
+			subjects = append(subjects, triple.Subj)
+		}
+		grouped[key] = append(grouped[key], triple)
+	}
+	return subjects, grouped
+}
@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBaseURI = "http://example.org/onto/"
+
+func newTestOntology() *model.Ontology {
+	ontology := model.NewOntology()
+	ontology.AddElement(&model.OntologyElement{Name: "Conseil_d'État", Type: "Institution", Description: `Juridiction "suprême"`, Positions: []int{3, 10}, Aliases: []string{"CE"},
+		Provenance: []model.Provenance{{FileID: "a1b2c3d4e5f6", Segment: 0, Pass: 1}, {FileID: "a1b2c3d4e5f6", Segment: 2, Pass: 1}}})
+	ontology.AddElement(&model.OntologyElement{Name: "Loi", Type: "Norme",
+		Mentions: []model.Mention{{FileID: "0f9e8d7c6b5a", Start: 120, End: 123, Paragraph: 4}, {FileID: "a1b2c3d4e5f6", Start: 8, End: 11, Page: 2}}})
+	ontology.AddRelation(&model.Relation{
+		Source:      "Conseil_d'État",
+		Type:        "interprète",
+		Target:      "Loi",
+		Description: "interprétation",
+		Weight:      3,
+		Direction:   sql.NullString{String: "forward", Valid: true},
+		Provenance:  []model.Provenance{{FileID: "0f9e8d7c6b5a", Segment: 1, Pass: 2}},
+	})
+	return ontology
+}
+
+func TestTurtleExporterRoundTrip(t *testing.T) {
+	output, err := NewTurtleExporter(testBaseURI).Export(newTestOntology())
+	assert.NoError(t, err)
+
+	triples, err := rdf.NewTripleDecoder(bytes.NewReader(output), rdf.Turtle).DecodeAll()
+	assert.NoError(t, err)
+
+	expected, err := BuildGraph(newTestOntology(), testBaseURI)
+	assert.NoError(t, err)
+	assert.Len(t, triples, len(expected))
+
+	individual := MintIRI(testBaseURI, "Conseil_d'État")
+	assert.Equal(t, testBaseURI+"Conseil_d_État", individual)
+	assert.Contains(t, string(output), "onto:interprète")
+
+	var found bool
+	for _, triple := range triples {
+		if triple.Subj.String() == individual && triple.Pred.String() == testBaseURI+"interprète" {
+			found = triple.Obj.String() == testBaseURI+"Loi"
+		}
+	}
+	assert.True(t, found, "relation triple should survive the round trip")
+}
+
+func TestOWLExporterIsWellFormedXML(t *testing.T) {
+	output, err := NewOWLExporter(testBaseURI).Export(newTestOntology())
+	assert.NoError(t, err)
+
+	decoder := xml.NewDecoder(bytes.NewReader(output))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			assert.Equal(t, "EOF", err.Error())
+			break
+		}
+	}
+	assert.Contains(t, string(output), `<owl:annotatedTarget rdf:resource="http://example.org/onto/Loi"/>`)
+	assert.Contains(t, string(output), `<onto:weight rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">3</onto:weight>`)
+}
+
+func TestJSONLDExporter(t *testing.T) {
+	output, err := NewJSONLDExporter(testBaseURI).Export(newTestOntology())
+	assert.NoError(t, err)
+
+	var document struct {
+		Graph []map[string]interface{} `json:"@graph"`
+	}
+	assert.NoError(t, json.Unmarshal(output, &document))
+
+	var loi map[string]interface{}
+	for _, node := range document.Graph {
+		if node["@id"] == testBaseURI+"Loi" {
+			loi = node
+		}
+	}
+	assert.NotNil(t, loi)
+	assert.ElementsMatch(t, []interface{}{OWLNamespace + "NamedIndividual", testBaseURI + "Norme"}, loi["@type"])
+}
+
+func TestTSVExporter(t *testing.T) {
+	output, err := NewTSVExporter(testBaseURI).Export(newTestOntology())
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	assert.Equal(t, []string{
+		"Conseil_d'État\tInstitution\tJuridiction \"suprême\"\t3,10\ta1b2c3d4e5f6:0:1,a1b2c3d4e5f6:2:1",
+		"CE\talias\tConseil_d'État",
+		"Loi\tNorme\t\t0f9e8d7c6b5a:120-123:§4,a1b2c3d4e5f6:8-11:p2",
+		"Conseil_d'État\tinterprète:3\tLoi\tinterprétation\t0f9e8d7c6b5a:1:2",
+	}, lines)
+}
+
+func TestGetExporter(t *testing.T) {
+	for _, format := range []string{"tsv", "ttl", "owl", "jsonld", ".TTL"} {
+		_, err := GetExporter(format, testBaseURI)
+		assert.NoError(t, err, format)
+	}
+	_, err := GetExporter("csv", testBaseURI)
+	assert.Error(t, err)
+}
@@ -0,0 +1,429 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+)
+
+// Import formats understood by ImportOntology
+const (
+	ImportFormatTSV      = "tsv"
+	ImportFormatTurtle   = "ttl"
+	ImportFormatNTriples = "nt"
+	ImportFormatRDFXML   = "rdfxml"
+	ImportFormatJSONLD   = "jsonld"
+)
+
+// DetectImportFormat guesses the serialization of an existing ontology from its extension, then from its content
+func DetectImportFormat(path string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttl", ".turtle":
+		return ImportFormatTurtle
+	case ".nt":
+		return ImportFormatNTriples
+	case ".owl", ".rdf", ".xml":
+		return ImportFormatRDFXML
+	case ".jsonld", ".json":
+		return ImportFormatJSONLD
+	case ".tsv", ".txt":
+		return ImportFormatTSV
+	}
+
+	trimmed := bytes.TrimSpace(content)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")):
+		return ImportFormatJSONLD
+	case bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.Contains(trimmed, []byte("<rdf:RDF")):
+		return ImportFormatRDFXML
+	case bytes.HasPrefix(trimmed, []byte("@prefix")) || bytes.HasPrefix(trimmed, []byte("@base")) || bytes.HasPrefix(bytes.ToUpper(trimmed), []byte("PREFIX")):
+		return ImportFormatTurtle
+	}
+	return ImportFormatTSV
+}
+
+// ImportOntology parses an existing ontology into a model.Ontology holding its individuals and relations,
+// and a Vocabulary holding the class and property names the enrichment should reuse
+func ImportOntology(content []byte, format string) (*model.Ontology, *model.Vocabulary, error) {
+	log.Debug("Importing ontology in %s format, %d bytes", format, len(content))
+
+	var triples []rdf.Triple
+	var err error
+	switch strings.ToLower(format) {
+	case ImportFormatTSV:
+		ontology, vocabulary := importTSV(string(content))
+		return ontology, vocabulary, nil
+	case ImportFormatTurtle:
+		triples, err = rdf.NewTripleDecoder(bytes.NewReader(content), rdf.Turtle).DecodeAll()
+	case ImportFormatNTriples:
+		triples, err = rdf.NewTripleDecoder(bytes.NewReader(content), rdf.NTriples).DecodeAll()
+	case ImportFormatRDFXML, "owl", "rdf":
+		triples, err = rdf.NewTripleDecoder(bytes.NewReader(content), rdf.RDFXML).DecodeAll()
+	case ImportFormatJSONLD:
+		triples, err = decodeJSONLD(content)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import format: %s", format)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode %s ontology: %w", format, err)
+	}
+	log.Debug("Decoded %d triples", len(triples))
+
+	ontology, vocabulary := ontologyFromTriples(triples)
+	log.Debug("Imported %d elements, %d relations, %d classes, %d properties",
+		len(ontology.Elements), len(ontology.Relations), len(vocabulary.Classes), len(vocabulary.Properties))
+	return ontology, vocabulary, nil
+}
+
+// importTSV reads the project's own TSV format (see TSVExporter)
+func importTSV(content string) (*model.Ontology, *model.Vocabulary) {
+	ontology := model.NewOntology()
+	vocabulary := model.NewVocabulary()
+	var aliasLines [][]string
+
+	for _, line := range strings.Split(content, "\n") {
+		parts := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(parts) < 3 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+
+		if parts[1] == model.AliasType {
+			aliasLines = append(aliasLines, parts)
+			continue
+		}
+
+		if idx := strings.LastIndex(parts[1], ":"); idx != -1 {
+			if weight, err := strconv.Atoi(parts[1][idx+1:]); err == nil {
+				relationType := parts[1][:idx]
+				relation := &model.Relation{
+					Source: parts[0],
+					Type:   relationType,
+					Target: parts[2],
+					Weight: weight,
+				}
+				if len(parts) > 3 {
+					relation.Description = parts[3]
+				}
+				if len(parts) > 4 {
+					relation.Provenance = model.ParseProvenanceList(parts[4])
+				}
+				ontology.AddRelation(relation)
+				vocabulary.AddProperty(model.VocabularyTerm{Name: relationType})
+				continue
+			}
+		}
+
+		if ontology.GetElementByName(parts[0]) != nil {
+			continue
+		}
+		element := model.NewOntologyElement(parts[0], parts[1])
+		element.Description = parts[2]
+		if len(parts) > 3 {
+			if strings.Contains(parts[3], ":") {
+				element.Mentions = model.ParseMentionList(parts[3])
+			} else {
+				element.SetPositions(parsePositions(parts[3]))
+			}
+		}
+		if len(parts) > 4 {
+			element.Provenance = model.ParseProvenanceList(parts[4])
+			element.Source = strings.Join(model.ProvenanceFileIDs(element.Provenance), ",")
+		}
+		ontology.AddElement(element)
+		vocabulary.AddClass(model.VocabularyTerm{Name: parts[1]})
+	}
+
+	// Alias lines may precede the line of their element
+	for _, parts := range aliasLines {
+		if element := ontology.GetElementByName(parts[2]); element != nil {
+			element.AddAlias(parts[0])
+		}
+	}
+	return ontology, vocabulary
+}
+
+func parsePositions(field string) []int {
+	positions := []int{}
+	for _, value := range strings.Split(field, ",") {
+		if position, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// resourceDescription gathers what the graph says about one subject
+type resourceDescription struct {
+	term       rdf.Term
+	types      []string
+	label      string
+	comment    string
+	parent     string
+	positions  []int
+	mentions   []model.Mention
+	aliases    []string
+	provenance []model.Provenance
+}
+
+// ontologyFromTriples maps an RDF graph onto the project's model:
+// owl:Class and rdfs:Class become vocabulary classes, object properties vocabulary properties,
+// typed individuals ontology elements and the triples linking two individuals relations.
+// owl:Axiom annotations written by the exporters restore relation descriptions, weights, directions and provenance.
+func ontologyFromTriples(triples []rdf.Triple) (*model.Ontology, *model.Vocabulary) {
+	subjects, grouped := groupBySubject(triples)
+
+	resources := make(map[string]*resourceDescription)
+	describe := func(term rdf.Term) *resourceDescription {
+		key := term.Serialize(rdf.NTriples)
+		if resources[key] == nil {
+			resources[key] = &resourceDescription{term: term}
+		}
+		return resources[key]
+	}
+
+	classes := make(map[string]bool)
+	properties := make(map[string]bool)
+	for _, subject := range subjects {
+		resource := describe(subject)
+		for _, triple := range grouped[subject.Serialize(rdf.NTriples)] {
+			predicate := triple.Pred.String()
+			switch {
+			case predicate == RDFNamespace+"type":
+				resource.types = append(resource.types, triple.Obj.String())
+				if !isBuiltinIRI(triple.Obj.String()) {
+					classes[triple.Obj.Serialize(rdf.NTriples)] = true
+				}
+			case isLabelPredicate(predicate):
+				if resource.label == "" {
+					resource.label = triple.Obj.String()
+				}
+			case predicate == SKOSNamespace+"altLabel":
+				resource.aliases = append(resource.aliases, triple.Obj.String())
+			case isCommentPredicate(predicate):
+				if resource.comment == "" {
+					resource.comment = triple.Obj.String()
+				}
+			case predicate == RDFSNamespace+"subClassOf" || predicate == RDFSNamespace+"subPropertyOf":
+				if triple.Obj.Type() == rdf.TermIRI {
+					resource.parent = triple.Obj.String()
+					if predicate == RDFSNamespace+"subClassOf" {
+						classes[triple.Obj.Serialize(rdf.NTriples)] = true
+					}
+				}
+			case localNameOf(predicate) == PositionProperty:
+				if position, err := strconv.Atoi(triple.Obj.String()); err == nil {
+					resource.positions = append(resource.positions, position)
+				}
+			case localNameOf(predicate) == MentionProperty:
+				if mention, err := model.ParseMention(triple.Obj.String()); err == nil {
+					resource.mentions = append(resource.mentions, mention)
+				}
+			case localNameOf(predicate) == ProvenanceProperty:
+				if entry, err := model.ParseProvenance(triple.Obj.String()); err == nil {
+					resource.provenance = append(resource.provenance, entry)
+				}
+			}
+		}
+		for _, t := range resource.types {
+			switch t {
+			case OWLNamespace + "Class", RDFSNamespace + "Class":
+				classes[subject.Serialize(rdf.NTriples)] = true
+			case OWLNamespace + "ObjectProperty", RDFNamespace + "Property", OWLNamespace + "TransitiveProperty",
+				OWLNamespace + "SymmetricProperty", OWLNamespace + "FunctionalProperty", OWLNamespace + "InverseFunctionalProperty":
+				properties[subject.Serialize(rdf.NTriples)] = true
+			}
+		}
+	}
+
+	nameOf := func(term rdf.Term) string {
+		if resource, ok := resources[term.Serialize(rdf.NTriples)]; ok && resource.label != "" {
+			return strings.Join(strings.Fields(resource.label), "_")
+		}
+		return localNameOf(term.String())
+	}
+	nameOfIRI := func(iri string) string {
+		term, err := rdf.NewIRI(iri)
+		if err != nil {
+			return localNameOf(iri)
+		}
+		return nameOf(term)
+	}
+
+	ontology := model.NewOntology()
+	vocabulary := model.NewVocabulary()
+
+	for _, subject := range subjects {
+		key := subject.Serialize(rdf.NTriples)
+		resource := resources[key]
+		switch {
+		case classes[key]:
+			term := model.VocabularyTerm{Name: nameOf(subject), Description: resource.comment}
+			if resource.parent != "" {
+				term.Parent = nameOfIRI(resource.parent)
+			}
+			vocabulary.AddClass(term)
+		case properties[key]:
+			term := model.VocabularyTerm{Name: nameOf(subject), Description: resource.comment}
+			if resource.parent != "" {
+				term.Parent = nameOfIRI(resource.parent)
+			}
+			vocabulary.AddProperty(term)
+		}
+	}
+
+	individuals := make(map[string]*model.OntologyElement)
+	for _, subject := range subjects {
+		key := subject.Serialize(rdf.NTriples)
+		resource := resources[key]
+		if classes[key] || properties[key] || !isIndividual(resource.types) {
+			continue
+		}
+		elementType := "Thing"
+		for _, t := range resource.types {
+			if !isBuiltinIRI(t) {
+				elementType = nameOfIRI(t)
+				break
+			}
+		}
+		element := model.NewOntologyElement(nameOf(subject), elementType)
+		element.Description = resource.comment
+		if len(resource.positions) > 0 {
+			element.SetPositions(resource.positions)
+		}
+		element.Mentions = resource.mentions
+		for _, alias := range resource.aliases {
+			element.AddAlias(alias)
+		}
+		if len(resource.provenance) > 0 {
+			element.Provenance = resource.provenance
+			element.Source = strings.Join(model.ProvenanceFileIDs(resource.provenance), ",")
+		}
+		ontology.AddElement(element)
+		individuals[key] = element
+		vocabulary.AddClass(model.VocabularyTerm{Name: elementType})
+	}
+
+	relations := make(map[string]*model.Relation)
+	for _, triple := range triples {
+		source := individuals[triple.Subj.Serialize(rdf.NTriples)]
+		target := individuals[triple.Obj.Serialize(rdf.NTriples)]
+		if source == nil || target == nil || isBuiltinIRI(triple.Pred.String()) {
+			continue
+		}
+		relation := &model.Relation{
+			Source: source.Name,
+			Type:   nameOf(triple.Pred),
+			Target: target.Name,
+			Weight: 1,
+		}
+		relation.Direction.String, relation.Direction.Valid = model.DirectionForward, true
+		ontology.AddRelation(relation)
+		relations[relationKey(triple.Subj, triple.Pred, triple.Obj)] = relation
+		vocabulary.AddProperty(model.VocabularyTerm{Name: relation.Type})
+	}
+
+	// Annotations portées par les owl:Axiom réifiant les relations
+	for _, subject := range subjects {
+		key := subject.Serialize(rdf.NTriples)
+		if !containsString(resources[key].types, OWLNamespace+"Axiom") {
+			continue
+		}
+		var source, property, target rdf.Term
+		for _, triple := range grouped[key] {
+			switch triple.Pred.String() {
+			case OWLNamespace + "annotatedSource":
+				source = triple.Obj
+			case OWLNamespace + "annotatedProperty":
+				property = triple.Obj
+			case OWLNamespace + "annotatedTarget":
+				target = triple.Obj
+			}
+		}
+		if source == nil || property == nil || target == nil {
+			continue
+		}
+		relation := relations[relationKey(source, property, target)]
+		if relation == nil {
+			continue
+		}
+		for _, triple := range grouped[key] {
+			switch {
+			case isCommentPredicate(triple.Pred.String()):
+				relation.Description = triple.Obj.String()
+			case localNameOf(triple.Pred.String()) == WeightProperty:
+				if weight, err := strconv.Atoi(triple.Obj.String()); err == nil {
+					relation.Weight = weight
+				}
+			case localNameOf(triple.Pred.String()) == DirectionProperty:
+				relation.Direction.String = triple.Obj.String()
+			}
+		}
+		if axiom := resources[key]; len(axiom.provenance) > 0 {
+			relation.Provenance = axiom.provenance
+		}
+	}
+
+	return ontology, vocabulary
+}
+
+func relationKey(source, property, target rdf.Term) string {
+	return source.Serialize(rdf.NTriples) + " " + property.Serialize(rdf.NTriples) + " " + target.Serialize(rdf.NTriples)
+}
+
+// isIndividual reports whether the rdf:type values describe an individual rather than a schema resource
+func isIndividual(types []string) bool {
+	for _, t := range types {
+		if t == OWLNamespace+"NamedIndividual" || !isBuiltinIRI(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBuiltinIRI reports whether the IRI belongs to the RDF, RDFS, OWL or XSD vocabularies
+func isBuiltinIRI(iri string) bool {
+	for _, namespace := range []string{RDFNamespace, RDFSNamespace, OWLNamespace, XSDNamespace} {
+		if strings.HasPrefix(iri, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLabelPredicate(predicate string) bool {
+	return predicate == RDFSNamespace+"label" || predicate == SKOSNamespace+"prefLabel"
+}
+
+func isCommentPredicate(predicate string) bool {
+	switch predicate {
+	case RDFSNamespace + "comment", SKOSNamespace + "definition",
+		"http://purl.org/dc/terms/description", "http://purl.org/dc/elements/1.1/description":
+		return true
+	}
+	return false
+}
+
+// localNameOf returns the part of an IRI after its last '#' or '/'
+func localNameOf(iri string) string {
+	if idx := strings.LastIndexAny(iri, "#/"); idx != -1 && idx < len(iri)-1 {
+		return iri[idx+1:]
+	}
+	return iri
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
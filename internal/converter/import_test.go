@@ -0,0 +1,103 @@
+package converter
+
+import (
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportOntologyRoundTrip(t *testing.T) {
+	formats := map[string]string{"ttl": ImportFormatTurtle, "owl": ImportFormatRDFXML, "jsonld": ImportFormatJSONLD, "tsv": ImportFormatTSV}
+	for exportFormat, importFormat := range formats {
+		exporter, err := GetExporter(exportFormat, testBaseURI)
+		assert.NoError(t, err)
+		content, err := exporter.Export(newTestOntology())
+		assert.NoError(t, err)
+
+		ontology, vocabulary, err := ImportOntology(content, importFormat)
+		assert.NoError(t, err, exportFormat)
+		if err != nil {
+			continue
+		}
+
+		assert.Len(t, ontology.Elements, 2, exportFormat)
+		element := ontology.GetElementByName("Conseil_d'État")
+		if assert.NotNil(t, element, exportFormat) {
+			assert.Equal(t, "Institution", element.Type, exportFormat)
+			assert.Equal(t, `Juridiction "suprême"`, element.Description, exportFormat)
+			assert.ElementsMatch(t, []int{3, 10}, element.Positions, exportFormat)
+			assert.Equal(t, []string{"CE"}, element.Aliases, exportFormat)
+			assert.ElementsMatch(t, newTestOntology().Elements[0].Provenance, element.Provenance, exportFormat)
+			assert.Equal(t, "a1b2c3d4e5f6", element.Source, exportFormat)
+		}
+
+		if loi := ontology.GetElementByName("Loi"); assert.NotNil(t, loi, exportFormat) {
+			assert.ElementsMatch(t, newTestOntology().Elements[1].Mentions, loi.Mentions, exportFormat)
+		}
+
+		relation := ontology.GetRelation("Conseil_d'État", "interprète", "Loi")
+		if assert.NotNil(t, relation, exportFormat) {
+			assert.Equal(t, 3, relation.Weight, exportFormat)
+			assert.Equal(t, "interprétation", relation.Description, exportFormat)
+			assert.Equal(t, []model.Provenance{{FileID: "0f9e8d7c6b5a", Segment: 1, Pass: 2}}, relation.Provenance, exportFormat)
+			if exportFormat != "tsv" {
+				assert.Equal(t, "forward", relation.Direction.String, exportFormat)
+			}
+		}
+
+		assert.ElementsMatch(t, []string{"Institution", "Norme"}, termNames(vocabulary.Classes), exportFormat)
+		assert.Equal(t, []string{"interprète"}, termNames(vocabulary.Properties), exportFormat)
+	}
+}
+
+func TestImportSchemaOnlyTurtle(t *testing.T) {
+	content := `@prefix owl: <http://www.w3.org/2002/07/owl#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix ex: <http://example.org/smsi#> .
+
+ex:Actif a owl:Class ; rdfs:comment "Ressource à protéger" .
+ex:Actif_Informationnel a owl:Class ; rdfs:subClassOf ex:Actif .
+ex:protege a owl:ObjectProperty ; rdfs:label "protège" .
+`
+	ontology, vocabulary, err := ImportOntology([]byte(content), DetectImportFormat("smsi.ttl", []byte(content)))
+	assert.NoError(t, err)
+	assert.Empty(t, ontology.Elements)
+	assert.Equal(t, []model.VocabularyTerm{
+		{Name: "Actif", Description: "Ressource à protéger"},
+		{Name: "Actif_Informationnel", Parent: "Actif"},
+	}, vocabulary.Classes)
+	assert.Equal(t, []string{"protège"}, termNames(vocabulary.Properties))
+}
+
+func TestImportCompactJSONLD(t *testing.T) {
+	content := `{
+  "@context": {"ex": "http://example.org/", "name": "http://www.w3.org/2000/01/rdf-schema#label", "knows": {"@id": "ex:knows", "@type": "@id"}},
+  "@graph": [
+    {"@id": "ex:alice", "@type": "ex:Person", "name": "Alice", "knows": "ex:bob"},
+    {"@id": "ex:bob", "@type": "ex:Person", "name": "Bob"}
+  ]
+}`
+	ontology, vocabulary, err := ImportOntology([]byte(content), DetectImportFormat("people", []byte(content)))
+	assert.NoError(t, err)
+	assert.NotNil(t, ontology.GetElementByName("Alice"))
+	assert.NotNil(t, ontology.GetRelation("Alice", "knows", "Bob"))
+	assert.Equal(t, []string{"Person"}, termNames(vocabulary.Classes))
+}
+
+func TestImportTSV(t *testing.T) {
+	content := "Loi\tNorme\tTexte voté\t1,4\nConseil_d_État\tInstitution\tJuridiction\t\nConseil_d_État\tinterprète:2\tLoi\tinterprétation\n"
+	ontology, vocabulary, err := ImportOntology([]byte(content), DetectImportFormat("onto.tsv", []byte(content)))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, ontology.GetElementByName("Loi").Positions)
+	assert.Equal(t, 2, ontology.GetRelation("Conseil_d_État", "interprète", "Loi").Weight)
+	assert.Equal(t, []string{"Norme", "Institution"}, termNames(vocabulary.Classes))
+}
+
+func termNames(terms []model.VocabularyTerm) []string {
+	var names []string
+	for _, term := range terms {
+		names = append(names, term.Name)
+	}
+	return names
+}
@@ -0,0 +1,344 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+)
+
+// JSONLDExporter serializes an ontology as JSON-LD in expanded form
+type JSONLDExporter struct {
+	baseURI string
+}
+
+func init() {
+	RegisterExporter("jsonld", NewJSONLDExporter)
+}
+
+// NewJSONLDExporter creates a new JSONLDExporter
+func NewJSONLDExporter(baseURI string) Exporter {
+	return &JSONLDExporter{baseURI: NormalizeBaseURI(baseURI)}
+}
+
+// Export serializes the ontology as a JSON-LD @graph, one node object per subject
+func (e *JSONLDExporter) Export(ontology *model.Ontology) ([]byte, error) {
+	log.Debug("Starting conversion to JSON-LD format")
+
+	triples, err := BuildGraph(ontology, e.baseURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build RDF graph: %w", err)
+	}
+
+	var graph []map[string]interface{}
+	subjects, grouped := groupBySubject(triples)
+	for _, subject := range subjects {
+		node := map[string]interface{}{"@id": jsonLDID(subject)}
+		var types []string
+		for _, triple := range grouped[subject.Serialize(rdf.NTriples)] {
+			predicate := triple.Pred.String()
+			if predicate == RDFNamespace+"type" {
+				types = append(types, jsonLDID(triple.Obj))
+				continue
+			}
+			var value map[string]interface{}
+			switch obj := triple.Obj.(type) {
+			case rdf.Literal:
+				value = map[string]interface{}{"@value": obj.String(), "@type": obj.DataType.String()}
+			default:
+				value = map[string]interface{}{"@id": jsonLDID(obj)}
+			}
+			values, _ := node[predicate].([]interface{})
+			node[predicate] = append(values, value)
+		}
+		if len(types) > 0 {
+			node["@type"] = types
+		}
+		graph = append(graph, node)
+	}
+
+	output, err := json.MarshalIndent(map[string]interface{}{"@graph": graph}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON-LD: %w", err)
+	}
+
+	log.Debug("JSON-LD conversion completed")
+	return output, nil
+}
+
+// Extension returns the JSON-LD file extension
+func (e *JSONLDExporter) Extension() string {
+	return ".jsonld"
+}
+
+// jsonLDID returns the node identifier of an IRI or blank node term
+func jsonLDID(term rdf.Term) string {
+	if term.Type() == rdf.TermBlank {
+		return "_:" + term.String()
+	}
+	return term.String()
+}
+
+// jsonLDContext holds the term definitions of a JSON-LD @context
+type jsonLDContext struct {
+	vocab   string
+	base    string
+	terms   map[string]string
+	idTerms map[string]bool // terms whose string values are IRIs ("@type": "@id")
+}
+
+// jsonLDDecoder turns a JSON-LD document into triples.
+// It supports expanded and compacted documents with an inline @context; remote contexts are not fetched.
+type jsonLDDecoder struct {
+	triples []rdf.Triple
+	blanks  int
+}
+
+// decodeJSONLD decodes a JSON-LD document into RDF triples
+func decodeJSONLD(content []byte) ([]rdf.Triple, error) {
+	var document interface{}
+	if err := json.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON-LD: %w", err)
+	}
+
+	d := &jsonLDDecoder{}
+	ctx := &jsonLDContext{terms: make(map[string]string), idTerms: make(map[string]bool)}
+	if err := d.decodeValue(document, ctx); err != nil {
+		return nil, err
+	}
+	return d.triples, nil
+}
+
+// decodeValue handles the top-level document: a node, a list of nodes or a @graph container
+func (d *jsonLDDecoder) decodeValue(value interface{}, ctx *jsonLDContext) error {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if err := d.decodeValue(item, ctx); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		ctx = ctx.with(v["@context"])
+		if graph, ok := v["@graph"]; ok {
+			return d.decodeValue(graph, ctx)
+		}
+		_, err := d.decodeNode(v, ctx)
+		return err
+	}
+	return nil
+}
+
+// decodeNode emits the triples of a node object and returns its subject
+func (d *jsonLDDecoder) decodeNode(node map[string]interface{}, ctx *jsonLDContext) (rdf.Subject, error) {
+	ctx = ctx.with(node["@context"])
+
+	var subject rdf.Subject
+	if id, ok := node["@id"].(string); ok {
+		term, err := d.resource(ctx.expand(id, false))
+		if err != nil {
+			return nil, err
+		}
+		subject = term.(rdf.Subject)
+	} else {
+		d.blanks++
+		blank, err := rdf.NewBlank(fmt.Sprintf("b%d", d.blanks))
+		if err != nil {
+			return nil, err
+		}
+		subject = blank
+	}
+
+	rdfType, _ := rdf.NewIRI(RDFNamespace + "type")
+	for _, t := range asList(node["@type"]) {
+		typeName, ok := t.(string)
+		if !ok {
+			continue
+		}
+		object, err := d.resource(ctx.expand(typeName, true))
+		if err != nil {
+			return nil, err
+		}
+		d.triples = append(d.triples, rdf.Triple{Subj: subject, Pred: rdfType, Obj: object.(rdf.Object)})
+	}
+
+	for key, value := range node {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		predicate, err := rdf.NewIRI(ctx.expand(key, true))
+		if err != nil {
+			log.Warning("Skipping JSON-LD property %s: %v", key, err)
+			continue
+		}
+		for _, item := range asList(value) {
+			object, err := d.decodeObject(item, ctx, ctx.idTerms[key])
+			if err != nil {
+				return nil, err
+			}
+			if object != nil {
+				d.triples = append(d.triples, rdf.Triple{Subj: subject, Pred: predicate, Obj: object})
+			}
+		}
+	}
+	return subject, nil
+}
+
+// decodeObject converts a property value into an RDF object
+func (d *jsonLDDecoder) decodeObject(value interface{}, ctx *jsonLDContext, isID bool) (rdf.Object, error) {
+	switch v := value.(type) {
+	case string:
+		if isID {
+			term, err := d.resource(ctx.expand(v, false))
+			if err != nil {
+				return nil, err
+			}
+			return term.(rdf.Object), nil
+		}
+		return rdf.NewTypedLiteral(v, mustIRI(XSDNamespace+"string")), nil
+	case float64:
+		if v == float64(int64(v)) {
+			return rdf.NewTypedLiteral(strconv.FormatInt(int64(v), 10), mustIRI(XSDNamespace+"integer")), nil
+		}
+		return rdf.NewTypedLiteral(strconv.FormatFloat(v, 'f', -1, 64), mustIRI(XSDNamespace+"double")), nil
+	case bool:
+		return rdf.NewTypedLiteral(strconv.FormatBool(v), mustIRI(XSDNamespace+"boolean")), nil
+	case map[string]interface{}:
+		if literal, ok := v["@value"]; ok {
+			lexical := fmt.Sprint(literal)
+			if lang, ok := v["@language"].(string); ok {
+				return rdf.NewLangLiteral(lexical, lang)
+			}
+			datatype := XSDNamespace + "string"
+			if t, ok := v["@type"].(string); ok {
+				datatype = ctx.expand(t, true)
+			}
+			datatypeIRI, err := rdf.NewIRI(datatype)
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON-LD datatype %q: %w", datatype, err)
+			}
+			return rdf.NewTypedLiteral(lexical, datatypeIRI), nil
+		}
+		if id, ok := v["@id"].(string); ok && len(v) == 1 {
+			term, err := d.resource(ctx.expand(id, false))
+			if err != nil {
+				return nil, err
+			}
+			return term.(rdf.Object), nil
+		}
+		subject, err := d.decodeNode(v, ctx)
+		if err != nil {
+			return nil, err
+		}
+		return subject.(rdf.Object), nil
+	}
+	return nil, nil
+}
+
+// resource returns the IRI or blank node identified by an expanded JSON-LD identifier
+func (d *jsonLDDecoder) resource(id string) (rdf.Term, error) {
+	if strings.HasPrefix(id, "_:") {
+		return rdf.NewBlank(strings.TrimPrefix(id, "_:"))
+	}
+	iri, err := rdf.NewIRI(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON-LD identifier %q: %w", id, err)
+	}
+	return iri, nil
+}
+
+// with returns the context extended by an inline @context value
+func (c *jsonLDContext) with(value interface{}) *jsonLDContext {
+	if value == nil {
+		return c
+	}
+	next := &jsonLDContext{vocab: c.vocab, base: c.base, terms: make(map[string]string), idTerms: make(map[string]bool)}
+	for term, iri := range c.terms {
+		next.terms[term] = iri
+	}
+	for term := range c.idTerms {
+		next.idTerms[term] = true
+	}
+
+	for _, item := range asList(value) {
+		definitions, ok := item.(map[string]interface{})
+		if !ok {
+			log.Warning("Remote JSON-LD contexts are not supported, ignoring %v", item)
+			continue
+		}
+		for term, definition := range definitions {
+			switch def := definition.(type) {
+			case string:
+				switch term {
+				case "@vocab":
+					next.vocab = def
+				case "@base":
+					next.base = def
+				default:
+					next.terms[term] = def
+				}
+			case map[string]interface{}:
+				if id, ok := def["@id"].(string); ok {
+					next.terms[term] = id
+				}
+				if def["@type"] == "@id" || def["@type"] == "@vocab" {
+					next.idTerms[term] = true
+				}
+			}
+		}
+	}
+
+	// Les définitions peuvent elles-mêmes utiliser des préfixes compacts
+	for term, iri := range next.terms {
+		next.terms[term] = next.expand(iri, false)
+	}
+	return next
+}
+
+// expand turns a term, compact IRI or relative IRI into an absolute IRI
+func (c *jsonLDContext) expand(value string, vocab bool) string {
+	if strings.HasPrefix(value, "@") || strings.HasPrefix(value, "_:") {
+		return value
+	}
+	if iri, ok := c.terms[value]; ok {
+		return iri
+	}
+	if idx := strings.Index(value, ":"); idx > 0 {
+		if prefix, ok := c.terms[value[:idx]]; ok && !strings.HasPrefix(value[idx+1:], "//") {
+			return prefix + value[idx+1:]
+		}
+		return value
+	}
+	if vocab && c.vocab != "" {
+		return c.vocab + value
+	}
+	if c.base != "" {
+		return c.base + value
+	}
+	return value
+}
+
+func asList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case map[string]interface{}:
+		if list, ok := v["@list"].([]interface{}); ok {
+			return list
+		}
+	}
+	return []interface{}{value}
+}
+
+func mustIRI(value string) rdf.IRI {
+	iri, err := rdf.NewIRI(value)
+	if err != nil {
+		panic(err)
+	}
+	return iri
+}
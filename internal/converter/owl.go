@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/i18n"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+)
+
+// OWLExporter serializes an ontology as OWL in RDF/XML syntax
+type OWLExporter struct {
+	baseURI string
+}
+
+func init() {
+	RegisterExporter("owl", NewOWLExporter)
+}
+
+// NewOWLExporter creates a new OWLExporter
+func NewOWLExporter(baseURI string) Exporter {
+	return &OWLExporter{baseURI: NormalizeBaseURI(baseURI)}
+}
+
+// Export serializes the ontology in RDF/XML
+func (e *OWLExporter) Export(ontology *model.Ontology) ([]byte, error) {
+	log.Debug(i18n.GetMessage("StartingOWLConversion"))
+
+	triples, err := BuildGraph(ontology, e.baseURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build RDF graph: %w", err)
+	}
+
+	prefixes := map[string]string{
+		RDFNamespace:  "rdf",
+		RDFSNamespace: "rdfs",
+		OWLNamespace:  "owl",
+		XSDNamespace:  "xsd",
+		SKOSNamespace: "skos",
+		e.baseURI:     "onto",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString("<rdf:RDF")
+	for _, namespace := range []string{RDFNamespace, RDFSNamespace, OWLNamespace, XSDNamespace, SKOSNamespace, e.baseURI} {
+		buf.WriteString(fmt.Sprintf("\n    xmlns:%s=\"%s\"", prefixes[namespace], xmlEscape(namespace)))
+	}
+	buf.WriteString(fmt.Sprintf("\n    xml:base=\"%s\">\n", xmlEscape(e.baseURI)))
+
+	subjects, grouped := groupBySubject(triples)
+	for _, subject := range subjects {
+		if subject.Type() == rdf.TermBlank {
+			buf.WriteString(fmt.Sprintf("  <rdf:Description rdf:nodeID=\"%s\">\n", xmlEscape(subject.String())))
+		} else {
+			buf.WriteString(fmt.Sprintf("  <rdf:Description rdf:about=\"%s\">\n", xmlEscape(subject.String())))
+		}
+		for _, triple := range grouped[subject.Serialize(rdf.NTriples)] {
+			qname, err := qualifiedName(triple.Pred.String(), prefixes)
+			if err != nil {
+				return nil, err
+			}
+			switch obj := triple.Obj.(type) {
+			case rdf.IRI:
+				buf.WriteString(fmt.Sprintf("    <%s rdf:resource=\"%s\"/>\n", qname, xmlEscape(obj.String())))
+			case rdf.Blank:
+				buf.WriteString(fmt.Sprintf("    <%s rdf:nodeID=\"%s\"/>\n", qname, xmlEscape(obj.String())))
+			case rdf.Literal:
+				buf.WriteString(fmt.Sprintf("    <%s rdf:datatype=\"%s\">%s</%s>\n", qname, xmlEscape(obj.DataType.String()), xmlEscape(obj.String()), qname))
+			}
+		}
+		buf.WriteString("  </rdf:Description>\n")
+	}
+	buf.WriteString("</rdf:RDF>\n")
+
+	log.Debug(i18n.GetMessage("OWLConversionCompleted"))
+	return buf.Bytes(), nil
+}
+
+// Extension returns the OWL file extension
+func (e *OWLExporter) Extension() string {
+	return ".owl"
+}
+
+// qualifiedName shortens a predicate IRI into a prefix:local XML name
+func qualifiedName(iri string, prefixes map[string]string) (string, error) {
+	for namespace, prefix := range prefixes {
+		if strings.HasPrefix(iri, namespace) {
+			local := strings.TrimPrefix(iri, namespace)
+			if local != "" && LocalName(local) == local {
+				return prefix + ":" + local, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("cannot express predicate %s as an XML qualified name", iri)
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,64 @@
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+// TSVExporter serializes an ontology in the project's own TSV format
+type TSVExporter struct{}
+
+func init() {
+	RegisterExporter("tsv", NewTSVExporter)
+}
+
+// NewTSVExporter creates a new TSVExporter; the base URI is not used by this format
+func NewTSVExporter(baseURI string) Exporter {
+	return &TSVExporter{}
+}
+
+// Export writes one line per element (Name, Type, Description, Positions)
+// where positions are the element's mentions (fileID:start-end[:pN][:§N]), or its legacy word positions,
+// and one line per alias (Alias, "alias", Name), followed by one line per relation (Source, Type:Weight, Target, Description).
+// When known, the provenance (fileID:segment:pass, comma separated) is appended as a last column.
+func (e *TSVExporter) Export(ontology *model.Ontology) ([]byte, error) {
+	var tsvBuilder strings.Builder
+
+	for _, element := range ontology.Elements {
+		positions := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(element.Positions)), ","), "[]")
+		if len(element.Mentions) > 0 {
+			positions = model.FormatMentions(element.Mentions)
+		}
+		line := fmt.Sprintf("%s\t%s\t%s\t%s", element.Name, element.Type, element.Description, positions)
+		tsvBuilder.WriteString(withProvenanceColumn(line, element.Provenance))
+		for _, alias := range element.Aliases {
+			tsvBuilder.WriteString(fmt.Sprintf("%s\t%s\t%s\n", alias, model.AliasType, element.Name))
+		}
+	}
+
+	for _, relation := range ontology.Relations {
+		line := fmt.Sprintf("%s\t%s:%d\t%s\t%s",
+			relation.Source,
+			relation.Type,
+			relation.Weight,
+			relation.Target,
+			relation.Description)
+		tsvBuilder.WriteString(withProvenanceColumn(line, relation.Provenance))
+	}
+
+	return []byte(tsvBuilder.String()), nil
+}
+
+// Extension returns the TSV file extension
+func (e *TSVExporter) Extension() string {
+	return ".tsv"
+}
+
+func withProvenanceColumn(line string, provenance []model.Provenance) string {
+	if len(provenance) > 0 {
+		line += "\t" + model.FormatProvenance(provenance)
+	}
+	return line + "\n"
+}
@@ -0,0 +1,67 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/chrlesur/Ontology/internal/i18n"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/knakk/rdf"
+)
+
+// TurtleExporter serializes an ontology as RDF Turtle
+type TurtleExporter struct {
+	baseURI string
+}
+
+func init() {
+	RegisterExporter("ttl", NewTurtleExporter)
+}
+
+// NewTurtleExporter creates a new TurtleExporter
+func NewTurtleExporter(baseURI string) Exporter {
+	return &TurtleExporter{baseURI: NormalizeBaseURI(baseURI)}
+}
+
+// Export serializes the ontology in Turtle
+func (e *TurtleExporter) Export(ontology *model.Ontology) ([]byte, error) {
+	log.Debug(i18n.GetMessage("StartingRDFConversion"))
+
+	triples, err := BuildGraph(ontology, e.baseURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build RDF graph: %w", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := rdf.NewTripleEncoder(&buf, rdf.Turtle)
+	encoder.Namespaces = map[string]string{
+		RDFNamespace:  "rdf",
+		RDFSNamespace: "rdfs",
+		OWLNamespace:  "owl",
+		XSDNamespace:  "xsd",
+		SKOSNamespace: "skos",
+		e.baseURI:     "onto",
+	}
+	encoder.GenerateNamespaces = false
+
+	// Encode subject by subject so that each resource gets a single predicate list
+	subjects, grouped := groupBySubject(triples)
+	for _, subject := range subjects {
+		for _, triple := range grouped[subject.Serialize(rdf.NTriples)] {
+			if err := encoder.Encode(triple); err != nil {
+				return nil, fmt.Errorf("failed to encode Turtle: %w", err)
+			}
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to flush Turtle encoder: %w", err)
+	}
+
+	log.Debug(i18n.GetMessage("RDFConversionCompleted"))
+	return buf.Bytes(), nil
+}
+
+// Extension returns the Turtle file extension
+func (e *TurtleExporter) Extension() string {
+	return ".ttl"
+}
@@ -0,0 +1,14 @@
+package language
+
+import "github.com/kljensen/snowball/english"
+
+func init() {
+	Register(NewAnalyzer("en", func(word string) string { return english.Stem(word, false) }, englishStopWords))
+}
+
+var englishStopWords = []string{
+	"a", "an", "the", "and", "or", "but", "of", "to", "in", "on", "at", "by", "for", "from", "with", "into",
+	"is", "are", "was", "were", "be", "been", "has", "have", "had", "it", "its", "this", "that", "these",
+	"those", "which", "who", "as", "not", "can", "will", "their", "they", "such", "than", "other", "also",
+	"between", "each", "all", "any",
+}
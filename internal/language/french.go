@@ -0,0 +1,14 @@
+package language
+
+import "github.com/kljensen/snowball/french"
+
+func init() {
+	Register(NewAnalyzer("fr", func(word string) string { return french.Stem(word, false) }, frenchStopWords))
+}
+
+var frenchStopWords = []string{
+	"le", "la", "les", "un", "une", "des", "et", "en", "de", "du", "ce", "qui", "que", "dans", "pour",
+	"par", "sur", "est", "sont", "au", "aux", "avec", "il", "elle", "ils", "elles", "ne", "pas", "plus",
+	"ou", "se", "son", "sa", "ses", "leur", "leurs", "cette", "ces", "mais", "comme", "été", "être",
+	"lors", "dont", "où",
+}
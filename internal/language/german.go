@@ -0,0 +1,51 @@
+package language
+
+import "strings"
+
+func init() {
+	Register(NewAnalyzer("de", stemGerman, germanStopWords))
+}
+
+var germanStopWords = []string{
+	"der", "die", "das", "und", "ist", "zu", "den", "dem", "des", "ein", "eine", "einer", "eines", "einem",
+	"mit", "von", "im", "für", "auf", "nicht", "sich", "auch", "es", "als", "werden", "wird", "sind",
+	"bei", "oder", "aus", "nach", "wie", "dass", "zum", "zur", "durch", "über", "wurde", "kann", "sie",
+}
+
+// stemGerman est une racinisation légère (algorithme de J. Savoy) : elle retire les
+// terminaisons de flexion les plus courantes d'un mot sans accents
+func stemGerman(word string) string {
+	runes := []rune(word)
+	n := germanStep2(runes, germanStep1(runes, len(runes)))
+	return string(runes[:n])
+}
+
+func germanStep1(s []rune, n int) int {
+	switch {
+	case n > 5 && strings.HasSuffix(string(s[:n]), "ern"):
+		return n - 3
+	case n > 4 && s[n-2] == 'e' && strings.ContainsRune("mnrs", s[n-1]):
+		return n - 2
+	case n > 3 && s[n-1] == 'e':
+		return n - 1
+	case n > 3 && s[n-1] == 's' && isGermanSTEnding(s[n-2]):
+		return n - 1
+	}
+	return n
+}
+
+func germanStep2(s []rune, n int) int {
+	switch {
+	case n > 5 && strings.HasSuffix(string(s[:n]), "est"):
+		return n - 3
+	case n > 4 && s[n-2] == 'e' && (s[n-1] == 'r' || s[n-1] == 'n'):
+		return n - 2
+	case n > 4 && s[n-2] == 's' && s[n-1] == 't' && isGermanSTEnding(s[n-3]):
+		return n - 2
+	}
+	return n
+}
+
+func isGermanSTEnding(r rune) bool {
+	return strings.ContainsRune("bdfghklmnt", r)
+}
@@ -0,0 +1,34 @@
+package language
+
+func init() {
+	Register(NewAnalyzer("it", stemItalian, italianStopWords))
+}
+
+var italianStopWords = []string{
+	"il", "lo", "la", "i", "gli", "le", "un", "una", "uno", "e", "di", "del", "della", "dei", "delle",
+	"che", "in", "per", "con", "non", "si", "al", "alla", "da", "dal", "sono", "come", "anche", "più",
+	"ma", "questo", "questa", "nel", "nella", "tra", "è", "essere", "stato",
+}
+
+// stemItalian est une racinisation légère (algorithme de J. Savoy) : elle retire la voyelle
+// finale marquant le genre et le nombre d'un mot sans accents
+func stemItalian(word string) string {
+	s := []rune(word)
+	n := len(s)
+	if n < 6 {
+		return word
+	}
+	switch s[n-1] {
+	case 'e', 'i':
+		if s[n-2] == 'i' || s[n-2] == 'h' {
+			return string(s[:n-2])
+		}
+		return string(s[:n-1])
+	case 'a', 'o':
+		if s[n-2] == 'i' {
+			return string(s[:n-2])
+		}
+		return string(s[:n-1])
+	}
+	return word
+}
@@ -0,0 +1,131 @@
+// Package language fournit, pour chaque langue supportée, la racinisation et les mots vides
+// utilisés pour retrouver les positions des éléments dans les documents.
+package language
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Auto demande la détection de la langue de chaque fichier
+	Auto = "auto"
+	// Default est la langue retenue quand aucune langue n'est reconnue
+	Default = "fr"
+
+	// detectionSampleWords limite le nombre de mots examinés par Detect
+	detectionSampleWords = 2000
+)
+
+// Analyzer normalise les mots d'une langue
+type Analyzer interface {
+	// Code retourne le code ISO 639-1 de la langue
+	Code() string
+	// Stem retourne la racine d'un mot normalisé par Normalize
+	Stem(word string) string
+	// IsStopWord indique si un mot normalisé par Normalize est un mot vide
+	IsStopWord(word string) bool
+}
+
+// analyzers stocke l'analyseur de chaque langue supportée
+var analyzers = make(map[string]Analyzer)
+
+// Register enregistre l'analyseur d'une langue
+func Register(analyzer Analyzer) {
+	analyzers[analyzer.Code()] = analyzer
+}
+
+// Get retourne l'analyseur de la langue donnée
+func Get(code string) (Analyzer, error) {
+	analyzer, ok := analyzers[strings.ToLower(strings.TrimSpace(code))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", code)
+	}
+	return analyzer, nil
+}
+
+// Lookup retourne l'analyseur de la langue donnée, ou celui de la langue par défaut
+func Lookup(code string) Analyzer {
+	if analyzer, err := Get(code); err == nil {
+		return analyzer
+	}
+	return analyzers[Default]
+}
+
+// Supported retourne les codes des langues supportées, triés
+func Supported() []string {
+	var codes []string
+	for code := range analyzers {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Detect retourne la langue dont les mots vides sont les plus fréquents dans le texte,
+// ou la langue par défaut si aucun mot vide n'est reconnu
+func Detect(text string) string {
+	words := strings.Fields(text)
+	if len(words) > detectionSampleWords {
+		words = words[:detectionSampleWords]
+	}
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		word = Normalize(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+		for code, analyzer := range analyzers {
+			if analyzer.IsStopWord(word) {
+				scores[code]++
+			}
+		}
+	}
+
+	best, bestScore := Default, 0
+	for _, code := range Supported() {
+		if scores[code] > bestScore {
+			best, bestScore = code, scores[code]
+		}
+	}
+	return best
+}
+
+// Normalize met un mot en minuscules et retire ses accents
+func Normalize(word string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, _ := transform.String(t, strings.ToLower(word))
+	return strings.ReplaceAll(result, "ß", "ss")
+}
+
+// analyzer associe une fonction de racinisation à une liste de mots vides
+type analyzer struct {
+	code      string
+	stem      func(string) string
+	stopWords map[string]bool
+}
+
+// NewAnalyzer crée l'analyseur d'une langue ; les mots vides sont normalisés par Normalize
+func NewAnalyzer(code string, stem func(string) string, stopWords []string) Analyzer {
+	a := &analyzer{code: code, stem: stem, stopWords: make(map[string]bool, len(stopWords))}
+	for _, word := range stopWords {
+		a.stopWords[Normalize(word)] = true
+	}
+	return a
+}
+
+func (a *analyzer) Code() string {
+	return a.code
+}
+
+func (a *analyzer) Stem(word string) string {
+	return a.stem(word)
+}
+
+func (a *analyzer) IsStopWord(word string) bool {
+	return a.stopWords[word]
+}
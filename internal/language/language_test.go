@@ -0,0 +1,45 @@
+package language
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetect(t *testing.T) {
+	samples := map[string]string{
+		"en": "The security policy is reviewed by the board and applies to all of the employees.",
+		"fr": "La politique de sécurité est validée par le comité et s'applique à tous les salariés.",
+		"de": "Die Sicherheitsrichtlinie wird von dem Vorstand geprüft und gilt für alle Mitarbeiter.",
+		"es": "La política de seguridad es revisada por el consejo y se aplica a todos los empleados.",
+		"it": "La politica di sicurezza è approvata dal consiglio e si applica a tutti gli impiegati.",
+	}
+	for expected, text := range samples {
+		assert.Equal(t, expected, Detect(text), text)
+	}
+	assert.Equal(t, Default, Detect("1234 5678"))
+}
+
+func TestStemmers(t *testing.T) {
+	cases := []struct {
+		code, word, stem string
+	}{
+		{"en", "policies", "polici"},
+		{"fr", "politiques", "polit"},
+		{"es", "políticas", "polit"},
+		{"de", Normalize("Richtlinien"), "richtlini"},
+		{"it", "politiche", "politic"},
+		{"it", "politica", "politic"},
+	}
+	for _, c := range cases {
+		analyzer, err := Get(c.code)
+		if assert.NoError(t, err) {
+			assert.Equal(t, c.stem, analyzer.Stem(Normalize(c.word)), c.word)
+		}
+	}
+
+	_, err := Get("xx")
+	assert.Error(t, err)
+	assert.Equal(t, Default, Lookup("xx").Code())
+	assert.Equal(t, []string{"de", "en", "es", "fr", "it"}, Supported())
+}
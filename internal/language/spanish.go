@@ -0,0 +1,13 @@
+package language
+
+import "github.com/kljensen/snowball/spanish"
+
+func init() {
+	Register(NewAnalyzer("es", func(word string) string { return spanish.Stem(word, false) }, spanishStopWords))
+}
+
+var spanishStopWords = []string{
+	"el", "la", "los", "las", "un", "una", "unos", "unas", "y", "de", "del", "en", "que", "es", "por",
+	"para", "con", "no", "se", "lo", "al", "su", "sus", "como", "más", "pero", "son", "está", "este",
+	"esta", "esto", "ha", "han", "entre", "sobre", "también", "fue", "ser", "muy",
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -11,12 +12,13 @@ import (
 	"github.com/chrlesur/Ontology/internal/config"
 	"github.com/chrlesur/Ontology/internal/logger"
 	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/chrlesur/Ontology/internal/tokenizer"
 )
 
 const AIYOUAPIURL = "https://ai.dragonflygroup.fr/api"
 
 type APICaller interface {
-	Call(endpoint, method string, data interface{}, response interface{}) error
+	Call(ctx context.Context, endpoint, method string, data interface{}, response interface{}) error
 	SetToken(token string)
 }
 
@@ -76,7 +78,7 @@ func (c *AIYOUClient) Login(email, password string) error {
 		ExpiresAt string `json:"expires_at"`
 	}
 
-	err := c.apiCaller.Call("/login", "POST", loginData, &loginResp)
+	err := c.apiCaller.Call(context.Background(), "/login", "POST", loginData, &loginResp)
 	if err != nil {
 		c.logger.Error(fmt.Sprintf("Login failed: %v", err))
 		return fmt.Errorf("login failed: %w", err)
@@ -87,39 +89,71 @@ func (c *AIYOUClient) Login(email, password string) error {
 	return nil
 }
 
-func (c *AIYOUClient) Translate(prompt string, context string) (string, error) {
+func (c *AIYOUClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
 	c.logger.Debug("Starting AI.YOU translation")
 
-	threadID, err := c.CreateThread()
+	threadID, err := c.CreateThread(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to create thread: %w", err)
+		return "", Usage{}, fmt.Errorf("failed to create thread: %w", err)
 	}
 
-	fullPrompt := fmt.Sprintf("%s\n\nContext: %s", prompt, context)
-	response, err := c.ChatInThread(threadID, fullPrompt)
+	fullPrompt := fmt.Sprintf("%s\n\nContext: %s", prompt, systemContext)
+	response, err := c.ChatInThread(ctx, threadID, fullPrompt)
 	if err != nil {
-		return "", fmt.Errorf("error during chat: %w", err)
+		return "", Usage{}, fmt.Errorf("error during chat: %w", err)
 	}
 
 	c.logger.Debug("AI.YOU translation completed")
-	return response, nil
+	return response, estimateUsage(fullPrompt, response), nil
 }
 
-func (c *AIYOUClient) ProcessWithPrompt(promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error) {
+// estimateUsage counts the tokens of a request locally since the AI.YOU API does not report them
+func estimateUsage(prompt string, response string) Usage {
+	usage := Usage{Requests: 1, Estimated: true}
+	if count, err := tokenizer.CountTokens(prompt); err == nil {
+		usage.InputTokens = count
+	}
+	if count, err := tokenizer.CountTokens(response); err == nil {
+		usage.OutputTokens = count
+	}
+	return usage
+}
+
+func (c *AIYOUClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
 	c.logger.Debug("Processing with prompt using AI.YOU")
 
-	formattedPrompt := promptTemplate.Format(values)
-	return c.Translate(formattedPrompt, "")
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return c.Translate(ctx, formattedPrompt, "")
+}
+
+// ProcessWithPromptJSON embeds the JSON schema in the prompt since AI.YOU assistants have no native structured output
+func (c *AIYOUClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	c.logger.Debug("Processing structured prompt using AI.YOU")
+
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error marshalling JSON schema: %w", err)
+	}
+
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	formattedPrompt += fmt.Sprintf("\n\nRespond only with a JSON document named %s that validates against this JSON schema, without any comment or code fence:\n%s", schemaName, string(schemaJSON))
+	return c.Translate(ctx, formattedPrompt, "")
 }
 
-func (c *AIYOUClient) CreateThread() (string, error) {
+func (c *AIYOUClient) CreateThread(ctx context.Context) (string, error) {
 	c.logger.Debug("Creating new AI.YOU thread")
 
 	var threadResp struct {
 		ID string `json:"id"`
 	}
 
-	err := c.apiCaller.Call("/v1/threads", "POST", map[string]string{}, &threadResp)
+	err := c.apiCaller.Call(ctx, "/v1/threads", "POST", map[string]string{}, &threadResp)
 	if err != nil {
 		return "", fmt.Errorf("error creating thread: %w", err)
 	}
@@ -132,20 +166,20 @@ func (c *AIYOUClient) CreateThread() (string, error) {
 	return threadResp.ID, nil
 }
 
-func (c *AIYOUClient) ChatInThread(threadID, input string) (string, error) {
+func (c *AIYOUClient) ChatInThread(ctx context.Context, threadID, input string) (string, error) {
 	c.logger.Debug(fmt.Sprintf("Chatting in thread %s", threadID))
 
-	err := c.addMessage(threadID, input)
+	err := c.addMessage(ctx, threadID, input)
 	if err != nil {
 		return "", fmt.Errorf("failed to add message: %w", err)
 	}
 
-	runID, err := c.createRun(threadID)
+	runID, err := c.createRun(ctx, threadID)
 	if err != nil {
 		return "", fmt.Errorf("failed to create run: %w", err)
 	}
 
-	completedRun, err := c.waitForCompletion(threadID, runID)
+	completedRun, err := c.waitForCompletion(ctx, threadID, runID)
 	if err != nil {
 		return "", fmt.Errorf("run failed: %w", err)
 	}
@@ -153,7 +187,7 @@ func (c *AIYOUClient) ChatInThread(threadID, input string) (string, error) {
 	return completedRun.Response, nil
 }
 
-func (c *AIYOUClient) addMessage(threadID, content string) error {
+func (c *AIYOUClient) addMessage(ctx context.Context, threadID, content string) error {
 	c.logger.Debug(fmt.Sprintf("Adding message to thread %s", threadID))
 
 	messageData := map[string]string{
@@ -162,7 +196,7 @@ func (c *AIYOUClient) addMessage(threadID, content string) error {
 	}
 
 	var response interface{}
-	err := c.apiCaller.Call(fmt.Sprintf("/v1/threads/%s/messages", threadID), "POST", messageData, &response)
+	err := c.apiCaller.Call(ctx, fmt.Sprintf("/v1/threads/%s/messages", threadID), "POST", messageData, &response)
 	if err != nil {
 		return fmt.Errorf("error adding message: %w", err)
 	}
@@ -171,7 +205,7 @@ func (c *AIYOUClient) addMessage(threadID, content string) error {
 	return nil
 }
 
-func (c *AIYOUClient) createRun(threadID string) (string, error) {
+func (c *AIYOUClient) createRun(ctx context.Context, threadID string) (string, error) {
 	c.logger.Debug(fmt.Sprintf("Creating run for thread %s", threadID))
 
 	runData := map[string]string{
@@ -181,7 +215,7 @@ func (c *AIYOUClient) createRun(threadID string) (string, error) {
 	var runResp struct {
 		ID string `json:"id"`
 	}
-	err := c.apiCaller.Call(fmt.Sprintf("/v1/threads/%s/runs", threadID), "POST", runData, &runResp)
+	err := c.apiCaller.Call(ctx, fmt.Sprintf("/v1/threads/%s/runs", threadID), "POST", runData, &runResp)
 	if err != nil {
 		return "", fmt.Errorf("error creating run: %w", err)
 	}
@@ -190,13 +224,13 @@ func (c *AIYOUClient) createRun(threadID string) (string, error) {
 	return runResp.ID, nil
 }
 
-func (c *AIYOUClient) waitForCompletion(threadID, runID string) (*Run, error) {
+func (c *AIYOUClient) waitForCompletion(ctx context.Context, threadID, runID string) (*Run, error) {
 	maxAttempts := 30
 	delayBetweenAttempts := 2 * time.Second
 
 	for i := 0; i < maxAttempts; i++ {
 		c.logger.Debug(fmt.Sprintf("Attempt %d to retrieve run status", i+1))
-		run, err := c.retrieveRun(threadID, runID)
+		run, err := c.retrieveRun(ctx, threadID, runID)
 		if err != nil {
 			return nil, err
 		}
@@ -209,18 +243,20 @@ func (c *AIYOUClient) waitForCompletion(threadID, runID string) (*Run, error) {
 			return nil, fmt.Errorf("run failed with status: %s", run.Status)
 		default:
 			c.logger.Debug(fmt.Sprintf("Waiting for run completion. Pausing for %v", delayBetweenAttempts))
-			time.Sleep(delayBetweenAttempts)
+			if err := sleepContext(ctx, delayBetweenAttempts); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return nil, fmt.Errorf("timeout waiting for run completion")
 }
 
-func (c *AIYOUClient) retrieveRun(threadID, runID string) (*Run, error) {
+func (c *AIYOUClient) retrieveRun(ctx context.Context, threadID, runID string) (*Run, error) {
 	c.logger.Debug(fmt.Sprintf("Retrieving run %s for thread %s", runID, threadID))
 
 	var runStatus Run
-	err := c.apiCaller.Call(fmt.Sprintf("/v1/threads/%s/runs/%s", threadID, runID), "POST", map[string]string{}, &runStatus)
+	err := c.apiCaller.Call(ctx, fmt.Sprintf("/v1/threads/%s/runs/%s", threadID, runID), "POST", map[string]string{}, &runStatus)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving run: %w", err)
 	}
@@ -238,7 +274,7 @@ func NewHTTPAPICaller(baseURL string) APICaller {
 	}
 }
 
-func (c *HTTPAPICaller) Call(endpoint, method string, data interface{}, response interface{}) error {
+func (c *HTTPAPICaller) Call(ctx context.Context, endpoint, method string, data interface{}, response interface{}) error {
 	url := c.baseURL + endpoint
 	var req *http.Request
 	var err error
@@ -248,9 +284,9 @@ func (c *HTTPAPICaller) Call(endpoint, method string, data interface{}, response
 		if err != nil {
 			return fmt.Errorf("error marshaling request data: %w", err)
 		}
-		req, err = http.NewRequest(method, url, bytes.NewBuffer(jsonData))
+		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewBuffer(jsonData))
 	} else {
-		req, err = http.NewRequest(method, url, nil)
+		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	}
 
 	if err != nil {
@@ -274,7 +310,7 @@ func (c *HTTPAPICaller) Call(endpoint, method string, data interface{}, response
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return fmt.Errorf("API error: status code %d, body: %s", resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	err = json.Unmarshal(body, response)
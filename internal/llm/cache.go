@@ -0,0 +1,231 @@
+// internal/llm/cache.go
+
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CacheEntry is a response stored on disk with the parameters it was produced with
+type CacheEntry struct {
+	Key         string    `json:"key"`
+	Provider    string    `json:"provider"`
+	Model       string    `json:"model"`
+	Temperature string    `json:"temperature"`
+	PromptHash  string    `json:"prompt_hash"`
+	CreatedAt   time.Time `json:"created_at"`
+	Response    string    `json:"response"`
+}
+
+// CacheStats summarizes the content of a response cache
+type CacheStats struct {
+	Entries int
+	Expired int
+	Bytes   int64
+	Oldest  time.Time
+	Newest  time.Time
+	ByModel map[string]int // entries per provider/model
+}
+
+// ResponseCache stores LLM responses as JSON files, one per key, under a directory.
+// Entries older than the TTL are ignored by Get and removed by Prune; a zero TTL never expires.
+type ResponseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewResponseCache returns a cache rooted at dir
+func NewResponseCache(dir string, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{dir: dir, ttl: ttl}
+}
+
+// CacheKey derives the key of a request from the provider, model, temperature and the hash of the prompt
+func CacheKey(provider, model, temperature, promptHash string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{provider, model, temperature, promptHash}, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashPrompt hashes the parts of a request that determine the response: prompt, system context, schema...
+func HashPrompt(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file of a key, sharded by its first two characters
+func (c *ResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// expired reports whether an entry created at the given time is older than the TTL
+func (c *ResponseCache) expired(createdAt time.Time, now time.Time) bool {
+	return c.ttl > 0 && now.Sub(createdAt) > c.ttl
+}
+
+// Get returns the cached response of a key, if present and not expired
+func (c *ResponseCache) Get(key string) (string, bool) {
+	content, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Warning("Failed to read cache entry %s: %v", key, err)
+		}
+		return "", false
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		log.Warning("Ignoring corrupted cache entry %s: %v", key, err)
+		return "", false
+	}
+	if c.expired(entry.CreatedAt, time.Now()) {
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// Put stores an entry, replacing the previous response of its key.
+// The file is written under a temporary name then renamed, so concurrent readers never see a partial entry.
+func (c *ResponseCache) Put(entry CacheEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	path := c.path(entry.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), entry.Key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry: %w", err)
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry: %w", err)
+	}
+	return nil
+}
+
+// walk calls fn for every entry of the cache with its file path and size
+func (c *ResponseCache) walk(fn func(path string, size int64, entry *CacheEntry) error) error {
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var entry CacheEntry
+		if err := json.Unmarshal(content, &entry); err != nil {
+			return fn(path, info.Size(), nil)
+		}
+		return fn(path, info.Size(), &entry)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Stats counts the entries of the cache, their size and age
+func (c *ResponseCache) Stats() (CacheStats, error) {
+	stats := CacheStats{ByModel: make(map[string]int)}
+	now := time.Now()
+	err := c.walk(func(path string, size int64, entry *CacheEntry) error {
+		stats.Entries++
+		stats.Bytes += size
+		if entry == nil {
+			stats.Expired++
+			return nil
+		}
+		if c.expired(entry.CreatedAt, now) {
+			stats.Expired++
+		}
+		if stats.Oldest.IsZero() || entry.CreatedAt.Before(stats.Oldest) {
+			stats.Oldest = entry.CreatedAt
+		}
+		if entry.CreatedAt.After(stats.Newest) {
+			stats.Newest = entry.CreatedAt
+		}
+		stats.ByModel[entry.Provider+"/"+entry.Model]++
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to read cache: %w", err)
+	}
+	return stats, nil
+}
+
+// Prune removes the expired and corrupted entries, and those older than olderThan when it is positive.
+// It returns the number of removed entries.
+func (c *ResponseCache) Prune(olderThan time.Duration) (int, error) {
+	now := time.Now()
+	removed := 0
+	err := c.walk(func(path string, size int64, entry *CacheEntry) error {
+		if entry != nil && !c.expired(entry.CreatedAt, now) && (olderThan <= 0 || now.Sub(entry.CreatedAt) <= olderThan) {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to prune cache: %w", err)
+	}
+	return removed, nil
+}
+
+// Clear removes every entry of the cache
+func (c *ResponseCache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, size int64, entry *CacheEntry) error {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return removed, nil
+}
+
+// SortedModels returns the provider/model names of the stats in alphabetical order
+func (s CacheStats) SortedModels() []string {
+	models := make([]string, 0, len(s.ByModel))
+	for model := range s.ByModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
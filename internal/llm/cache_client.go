@@ -0,0 +1,128 @@
+// internal/llm/cache_client.go
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// cachingClient answers from the response cache when the same request was already sent
+// with the same provider, model and temperature, and stores the responses of the other requests
+type cachingClient struct {
+	base        Client
+	cache       *ResponseCache
+	provider    string
+	model       string
+	temperature string
+	maxTokens   int
+}
+
+// cachingStructuredClient adds the cache to clients able to return schema-validated JSON
+type cachingStructuredClient struct {
+	*cachingClient
+}
+
+// NewCachingClient wraps a client with a response cache. The wrapper implements StructuredClient
+// only when the wrapped client does, so callers can keep detecting structured output support.
+func NewCachingClient(base Client, cache *ResponseCache, provider, model, temperature string, maxTokens int) Client {
+	client := &cachingClient{
+		base:        base,
+		cache:       cache,
+		provider:    provider,
+		model:       model,
+		temperature: temperature,
+		maxTokens:   maxTokens,
+	}
+	if _, ok := base.(StructuredClient); ok {
+		return &cachingStructuredClient{client}
+	}
+	return client
+}
+
+// validatorKey is the context key of the function validating the response of a request
+type validatorKey struct{}
+
+// WithValidator attaches to the context of a request the validation its response must pass
+// to be stored in the cache, or to be answered from it
+func WithValidator(ctx context.Context, validate func(response string) error) context.Context {
+	return context.WithValue(ctx, validatorKey{}, validate)
+}
+
+// validateResponse applies the validation attached to ctx, if any
+func validateResponse(ctx context.Context, response string) error {
+	if validate, ok := ctx.Value(validatorKey{}).(func(string) error); ok {
+		return validate(response)
+	}
+	return nil
+}
+
+// cached returns the cached response of a request, or calls the LLM and stores its response
+// once it passes the validation attached to ctx
+func (c *cachingClient) cached(ctx context.Context, kind string, parts []string, call func() (string, Usage, error)) (string, Usage, error) {
+	promptHash := HashPrompt(append([]string{kind, strconv.Itoa(c.maxTokens)}, parts...)...)
+	key := CacheKey(c.provider, c.model, c.temperature, promptHash)
+	if response, ok := c.cache.Get(key); ok {
+		if err := validateResponse(ctx, response); err != nil {
+			log.Debug("Ignoring invalid cached response for %s request %s: %v", kind, key[:12], err)
+		} else {
+			log.Debug("LLM cache hit for %s request %s", kind, key[:12])
+			return response, Usage{CachedRequests: 1}, nil
+		}
+	}
+
+	response, usage, err := call()
+	if err != nil || response == "" {
+		return response, usage, err
+	}
+	if err := validateResponse(ctx, response); err != nil {
+		log.Debug("Not caching invalid response for %s request %s: %v", kind, key[:12], err)
+		return response, usage, nil
+	}
+	entry := CacheEntry{
+		Key:         key,
+		Provider:    c.provider,
+		Model:       c.model,
+		Temperature: c.temperature,
+		PromptHash:  promptHash,
+		Response:    response,
+	}
+	if err := c.cache.Put(entry); err != nil {
+		log.Warning("Failed to cache LLM response: %v", err)
+	}
+	return response, usage, nil
+}
+
+func (c *cachingClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
+	return c.cached(ctx, "translate", []string{prompt, systemContext}, func() (string, Usage, error) {
+		return c.base.Translate(ctx, prompt, systemContext)
+	})
+}
+
+func (c *cachingClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return c.cached(ctx, "prompt", []string{formattedPrompt}, func() (string, Usage, error) {
+		return c.base.ProcessWithPrompt(ctx, promptTemplate, values)
+	})
+}
+
+func (c *cachingStructuredClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error marshalling JSON schema: %w", err)
+	}
+	return c.cached(ctx, "json", []string{formattedPrompt, schemaName, string(schemaJSON)}, func() (string, Usage, error) {
+		return c.base.(StructuredClient).ProcessWithPromptJSON(ctx, promptTemplate, values, schemaName, schema)
+	})
+}
@@ -0,0 +1,179 @@
+// internal/llm/cache_test.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCacheExpiresEntries(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	key := CacheKey("claude", "claude-3-5-sonnet-20240620", "default", HashPrompt("prompt"))
+
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+
+	assert.NoError(t, cache.Put(CacheEntry{Key: key, Response: "PSSI\tDocument"}))
+	response, ok := cache.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, "PSSI\tDocument", response)
+
+	// An entry older than the TTL is ignored
+	assert.NoError(t, cache.Put(CacheEntry{Key: key, Response: "PSSI\tDocument", CreatedAt: time.Now().Add(-2 * time.Hour)}))
+	_, ok = cache.Get(key)
+	assert.False(t, ok)
+
+	// A zero TTL never expires
+	cache.ttl = 0
+	_, ok = cache.Get(key)
+	assert.True(t, ok)
+}
+
+func TestResponseCachePruneAndStats(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewResponseCache(dir, 24*time.Hour)
+	now := time.Now()
+	entries := []CacheEntry{
+		{Key: HashPrompt("fresh"), Provider: "claude", Model: "claude-3-5-sonnet-20240620", CreatedAt: now.Add(-time.Minute)},
+		{Key: HashPrompt("old"), Provider: "claude", Model: "claude-3-5-sonnet-20240620", CreatedAt: now.Add(-3 * time.Hour)},
+		{Key: HashPrompt("expired"), Provider: "openai", Model: "gpt-4o", CreatedAt: now.Add(-48 * time.Hour)},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, cache.Put(entry))
+	}
+	corrupted := filepath.Join(dir, "zz", "corrupted.json")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(corrupted), 0755))
+	assert.NoError(t, os.WriteFile(corrupted, []byte("{"), 0644))
+
+	stats, err := cache.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Entries)
+	assert.Equal(t, 2, stats.Expired)
+	assert.Equal(t, map[string]int{"claude/claude-3-5-sonnet-20240620": 2, "openai/gpt-4o": 1}, stats.ByModel)
+	assert.Equal(t, []string{"claude/claude-3-5-sonnet-20240620", "openai/gpt-4o"}, stats.SortedModels())
+	assert.WithinDuration(t, entries[2].CreatedAt, stats.Oldest, time.Second)
+	assert.WithinDuration(t, entries[0].CreatedAt, stats.Newest, time.Second)
+
+	// The expired and corrupted entries go first, then those older than the given age
+	removed, err := cache.Prune(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	removed, err = cache.Prune(time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	_, ok := cache.Get(entries[0].Key)
+	assert.True(t, ok)
+
+	removed, err = cache.Clear()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	stats, err = cache.Stats()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestCachingClientKeysOnTheWholeRequest(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	base := &structuredScriptedClient{}
+	client := NewCachingClient(base, cache, "ollama", "llama3.1:8B", "default", 1000)
+	ctx := context.Background()
+
+	_, usage, err := client.Translate(ctx, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, usage.Requests)
+	result, usage, err := client.Translate(ctx, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument", result)
+	assert.Equal(t, Usage{CachedRequests: 1}, usage)
+	assert.Equal(t, 1, base.calls)
+
+	// The system context, the kind of request, max_tokens and the schema are part of the key
+	_, _, err = client.Translate(ctx, "prompt", "context")
+	assert.NoError(t, err)
+	_, _, err = client.ProcessWithPrompt(ctx, prompt.NewPromptTemplate("{text}"), map[string]string{"text": "prompt"})
+	assert.NoError(t, err)
+	_, _, err = NewCachingClient(base, cache, "ollama", "llama3.1:8B", "default", 2000).Translate(ctx, "prompt", "")
+	assert.NoError(t, err)
+	structured := client.(StructuredClient)
+	for _, schema := range []map[string]interface{}{{"type": "object"}, {"type": "array"}, {"type": "array"}} {
+		_, _, err = structured.ProcessWithPromptJSON(ctx, prompt.NewPromptTemplate("{text}"), map[string]string{"text": "prompt"}, "ontology", schema)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 6, base.calls)
+
+	// So are the provider, model and temperature
+	_, _, err = NewCachingClient(base, cache, "ollama", "mistral-nemo:12B", "default", 1000).Translate(ctx, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 7, base.calls)
+}
+
+func TestCachingClientPreservesStructuredOutput(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	_, ok := NewCachingClient(&scriptedClient{}, cache, "aiyou", "", "default", 1000).(StructuredClient)
+	assert.False(t, ok)
+
+	base := &structuredScriptedClient{}
+	structured, ok := NewCachingClient(base, cache, "openai", "gpt-4o", "0.7", 1000).(StructuredClient)
+	if assert.True(t, ok) {
+		result, _, err := structured.ProcessWithPromptJSON(context.Background(), prompt.NewPromptTemplate("{text}"), map[string]string{"text": "PSSI"}, "ontology", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "PSSI\tDocument", result)
+		assert.Equal(t, 1, base.calls)
+	}
+}
+
+func TestCachingClientStoresOnlyValidResponses(t *testing.T) {
+	cache := NewResponseCache(t.TempDir(), time.Hour)
+	base := &scriptedClient{}
+	client := NewCachingClient(base, cache, "claude", "claude-3-5-sonnet-20240620", "default", 1000)
+	invalid := WithValidator(context.Background(), func(response string) error {
+		if !strings.HasPrefix(response, "{") {
+			return errors.New("no JSON object found in response")
+		}
+		return nil
+	})
+
+	// The invalid response is returned to the caller but not stored
+	result, _, err := client.Translate(invalid, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument", result)
+	_, _, err = client.Translate(invalid, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, base.calls)
+
+	// A cached response failing the validation is not replayed
+	_, _, err = client.Translate(context.Background(), "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, base.calls)
+	_, _, err = client.Translate(invalid, "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, base.calls)
+}
+
+func TestGetClientWithoutCache(t *testing.T) {
+	cfg := config.GetConfig()
+	previousCache, previousDirectory := cfg.Cache, cfg.CacheDirectory
+	defer func() { cfg.Cache, cfg.CacheDirectory = previousCache, previousDirectory }()
+	cfg.CacheDirectory = t.TempDir()
+
+	cfg.Cache = true
+	client, err := GetClient("ollama", "llama3.1")
+	assert.NoError(t, err)
+	assert.IsType(t, &cachingStructuredClient{}, client)
+
+	// --no-cache sends every request to the provider
+	cfg.Cache = false
+	client, err = GetClient("ollama", "llama3.1")
+	assert.NoError(t, err)
+	assert.IsType(t, &resilientStructuredClient{}, client)
+}
@@ -4,11 +4,11 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/chrlesur/Ontology/internal/config"
@@ -53,120 +53,157 @@ func NewClaudeClient(apiKey string, model string) (*ClaudeClient, error) {
 }
 
 // Translate sends a prompt to the Claude API and returns the response
-func (c *ClaudeClient) Translate(prompt string, context string) (string, error) {
+func (c *ClaudeClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
 	log.Debug(i18n.Messages.TranslationStarted, "Claude", c.model)
-	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(context))
-
-	var result string
-	var err error
-	maxRetries := 5
-	baseDelay := time.Second * 10
-	maxDelay := time.Minute * 2
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		log.Debug("Attempt %d of %d", attempt+1, maxRetries)
-		result, err = c.makeRequest(prompt, context)
-		if err == nil {
-			log.Debug(i18n.Messages.TranslationCompleted, "Claude", c.model)
-			return result, nil
-		}
-
-		if !isRateLimitError(err) {
-			log.Warning(i18n.Messages.TranslationRetry, attempt+1, err)
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
+	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(systemContext))
 
-		delay := baseDelay * time.Duration(1<<uint(attempt))
-		if delay > maxDelay {
-			delay = maxDelay
-		}
-		log.Warning(i18n.Messages.RateLimitExceeded, delay)
-		time.Sleep(delay)
-	}
+	return c.makeRequest(ctx, prompt, systemContext)
+}
 
-	log.Error(i18n.Messages.TranslationFailed, err)
-	log.Debug("Translation completed. Result length: %d", len(result))
+// claudeResponse is the subset of the Messages API response used by the client
+type claudeResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+	Usage claudeUsage `json:"usage"`
+}
 
-	return "", fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+// claudeUsage is the token count reported by the Messages API
+type claudeUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
-func isRateLimitError(err error) bool {
-	return strings.Contains(err.Error(), "rate_limit_error")
+func (u claudeUsage) usage() Usage {
+	return Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens, Requests: 1}
 }
 
-func (c *ClaudeClient) makeRequest(prompt string, context string) (string, error) {
+func (c *ClaudeClient) makeRequest(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
 	log.Debug("Making request to Claude API")
-	url := config.GetConfig().ClaudeAPIURL
 
-	requestBody, err := json.Marshal(map[string]interface{}{
+	response, err := c.sendRequest(ctx, map[string]interface{}{
 		"model": c.model,
 		"messages": []map[string]string{
 			{"role": "user", "content": prompt},
 		},
-		"system":     context,
+		"system":     systemContext,
 		"max_tokens": c.config.MaxTokens,
 	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	if len(response.Content) == 0 {
+		log.Error("No content in response")
+		return "", Usage{}, fmt.Errorf("no content in response")
+	}
+	//log.Debug("Claude API Response : %s", response.Content)
+	log.Debug("Successfully received and parsed response from Claude API.")
+	return response.Content[0].Text, response.Usage.usage(), nil
+}
+
+// makeStructuredRequest forces Claude to answer through a tool whose input schema is the requested JSON schema
+func (c *ClaudeClient) makeStructuredRequest(ctx context.Context, prompt string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	log.Debug("Making structured request to Claude API with schema %s", schemaName)
+
+	response, err := c.sendRequest(ctx, map[string]interface{}{
+		"model": c.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": prompt},
+		},
+		"max_tokens": c.config.MaxTokens,
+		"tools": []map[string]interface{}{
+			{
+				"name":         schemaName,
+				"description":  "Record the extracted ontology elements",
+				"input_schema": schema,
+			},
+		},
+		"tool_choice": map[string]string{"type": "tool", "name": schemaName},
+	})
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	for _, block := range response.Content {
+		if block.Type == "tool_use" && len(block.Input) > 0 {
+			log.Debug("Successfully received structured response from Claude API.")
+			return string(block.Input), response.Usage.usage(), nil
+		}
+	}
+
+	log.Error("No tool_use block in structured response")
+	return "", Usage{}, fmt.Errorf("no tool_use block in response")
+}
+
+// sendRequest posts the request body to the Messages API and decodes the response
+func (c *ClaudeClient) sendRequest(ctx context.Context, body map[string]interface{}) (*claudeResponse, error) {
+	url := c.config.ClaudeAPIURL
+
+	requestBody, err := json.Marshal(body)
 	if err != nil {
 		log.Error("Error marshalling request: %v", err)
-		return "", fmt.Errorf("error marshalling request: %w", err)
+		return nil, fmt.Errorf("error marshalling request: %w", err)
 	}
 	//log.Debug("Claude API Request Body : %s", requestBody)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		log.Error("Error creating request: %v", err)
-		return "", fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("x-api-key", c.apiKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
 
-	//log.Debug("Sending request to Claude API : %s", prompt)
 	resp, err := c.client.Do(req)
 	if err != nil {
 		log.Error("Error sending request: %v", err)
-		return "", fmt.Errorf("error sending request: %w", err)
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Error("Error reading response: %v", err)
-		return "", fmt.Errorf("error reading response: %w", err)
+		return nil, fmt.Errorf("error reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		log.Error("API request failed with status code %d: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, string(body))
+		log.Error("API request failed with status code %d: %s", resp.StatusCode, string(respBody))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
-	var response struct {
-		Content []struct {
-			Text string `json:"text"`
-		} `json:"content"`
-	}
-
-	err = json.Unmarshal(body, &response)
+	var response claudeResponse
+	err = json.Unmarshal(respBody, &response)
 	if err != nil {
 		log.Error("Error unmarshalling response: %v", err)
-		return "", fmt.Errorf("error unmarshalling response: %w", err)
-	}
-
-	if len(response.Content) == 0 {
-		log.Error("No content in response")
-		return "", fmt.Errorf("no content in response")
+		return nil, fmt.Errorf("error unmarshalling response: %w", err)
 	}
-	//log.Debug("Claude API Response : %s", response.Content)
-	log.Debug("Successfully received and parsed response from Claude API.")
-	return response.Content[0].Text, nil
+	return &response, nil
 }
 
 // ProcessWithPrompt processes a prompt template with the given values and sends it to the Claude API
-func (c *ClaudeClient) ProcessWithPrompt(promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error) {
+func (c *ClaudeClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
 	log.Debug("Processing prompt with Claude")
-	formattedPrompt := promptTemplate.Format(values)
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
 
 	// Utilisez la méthode Translate existante pour envoyer le prompt formatté
-	return c.Translate(formattedPrompt, "")
+	return c.Translate(ctx, formattedPrompt, "")
+}
+
+// ProcessWithPromptJSON processes a prompt template and constrains Claude's answer to the given JSON schema
+func (c *ClaudeClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	log.Debug("Processing structured prompt with Claude")
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+
+	return c.makeStructuredRequest(ctx, formattedPrompt, schemaName, schema)
 }
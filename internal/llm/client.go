@@ -1,12 +1,44 @@
 package llm
 
 import (
+	"context"
+
 	"github.com/chrlesur/Ontology/internal/prompt"
 )
 
-// Client defines the interface for LLM clients
+// Client defines the interface for LLM clients. Cancelling ctx, or reaching its deadline,
+// aborts the request in flight and any retry.
 type Client interface {
-	// Translate takes a prompt and context, and returns the LLM's response
-	Translate(prompt string, context string) (string, error)
-	ProcessWithPrompt(promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error)
+	// Translate takes a prompt and context, and returns the LLM's response and the tokens it used
+	Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error)
+	ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error)
+}
+
+// StructuredClient is implemented by clients able to constrain their answer to a JSON schema
+type StructuredClient interface {
+	// ProcessWithPromptJSON formats the prompt and returns a JSON document matching the given schema
+	ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error)
+}
+
+// Usage counts the tokens billed for one or more requests
+type Usage struct {
+	InputTokens    int
+	OutputTokens   int
+	Requests       int    // requests sent to the provider
+	CachedRequests int    // requests answered from the response cache, not billed
+	Estimated      bool   // tokens counted locally because the provider does not report them
+	Provider       string // provider and model that answered, set when requests are routed
+	Model          string
+}
+
+// Add accumulates the usage of another request
+func (u *Usage) Add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.Requests += other.Requests
+	u.CachedRequests += other.CachedRequests
+	u.Estimated = u.Estimated || other.Estimated
+	if u.Model == "" {
+		u.Provider, u.Model = other.Provider, other.Model
+	}
 }
@@ -4,13 +4,15 @@ package llm
 
 import "time"
 
-
+// InitialRetryDelay and MaxRetryDelay bound the backoff between retries; retry_max_delay_seconds overrides MaxRetryDelay
 const (
-    MaxRetries        = 5
     InitialRetryDelay = 1 * time.Second
     MaxRetryDelay     = 32 * time.Second
 )
 
+// OpenAITemperature is the sampling temperature of OpenAI requests; the other providers use their default
+const OpenAITemperature = 0.7
+
 var ModelContextLimits = map[string]int{
     "GPT-4o":                 8192,
     "GPT-4o mini":            4096,
@@ -0,0 +1,67 @@
+// internal/llm/embedder.go
+
+package llm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/chrlesur/Ontology/internal/config"
+)
+
+// ErrInvalidEmbeddingProvider is returned for an unknown embedding provider
+var ErrInvalidEmbeddingProvider = errors.New("invalid embedding provider")
+
+// Embedder is implemented by providers able to turn texts into embedding vectors
+type Embedder interface {
+	// Embed returns one vector per input text, in the same order
+	Embed(texts []string) ([][]float32, error)
+	// Model identifies the embedding model; vectors of different models must never be compared
+	Model() string
+}
+
+// Default embedding models of each provider
+const (
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+)
+
+// GetEmbedder returns the embedder of the given provider: ollama, openai (or any OpenAI-compatible API) or hash.
+// An empty model selects the provider's default model.
+func GetEmbedder(provider string, model string) (Embedder, error) {
+	cfg := config.GetConfig()
+
+	switch provider {
+	case "ollama":
+		if model == "" {
+			model = DefaultOllamaEmbeddingModel
+		}
+		return NewOllamaEmbedder(cfg.OllamaEmbeddingAPIURL, model), nil
+	case "openai":
+		if model == "" {
+			model = DefaultOpenAIEmbeddingModel
+		}
+		return NewOpenAIEmbedder(cfg.EmbeddingAPIURL, cfg.OpenAIAPIKey, model)
+	case "hash":
+		return NewHashingEmbedder(0), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEmbeddingProvider, provider)
+	}
+}
+
+// embedInBatches splits the texts into batches of at most size texts and concatenates the vectors
+func embedInBatches(texts []string, size int, embed func(batch []string) ([][]float32, error)) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		batch, err := embed(texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(batch))
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
@@ -0,0 +1,90 @@
+// internal/llm/embedder_hash.go
+
+package llm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// defaultHashingDimensions is the size of the vectors of the hashing embedder
+const defaultHashingDimensions = 256
+
+// HashingEmbedder is a deterministic, local embedder: words and character trigrams are hashed into a fixed-size vector.
+// It needs no model or network and only captures spelling similarity, which makes it suitable for tests and offline runs.
+type HashingEmbedder struct {
+	dimensions int
+}
+
+// NewHashingEmbedder creates a hashing embedder; a zero dimension selects the default size
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	if dimensions <= 0 {
+		dimensions = defaultHashingDimensions
+	}
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+// Model identifies the hashing scheme and its dimension
+func (e *HashingEmbedder) Model() string {
+	return fmt.Sprintf("hash:%d", e.dimensions)
+}
+
+// Embed returns one L2-normalized vector per text
+func (e *HashingEmbedder) Embed(texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	add := func(feature string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(feature))
+		sum := h.Sum32()
+		// The hash bit above the index gives the sign, so that collisions cancel out on average
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vector[int(sum%uint32(e.dimensions))] += weight
+	}
+
+	words := strings.FieldsFunc(foldText(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		add("w:"+word, 1)
+		padded := []rune(" " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			add("t:"+string(padded[i:i+3]), 0.5)
+		}
+	}
+
+	var norm2 float64
+	for _, value := range vector {
+		norm2 += float64(value) * float64(value)
+	}
+	if norm2 > 0 {
+		scale := float32(1 / math.Sqrt(norm2))
+		for i := range vector {
+			vector[i] *= scale
+		}
+	}
+	return vector
+}
+
+// foldText lowercases a text and strips its accents
+func foldText(text string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, _ := transform.String(t, strings.ToLower(text))
+	return result
+}
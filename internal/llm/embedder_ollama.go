@@ -0,0 +1,76 @@
+// internal/llm/embedder_ollama.go
+
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// ollamaEmbeddingBatchSize bounds the number of texts sent in one request
+const ollamaEmbeddingBatchSize = 32
+
+// OllamaEmbedder computes embeddings with a local Ollama server
+type OllamaEmbedder struct {
+	url    string
+	model  string
+	client *http.Client
+}
+
+// NewOllamaEmbedder creates an embedder calling the Ollama /api/embed endpoint at the given URL
+func NewOllamaEmbedder(url string, model string) *OllamaEmbedder {
+	log.Debug("Creating new Ollama embedder with model: %s", model)
+	return &OllamaEmbedder{
+		url:    url,
+		model:  model,
+		client: &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// Model returns the Ollama embedding model
+func (e *OllamaEmbedder) Model() string {
+	return "ollama:" + e.model
+}
+
+// Embed returns one vector per text
+func (e *OllamaEmbedder) Embed(texts []string) ([][]float32, error) {
+	return embedInBatches(texts, ollamaEmbeddingBatchSize, e.embedBatch)
+}
+
+func (e *OllamaEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling embedding request: %w", err)
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewBuffer(requestBody))
+	if err != nil {
+		log.Error("Error sending embedding request: %v", err)
+		return nil, fmt.Errorf("error sending embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Error("Embedding request failed with status code %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("embedding request failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling embedding response: %w", err)
+	}
+	return response.Embeddings, nil
+}
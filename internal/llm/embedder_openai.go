@@ -0,0 +1,105 @@
+// internal/llm/embedder_openai.go
+
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// openAIEmbeddingBatchSize bounds the number of texts sent in one request
+const openAIEmbeddingBatchSize = 100
+
+// OpenAIEmbedder computes embeddings with the OpenAI embeddings API or any compatible server
+type OpenAIEmbedder struct {
+	url    string
+	apiKey string
+	model  string
+	client *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder calling the /v1/embeddings endpoint at the given URL.
+// The API key may be empty for compatible servers that do not require one, but not for api.openai.com.
+func NewOpenAIEmbedder(url string, apiKey string, model string) (*OpenAIEmbedder, error) {
+	log.Debug("Creating new OpenAI-compatible embedder with model: %s", model)
+	if apiKey == "" && url == defaultOpenAIEmbeddingURL {
+		log.Error("API key is missing for OpenAI embedder")
+		return nil, ErrAPIKeyMissing
+	}
+	return &OpenAIEmbedder{
+		url:    url,
+		apiKey: apiKey,
+		model:  model,
+		client: &http.Client{Timeout: 120 * time.Second},
+	}, nil
+}
+
+// defaultOpenAIEmbeddingURL is the embeddings endpoint of the OpenAI API
+const defaultOpenAIEmbeddingURL = "https://api.openai.com/v1/embeddings"
+
+// Model returns the embedding model
+func (e *OpenAIEmbedder) Model() string {
+	return "openai:" + e.model
+}
+
+// Embed returns one vector per text
+func (e *OpenAIEmbedder) Embed(texts []string) ([][]float32, error) {
+	return embedInBatches(texts, openAIEmbeddingBatchSize, e.embedBatch)
+}
+
+func (e *OpenAIEmbedder) embedBatch(texts []string) ([][]float32, error) {
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling embedding request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("error creating embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Error("Error sending embedding request: %v", err)
+		return nil, fmt.Errorf("error sending embedding request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedding response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Error("Embedding request failed with status code %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("embedding request failed with status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling embedding response: %w", err)
+	}
+	sort.Slice(response.Data, func(i, j int) bool { return response.Data[i].Index < response.Data[j].Index })
+
+	vectors := make([][]float32, len(response.Data))
+	for i, item := range response.Data {
+		vectors[i] = item.Embedding
+	}
+	return vectors, nil
+}
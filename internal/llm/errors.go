@@ -2,6 +2,9 @@ package llm
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/chrlesur/Ontology/internal/i18n"
 )
@@ -13,3 +16,32 @@ var (
 	ErrInvalidLLMType    = errors.New(i18n.Messages.ErrInvalidLLMType)
 	ErrContextTooLong    = errors.New(i18n.Messages.ErrContextTooLong)
 )
+
+// ErrCircuitOpen is returned without calling the provider while its circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// APIError is an error status returned by a provider's API
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // delay requested by the Retry-After header, 0 if absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API request failed with status code %d: %s", e.StatusCode, e.Body)
+}
+
+// RateLimited reports whether the provider rejected the request because of its rate limits
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// Transient reports whether the same request may succeed later: rate limiting, timeouts,
+// overload (529 for Claude) and server errors
+func (e *APIError) Transient() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+		return true
+	}
+	return e.StatusCode >= http.StatusInternalServerError
+}
@@ -3,14 +3,55 @@
 package llm
 
 import (
+	"context"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/chrlesur/Ontology/internal/config"
 )
 
+// GetClient returns the client of a provider, with retries, rate limiting and a circuit breaker,
+// answering from the response cache when it is enabled
 func GetClient(llmType string, model string) (Client, error) {
 	cfg := config.GetConfig()
 
+	client, err := newClient(llmType, model)
+	if err != nil {
+		return nil, err
+	}
+	client = NewResilientClient(client, llmType, cfg)
+	if !cfg.Cache {
+		return client, nil
+	}
+	cache := NewResponseCache(cfg.CacheDirectory, time.Duration(cfg.CacheTTLHours)*time.Hour)
+	return NewCachingClient(client, cache, llmType, model, providerTemperature(llmType), cfg.MaxTokens), nil
+}
+
+// GetRoutedClient returns the client of the selected provider and model, routed through the
+// routing rules and fallback chain of the configuration when there are any
+func GetRoutedClient(llmType string, model string) (Client, error) {
+	cfg := config.GetConfig()
+	if len(cfg.Fallback) == 0 && len(cfg.Routing) == 0 {
+		return GetClient(llmType, model)
+	}
+	return NewRouterClient(cfg, Link{Provider: llmType, Model: model}, func(link Link) (Client, error) {
+		return GetClient(link.Provider, link.Model)
+	})
+}
+
+// providerTemperature returns the sampling temperature sent by a provider's client, part of the cache key
+func providerTemperature(llmType string) string {
+	if llmType == "openai" {
+		return strconv.FormatFloat(OpenAITemperature, 'f', -1, 32)
+	}
+	return "default"
+}
+
+// newClient creates the client of a provider
+func newClient(llmType string, model string) (Client, error) {
+	cfg := config.GetConfig()
+
 	switch llmType {
 	case "openai":
 		return NewOpenAIClient(cfg.OpenAIAPIKey, model)
@@ -30,9 +71,9 @@ type contextCheckingClient struct {
 	model      string
 }
 
-func (c *contextCheckingClient) Translate(prompt string, context string) (string, error) {
-	if err := CheckContextLength(c.model, context); err != nil {
-		return "", err
+func (c *contextCheckingClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
+	if err := CheckContextLength(c.model, systemContext); err != nil {
+		return "", Usage{}, err
 	}
-	return c.baseClient.Translate(prompt, context)
+	return c.baseClient.Translate(ctx, prompt, systemContext)
 }
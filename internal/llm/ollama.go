@@ -2,6 +2,7 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -42,63 +43,38 @@ func NewOllamaClient(model string) (*OllamaClient, error) {
 	}, nil
 }
 
-func (c *OllamaClient) Translate(prompt string, context string) (string, error) {
+func (c *OllamaClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
 	log.Debug(i18n.Messages.TranslationStarted, "Ollama", c.model)
-	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(context))
-
-	var result string
-	var err error
-	maxRetries := 5
-	baseDelay := time.Second * 60
-	maxDelay := time.Minute * 5
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		log.Debug("Attempt %d of %d", attempt+1, maxRetries)
-		result, err = c.makeRequest(prompt, context)
-		if err == nil {
-			log.Debug(i18n.Messages.TranslationCompleted, "Ollama", c.model)
-			return result, nil
-		}
-
-		if !isRateLimitError(err) {
-			log.Warning(i18n.Messages.TranslationRetry, attempt+1, err)
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
-
-		delay := baseDelay * time.Duration(1<<uint(attempt))
-		if delay > maxDelay {
-			delay = maxDelay
-		}
-		log.Warning(i18n.Messages.RateLimitExceeded, delay)
-		time.Sleep(delay)
-	}
-
-	log.Error(i18n.Messages.TranslationFailed, err)
-	log.Debug("Translation completed. Result length: %d", len(result))
+	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(systemContext))
 
-	return "", fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	return c.makeRequest(ctx, prompt, systemContext, nil)
 }
 
-func (c *OllamaClient) makeRequest(prompt string, context string) (string, error) {
+// makeRequest sends the prompt to Ollama; a non-nil format constrains the answer to a JSON schema
+func (c *OllamaClient) makeRequest(ctx context.Context, prompt string, systemContext string, format map[string]interface{}) (string, Usage, error) {
 	log.Debug("Making request to Ollama API")
 	url := c.config.OllamaAPIURL
 
-	requestBody, err := json.Marshal(map[string]interface{}{
+	payload := map[string]interface{}{
 		"model":  c.model,
 		"prompt": prompt,
-		"system": context,
+		"system": systemContext,
 		"stream": false,
-	})
+	}
+	if format != nil {
+		payload["format"] = format
+	}
+
+	requestBody, err := json.Marshal(payload)
 	if err != nil {
 		log.Error("Error marshalling request: %v", err)
-		return "", fmt.Errorf("error marshalling request: %w", err)
+		return "", Usage{}, fmt.Errorf("error marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		log.Error("Error creating request: %v", err)
-		return "", fmt.Errorf("error creating request: %w", err)
+		return "", Usage{}, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -106,38 +82,53 @@ func (c *OllamaClient) makeRequest(prompt string, context string) (string, error
 	resp, err := c.client.Do(req)
 	if err != nil {
 		log.Error("Error sending request: %v", err)
-		return "", fmt.Errorf("error sending request: %w", err)
+		return "", Usage{}, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Error("Error reading response: %v", err)
-		return "", fmt.Errorf("error reading response: %w", err)
+		return "", Usage{}, fmt.Errorf("error reading response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		log.Error("API request failed with status code %d: %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API request failed with status code %d: %s", resp.StatusCode, string(body))
+		return "", Usage{}, &APIError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
 	}
 
 	var response struct {
-		Response string `json:"response"`
+		Response        string `json:"response"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
 	}
 
 	err = json.Unmarshal(body, &response)
 	if err != nil {
 		log.Error("Error unmarshalling response: %v", err)
-		return "", fmt.Errorf("error unmarshalling response: %w", err)
+		return "", Usage{}, fmt.Errorf("error unmarshalling response: %w", err)
 	}
 
 	log.Debug("Successfully received and parsed response from Ollama API.")
-	return response.Response, nil
+	return response.Response, Usage{InputTokens: response.PromptEvalCount, OutputTokens: response.EvalCount, Requests: 1}, nil
 }
 
-func (c *OllamaClient) ProcessWithPrompt(promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error) {
+func (c *OllamaClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
 	log.Debug("Processing prompt with Ollama")
-	formattedPrompt := promptTemplate.Format(values)
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+
+	return c.Translate(ctx, formattedPrompt, "")
+}
+
+func (c *OllamaClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	log.Debug("Processing structured prompt %s with Ollama", schemaName)
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
 
-	return c.Translate(formattedPrompt, "")
+	return c.makeRequest(ctx, formattedPrompt, "", schema)
 }
@@ -2,7 +2,10 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/chrlesur/Ontology/internal/config"
@@ -37,7 +40,9 @@ func NewOpenAIClient(apiKey string, model string) (*OpenAIClient, error) {
 		return nil, fmt.Errorf("%w: %s", ErrUnsupportedModel, model)
 	}
 
-	client := openai.NewClient(apiKey)
+	clientConfig := openai.DefaultConfig(apiKey)
+	clientConfig.HTTPClient = &http.Client{Transport: retryAfterTransport{base: http.DefaultTransport}}
+	client := openai.NewClientWithConfig(clientConfig)
 	return &OpenAIClient{
 		apiKey: apiKey,
 		model:  model,
@@ -46,45 +51,15 @@ func NewOpenAIClient(apiKey string, model string) (*OpenAIClient, error) {
 	}, nil
 }
 
-func (c *OpenAIClient) Translate(prompt string, context string) (string, error) {
+func (c *OpenAIClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
 	log.Debug(i18n.Messages.TranslationStarted, "OpenAI", c.model)
-	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(context))
-
-	var result string
-	var err error
-	maxRetries := 5
-	baseDelay := time.Second * 10
-	maxDelay := time.Minute * 2
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		log.Debug("Attempt %d of %d", attempt+1, maxRetries)
-		result, err = c.makeRequest(prompt, context)
-		if err == nil {
-			log.Debug(i18n.Messages.TranslationCompleted, "OpenAI", c.model)
-			return result, nil
-		}
-
-		if !isRateLimitError(err) {
-			log.Warning(i18n.Messages.TranslationRetry, attempt+1, err)
-			time.Sleep(time.Duration(attempt+1) * time.Second)
-			continue
-		}
-
-		delay := baseDelay * time.Duration(1<<uint(attempt))
-		if delay > maxDelay {
-			delay = maxDelay
-		}
-		log.Warning(i18n.Messages.RateLimitExceeded, delay)
-		time.Sleep(delay)
-	}
-
-	log.Error(i18n.Messages.TranslationFailed, err)
-	log.Debug("Translation completed. Result length: %d", len(result))
+	log.Debug("Starting Translate. Prompt length: %d, Context length: %d", len(prompt), len(systemContext))
 
-	return "", fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	return c.makeRequest(ctx, prompt, systemContext, nil)
 }
 
-func (c *OpenAIClient) makeRequest(prompt string, systemContext string) (string, error) {
+// makeRequest sends the prompt to OpenAI; a non-nil responseFormat constrains the answer format
+func (c *OpenAIClient) makeRequest(ctx context.Context, prompt string, systemContext string, responseFormat *openai.ChatCompletionResponseFormat) (string, Usage, error) {
 	log.Debug("Making request to OpenAI API")
 
 	messages := []openai.ChatCompletionMessage{
@@ -98,33 +73,74 @@ func (c *OpenAIClient) makeRequest(prompt string, systemContext string) (string,
 		},
 	}
 
+	var retryAfter time.Duration
 	resp, err := c.client.CreateChatCompletion(
-		context.Background(),
+		context.WithValue(ctx, retryAfterKey{}, &retryAfter),
 		openai.ChatCompletionRequest{
-			Model:       c.model,
-			Messages:    messages,
-			MaxTokens:   c.config.MaxTokens,
-			Temperature: 0.7,
+			Model:          c.model,
+			Messages:       messages,
+			MaxTokens:      c.config.MaxTokens,
+			Temperature:    OpenAITemperature,
+			ResponseFormat: responseFormat,
 		},
 	)
 
 	if err != nil {
 		log.Error("Error creating chat completion: %v", err)
-		return "", fmt.Errorf("error creating chat completion: %w", err)
+		return "", Usage{}, fmt.Errorf("error creating chat completion: %w", openAIError(err, retryAfter))
 	}
 
 	if len(resp.Choices) == 0 {
 		log.Error("No content in response")
-		return "", fmt.Errorf("no content in response")
+		return "", Usage{}, fmt.Errorf("no content in response")
 	}
 
 	log.Debug("Successfully received and parsed response from OpenAI API.")
-	return resp.Choices[0].Message.Content, nil
+	return resp.Choices[0].Message.Content, Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens, Requests: 1}, nil
 }
 
-func (c *OpenAIClient) ProcessWithPrompt(promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error) {
+// openAIError converts the status errors of the OpenAI SDK into an APIError
+func openAIError(err error, retryAfter time.Duration) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.HTTPStatusCode > 0 {
+		return &APIError{StatusCode: apiErr.HTTPStatusCode, Body: apiErr.Message, RetryAfter: retryAfter}
+	}
+	var requestErr *openai.RequestError
+	if errors.As(err, &requestErr) && requestErr.HTTPStatusCode > 0 {
+		return &APIError{StatusCode: requestErr.HTTPStatusCode, Body: string(requestErr.Body), RetryAfter: retryAfter}
+	}
+	return err
+}
+
+func (c *OpenAIClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
 	log.Debug("Processing prompt with OpenAI")
-	formattedPrompt := promptTemplate.Format(values)
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+
+	return c.Translate(ctx, formattedPrompt, "")
+}
+
+func (c *OpenAIClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	log.Debug("Processing structured prompt with OpenAI")
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error marshalling JSON schema: %w", err)
+	}
+	responseFormat := &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   schemaName,
+			Schema: json.RawMessage(schemaJSON),
+			Strict: true,
+		},
+	}
 
-	return c.Translate(formattedPrompt, "")
+	return c.makeRequest(ctx, formattedPrompt, "", responseFormat)
 }
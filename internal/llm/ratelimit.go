@@ -0,0 +1,215 @@
+// internal/llm/ratelimit.go
+
+package llm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+)
+
+// tokenBucket holds up to capacity units, refilled continuously at capacity per minute.
+// Reservations may overdraw it; the returned delay is the time until the debt is repaid.
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	perSecond float64
+	updated   time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		updated:   now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.available = math.Min(b.capacity, b.available+elapsed*b.perSecond)
+		b.updated = now
+	}
+}
+
+// reserve takes n units and returns how long to wait before using them
+func (b *tokenBucket) reserve(n float64, now time.Time) time.Duration {
+	b.refill(now)
+	b.available -= math.Min(n, b.capacity)
+	if b.available >= 0 {
+		return 0
+	}
+	return time.Duration(-b.available / b.perSecond * float64(time.Second))
+}
+
+// put gives back n units, or takes more when n is negative
+func (b *tokenBucket) put(n float64, now time.Time) {
+	b.refill(now)
+	b.available = math.Min(b.capacity, b.available+n)
+}
+
+// rateLimiter spaces the requests sent to a provider by all threads to stay under its requests
+// and tokens per minute, and holds them all back after the provider rejects one for rate limiting
+type rateLimiter struct {
+	mu          sync.Mutex
+	requests    *tokenBucket // nil without a requests per minute limit
+	tokens      *tokenBucket // nil without a tokens per minute limit
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+func newRateLimiter(limit config.RateLimit) *rateLimiter {
+	limiter := &rateLimiter{now: time.Now}
+	if limit.RequestsPerMinute > 0 {
+		limiter.requests = newTokenBucket(limit.RequestsPerMinute, limiter.now())
+	}
+	if limit.TokensPerMinute > 0 {
+		limiter.tokens = newTokenBucket(limit.TokensPerMinute, limiter.now())
+	}
+	return limiter
+}
+
+// reserve books a request of the given estimated tokens and returns how long to wait before sending it
+func (l *rateLimiter) reserve(tokens int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	delay := l.pausedUntil.Sub(now)
+	if l.requests != nil {
+		if wait := l.requests.reserve(1, now); wait > delay {
+			delay = wait
+		}
+	}
+	if l.tokens != nil {
+		if wait := l.tokens.reserve(float64(tokens), now); wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
+// wait books a request and blocks until it may be sent or ctx is done
+func (l *rateLimiter) wait(ctx context.Context, provider string, tokens int) error {
+	delay := l.reserve(tokens)
+	if delay <= 0 {
+		return nil
+	}
+	log.Debug("Waiting %v for the %s rate limit", delay, provider)
+	if err := sleepContext(ctx, delay); err != nil {
+		l.settle(tokens, Usage{}, err)
+		return err
+	}
+	return nil
+}
+
+// settle replaces the estimated tokens of a request by the tokens it used. A request that failed
+// without reporting usage gives its reservation back.
+func (l *rateLimiter) settle(estimated int, usage Usage, err error) {
+	if l.tokens == nil {
+		return
+	}
+	used := usage.InputTokens + usage.OutputTokens
+	if used == 0 && err == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens.put(float64(estimated-used), l.now())
+}
+
+// pause holds back every request to the provider for the given delay
+func (l *rateLimiter) pause(delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := l.now().Add(delay); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// circuitBreaker stops sending requests to a provider after consecutive failures. Once the cooldown
+// has elapsed, a single probe request is let through: its success closes the circuit, its failure
+// keeps it open for another cooldown.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int // 0 disables the breaker
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || b.now().Sub(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record counts the outcome of an allowed request; failed reports a provider failure,
+// as opposed to a success or an error of the request itself
+func (b *circuitBreaker) record(provider string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		if b.threshold > 0 && b.failures >= b.threshold {
+			log.Info("Circuit breaker for %s closed", provider)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openedAt = b.now()
+		log.Warning("Circuit breaker for %s opened after %d consecutive failures, retrying in %v", provider, b.failures, b.cooldown)
+	}
+}
+
+// release lets another probe through after an allowed request was abandoned before completing
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// providerGate is the rate limiter and circuit breaker shared by every client of a provider
+type providerGate struct {
+	limiter *rateLimiter
+	breaker *circuitBreaker
+}
+
+var (
+	gatesMu sync.Mutex
+	gates   = make(map[string]*providerGate)
+)
+
+// gateFor returns the gate of a provider, created from the configuration on first use
+func gateFor(provider string, cfg *config.Config) *providerGate {
+	gatesMu.Lock()
+	defer gatesMu.Unlock()
+	gate, ok := gates[provider]
+	if !ok {
+		gate = &providerGate{
+			limiter: newRateLimiter(cfg.RateLimits[provider]),
+			breaker: newCircuitBreaker(cfg.CircuitBreakerFailures, time.Duration(cfg.CircuitBreakerCooldownSeconds)*time.Second),
+		}
+		gates[provider] = gate
+	}
+	return gate
+}
@@ -0,0 +1,148 @@
+// internal/llm/resilient_client.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/i18n"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// resilientClient sends the requests of a provider's client through the provider's rate limiter
+// and circuit breaker, and retries transient failures with exponential backoff and jitter,
+// honouring the Retry-After delay of the provider
+type resilientClient struct {
+	base      Client
+	provider  string
+	policy    RetryPolicy
+	gate      *providerGate
+	maxTokens int
+}
+
+// resilientStructuredClient adds the retries and limits to clients able to return schema-validated JSON
+type resilientStructuredClient struct {
+	*resilientClient
+}
+
+// NewResilientClient wraps a provider's client with retries, rate limiting and a circuit breaker
+// configured by max_retries, rate_limits and circuit_breaker_*. The limits and the breaker are shared
+// by all the clients of the provider. The wrapper implements StructuredClient only when the wrapped client does.
+func NewResilientClient(base Client, provider string, cfg *config.Config) Client {
+	return newResilientClient(base, provider, retryPolicy(cfg), gateFor(provider, cfg), cfg.MaxTokens)
+}
+
+func newResilientClient(base Client, provider string, policy RetryPolicy, gate *providerGate, maxTokens int) Client {
+	client := &resilientClient{
+		base:      base,
+		provider:  provider,
+		policy:    policy,
+		gate:      gate,
+		maxTokens: maxTokens,
+	}
+	if _, ok := base.(StructuredClient); ok {
+		return &resilientStructuredClient{client}
+	}
+	return client
+}
+
+// ApproximateTokens estimates the tokens of texts without the tokenizer, at about four bytes per token
+func ApproximateTokens(texts ...string) int {
+	size := 0
+	for _, text := range texts {
+		size += len(text)
+	}
+	return (size + 3) / 4
+}
+
+// do sends a request, whose prompt holds about promptTokens tokens, until it succeeds, fails
+// with a permanent error, exhausts the retries or ctx is done
+func (c *resilientClient) do(ctx context.Context, promptTokens int, call func() (string, Usage, error)) (string, Usage, error) {
+	estimated := promptTokens + c.maxTokens
+	var err error
+	for attempt := 0; attempt <= c.policy.MaxRetries; attempt++ {
+		if !c.gate.breaker.allow() {
+			return "", Usage{}, fmt.Errorf("%w for %s", ErrCircuitOpen, c.provider)
+		}
+		if err := c.gate.limiter.wait(ctx, c.provider, estimated); err != nil {
+			c.gate.breaker.release()
+			return "", Usage{}, err
+		}
+
+		log.Debug("Attempt %d of %d", attempt+1, c.policy.MaxRetries+1)
+		var result string
+		var usage Usage
+		result, usage, err = call()
+		c.gate.limiter.settle(estimated, usage, err)
+		if err == nil {
+			c.gate.breaker.record(c.provider, false)
+			return result, usage, nil
+		}
+		if ctx.Err() != nil {
+			c.gate.breaker.release()
+			return "", Usage{}, ctx.Err()
+		}
+
+		var apiErr *APIError
+		rateLimited := errors.As(err, &apiErr) && apiErr.RateLimited()
+		// A rejected request (rate limit, bad request) shows that the provider is up
+		c.gate.breaker.record(c.provider, retryable(err) && !rateLimited)
+		if !retryable(err) || attempt == c.policy.MaxRetries {
+			break
+		}
+
+		var retryAfter time.Duration
+		if apiErr != nil {
+			retryAfter = apiErr.RetryAfter
+		}
+		if err := beforeRetry(ctx); err != nil {
+			return "", Usage{}, err
+		}
+		delay := c.policy.backoff(attempt, retryAfter)
+		if rateLimited {
+			c.gate.limiter.pause(delay)
+			log.Warning(i18n.Messages.RateLimitExceeded, delay)
+		} else {
+			log.Warning("%s request failed (attempt %d/%d), retrying in %v: %v", c.provider, attempt+1, c.policy.MaxRetries+1, delay, err)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return "", Usage{}, err
+		}
+	}
+
+	if !retryable(err) {
+		return "", Usage{}, err
+	}
+	log.Error(i18n.Messages.TranslationFailed, err)
+	return "", Usage{}, fmt.Errorf("%w: %w", ErrTranslationFailed, err)
+}
+
+func (c *resilientClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
+	return c.do(ctx, ApproximateTokens(prompt, systemContext), func() (string, Usage, error) {
+		return c.base.Translate(ctx, prompt, systemContext)
+	})
+}
+
+func (c *resilientClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return c.do(ctx, ApproximateTokens(formattedPrompt), func() (string, Usage, error) {
+		return c.base.ProcessWithPrompt(ctx, promptTemplate, values)
+	})
+}
+
+func (c *resilientStructuredClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return c.do(ctx, ApproximateTokens(formattedPrompt), func() (string, Usage, error) {
+		return c.base.(StructuredClient).ProcessWithPromptJSON(ctx, promptTemplate, values, schemaName, schema)
+	})
+}
@@ -0,0 +1,198 @@
+// internal/llm/resilient_client_test.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+// scriptedClient returns the given errors in turn, then succeeds
+type scriptedClient struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return "", Usage{}, s.errs[s.calls-1]
+	}
+	return "PSSI\tDocument", Usage{InputTokens: 10, OutputTokens: 5, Requests: 1}, nil
+}
+
+func (s *scriptedClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
+	return s.Translate(ctx, "", "")
+}
+
+func newTestGate(limit config.RateLimit, failures int) *providerGate {
+	return &providerGate{limiter: newRateLimiter(limit), breaker: newCircuitBreaker(failures, time.Minute)}
+}
+
+var testRetryPolicy = RetryPolicy{MaxRetries: 3, InitialDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+
+func TestResilientClientRetriesTransientErrors(t *testing.T) {
+	base := &scriptedClient{errs: []error{
+		&APIError{StatusCode: 529, Body: "overloaded"},
+		errors.New("error sending request: connection reset by peer"),
+	}}
+	client := newResilientClient(base, "claude", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+
+	result, usage, err := client.Translate(context.Background(), "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument", result)
+	assert.Equal(t, 1, usage.Requests)
+	assert.Equal(t, 3, base.calls)
+}
+
+func TestResilientClientStopsOnPermanentErrors(t *testing.T) {
+	badRequest := &APIError{StatusCode: http.StatusBadRequest, Body: "invalid model"}
+	base := &scriptedClient{errs: []error{badRequest}}
+	client := newResilientClient(base, "openai", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+
+	_, _, err := client.Translate(context.Background(), "prompt", "")
+	assert.ErrorIs(t, err, badRequest)
+	assert.Equal(t, 1, base.calls)
+
+	base = &scriptedClient{errs: []error{
+		&APIError{StatusCode: 503}, &APIError{StatusCode: 503}, &APIError{StatusCode: 503}, &APIError{StatusCode: 503},
+	}}
+	client = newResilientClient(base, "openai", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+	_, _, err = client.Translate(context.Background(), "prompt", "")
+	assert.ErrorIs(t, err, ErrTranslationFailed)
+	var apiErr *APIError
+	assert.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, 4, base.calls)
+}
+
+func TestResilientClientHonoursRetryAfter(t *testing.T) {
+	gate := newTestGate(config.RateLimit{}, 0)
+	base := &scriptedClient{errs: []error{&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Millisecond}}}
+	client := newResilientClient(base, "claude", testRetryPolicy, gate, 100)
+
+	start := time.Now()
+	_, _, err := client.Translate(context.Background(), "prompt", "")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	// The other threads were held back for the same delay
+	assert.False(t, gate.limiter.pausedUntil.Before(start.Add(20*time.Millisecond)))
+}
+
+// structuredScriptedClient also answers structured requests
+type structuredScriptedClient struct {
+	scriptedClient
+}
+
+func (s *structuredScriptedClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	return s.Translate(ctx, "", "")
+}
+
+func TestResilientClientStopsWhenRetryHookFails(t *testing.T) {
+	refused := errors.New("LLM budget exceeded")
+	base := &scriptedClient{errs: []error{&APIError{StatusCode: 503}, &APIError{StatusCode: 503}}}
+	client := newResilientClient(base, "claude", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+	retries := 0
+	ctx := WithRetryHook(context.Background(), func() error {
+		retries++
+		if retries > 1 {
+			return refused
+		}
+		return nil
+	})
+
+	_, _, err := client.Translate(ctx, "prompt", "")
+	assert.ErrorIs(t, err, refused)
+	assert.Equal(t, 2, base.calls)
+}
+
+func TestResilientClientPreservesStructuredOutput(t *testing.T) {
+	client := newResilientClient(&scriptedClient{}, "aiyou", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+	_, ok := client.(StructuredClient)
+	assert.False(t, ok)
+
+	base := &structuredScriptedClient{scriptedClient{errs: []error{&APIError{StatusCode: 500}}}}
+	client = newResilientClient(base, "ollama", testRetryPolicy, newTestGate(config.RateLimit{}, 0), 100)
+	structured, ok := client.(StructuredClient)
+	if assert.True(t, ok) {
+		_, _, err := structured.ProcessWithPromptJSON(context.Background(), prompt.NewPromptTemplate("{text}"), map[string]string{"text": "PSSI"}, "ontology", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, base.calls)
+	}
+}
+
+func TestCircuitBreakerOpensAndProbes(t *testing.T) {
+	now := time.Now()
+	gate := newTestGate(config.RateLimit{}, 2)
+	gate.breaker.now = func() time.Time { return now }
+	base := &scriptedClient{errs: []error{&APIError{StatusCode: 502}, &APIError{StatusCode: 502}}}
+	client := newResilientClient(base, "claude", RetryPolicy{MaxRetries: 0}, gate, 100)
+
+	for i := 0; i < 2; i++ {
+		_, _, err := client.Translate(context.Background(), "prompt", "")
+		assert.ErrorIs(t, err, ErrTranslationFailed)
+	}
+	_, _, err := client.Translate(context.Background(), "prompt", "")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, base.calls)
+
+	// After the cooldown a single probe is let through, and its success closes the circuit
+	now = now.Add(time.Minute)
+	_, _, err = client.Translate(context.Background(), "prompt", "")
+	assert.NoError(t, err)
+	assert.True(t, gate.breaker.allow())
+	assert.Equal(t, 0, gate.breaker.failures)
+}
+
+func TestCircuitBreakerIgnoresRateLimiting(t *testing.T) {
+	breaker := newCircuitBreaker(1, time.Minute)
+	gate := &providerGate{limiter: newRateLimiter(config.RateLimit{}), breaker: breaker}
+	base := &scriptedClient{errs: []error{&APIError{StatusCode: http.StatusTooManyRequests}}}
+	client := newResilientClient(base, "openai", RetryPolicy{MaxRetries: 0}, gate, 100)
+
+	_, _, err := client.Translate(context.Background(), "prompt", "")
+	assert.Error(t, err)
+	assert.True(t, breaker.allow())
+}
+
+func TestRateLimiterSpacesRequests(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(config.RateLimit{RequestsPerMinute: 60, TokensPerMinute: 600})
+	limiter.now = func() time.Time { return now }
+	limiter.requests.updated, limiter.tokens.updated = now, now
+
+	assert.Equal(t, time.Duration(0), limiter.reserve(100))
+	// A request larger than the bucket takes it whole: at 10 tokens per second, the 100 tokens missing wait 10 s
+	assert.Equal(t, 10*time.Second, limiter.reserve(1000))
+
+	// The tokens actually used are given back
+	limiter.settle(1000, Usage{InputTokens: 50, OutputTokens: 50}, nil)
+	assert.Equal(t, time.Duration(0), limiter.reserve(100))
+
+	limiter.pause(5 * time.Second)
+	assert.Equal(t, 5*time.Second, limiter.reserve(0))
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, InitialDelay: time.Second, MaxDelay: 8 * time.Second}
+	for attempt, ceiling := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		delay := policy.backoff(attempt, 0)
+		assert.GreaterOrEqual(t, delay, ceiling/2)
+		assert.LessOrEqual(t, delay, ceiling)
+	}
+	assert.Equal(t, 30*time.Second, policy.backoff(0, 30*time.Second))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 20*time.Second, parseRetryAfter("20", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
@@ -0,0 +1,121 @@
+// internal/llm/retry.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+)
+
+// RetryPolicy is how many times, and after which delay, a failed request is sent again
+type RetryPolicy struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+}
+
+// retryPolicy returns the retry policy of the configuration
+func retryPolicy(cfg *config.Config) RetryPolicy {
+	policy := RetryPolicy{MaxRetries: cfg.MaxRetries, InitialDelay: InitialRetryDelay, MaxDelay: MaxRetryDelay}
+	if policy.MaxRetries < 0 {
+		policy.MaxRetries = 0
+	}
+	if cfg.RetryMaxDelaySeconds > 0 {
+		policy.MaxDelay = time.Duration(cfg.RetryMaxDelaySeconds) * time.Second
+	}
+	return policy
+}
+
+// backoff returns the delay before the retry following the given attempt (0 for the first one):
+// the delay requested by the provider if any, otherwise an exponential delay capped at MaxDelay,
+// randomized over its upper half so that concurrent requests do not retry in lockstep
+func (p RetryPolicy) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	delay := p.MaxDelay
+	if attempt < 32 && p.InitialDelay<<uint(attempt) < p.MaxDelay {
+		delay = p.InitialDelay << uint(attempt)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryable reports whether a failed request may succeed when sent again. Provider errors are
+// retried only when transient; errors raised before the request is sent are never retried.
+func retryable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Transient()
+	}
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrCircuitOpen),
+		errors.Is(err, ErrContextTooLong), errors.Is(err, ErrUnsupportedModel), errors.Is(err, ErrAPIKeyMissing):
+		return false
+	}
+	return true
+}
+
+// retryHookKey is the context key of the function called before each new attempt of a request
+type retryHookKey struct{}
+
+// WithRetryHook attaches to the context of a request a function called before each retry or fallback
+// sent for it, for instance to charge the new attempt against a budget. When it fails, the request
+// stops with its error instead of being sent again.
+func WithRetryHook(ctx context.Context, hook func() error) context.Context {
+	return context.WithValue(ctx, retryHookKey{}, hook)
+}
+
+// beforeRetry calls the retry hook attached to ctx, if any
+func beforeRetry(ctx context.Context) error {
+	if hook, ok := ctx.Value(retryHookKey{}).(func() error); ok {
+		return hook()
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header, given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// retryAfterKey is the context key of the *time.Duration where retryAfterTransport records the Retry-After of a response
+type retryAfterKey struct{}
+
+// retryAfterTransport records the Retry-After header of responses for clients, such as the OpenAI SDK,
+// that do not expose the headers of failed requests
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		if retryAfter, ok := req.Context().Value(retryAfterKey{}).(*time.Duration); ok {
+			*retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+	}
+	return resp, err
+}
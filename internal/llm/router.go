@@ -0,0 +1,277 @@
+// internal/llm/router.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// Tasks of the requests, matched by the routing rules
+const (
+	TaskSegment = "segment"
+	TaskMerge   = "merge"
+)
+
+// ErrAllProvidersFailed is returned when every link of the fallback chain failed
+var ErrAllProvidersFailed = errors.New("all LLM providers failed")
+
+// Route describes a request for the routing rules
+type Route struct {
+	Task   string
+	Tokens int // approximate size of the segment or of the merged results, 0 to measure the whole prompt
+}
+
+// routeKey is the context key of the Route of a request
+type routeKey struct{}
+
+// WithRoute attaches the route of a request to its context
+func WithRoute(ctx context.Context, route Route) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// Link is a provider and model of a fallback chain or routing rule
+type Link struct {
+	Provider string
+	Model    string
+}
+
+func (l Link) String() string {
+	if l.Model == "" {
+		return l.Provider
+	}
+	return l.Provider + ":" + l.Model
+}
+
+// ParseLink parses a provider:model link. The model may only be omitted for aiyou,
+// whose model is its assistant; everything after the first colon is the model, as in ollama:llama3.1:8B.
+func ParseLink(spec string) (Link, error) {
+	provider, model, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	link := Link{Provider: provider, Model: model}
+	switch provider {
+	case "openai", "claude", "ollama":
+		if model == "" {
+			return Link{}, fmt.Errorf("no model in LLM link %q, expected provider:model", spec)
+		}
+	case "aiyou":
+	default:
+		return Link{}, fmt.Errorf("%w in LLM link %q", ErrInvalidLLMType, spec)
+	}
+	return link, nil
+}
+
+// RoutedModels returns the models a request may be sent to: the selected one, then the routing
+// rules and the fallback chain of the configuration
+func RoutedModels(cfg *config.Config, selected Link) ([]Link, error) {
+	links := []Link{selected}
+	seen := map[Link]bool{selected: true}
+	add := func(link Link) {
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	for _, rule := range cfg.Routing {
+		if rule.Task != "" && rule.Task != TaskSegment && rule.Task != TaskMerge {
+			return nil, fmt.Errorf("unknown task %q in routing rule, expected %s or %s", rule.Task, TaskSegment, TaskMerge)
+		}
+		link, err := ParseLink(rule.LLM + ":" + rule.Model)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routing rule: %w", err)
+		}
+		add(link)
+	}
+	for _, spec := range cfg.Fallback {
+		link, err := ParseLink(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fallback: %w", err)
+		}
+		add(link)
+	}
+	return links, nil
+}
+
+// routedLink is a link with its client
+type routedLink struct {
+	Link
+	client Client
+}
+
+// routingRule is a rule of the configuration with the client of its link
+type routingRule struct {
+	task      string
+	maxTokens int
+	link      routedLink
+}
+
+func (r routingRule) matches(route Route) bool {
+	return (r.task == "" || r.task == route.Task) && (r.maxTokens <= 0 || route.Tokens <= r.maxTokens)
+}
+
+// routerClient sends each request to the model of the first matching routing rule, or to the selected
+// model, and falls back along the chain when a model fails, including when the request is too long for it
+type routerClient struct {
+	chain []routedLink // the selected model, then the fallback chain
+	rules []routingRule
+}
+
+// routerStructuredClient sends structured requests to the links able to answer them
+type routerStructuredClient struct {
+	*routerClient
+}
+
+// NewRouterClient returns a client routing the requests of the selected model through the routing
+// rules and fallback chain of the configuration, with one client per link created by newLinkClient.
+// It implements StructuredClient when one of its links does.
+func NewRouterClient(cfg *config.Config, selected Link, newLinkClient func(Link) (Client, error)) (Client, error) {
+	links, err := RoutedModels(cfg, selected)
+	if err != nil {
+		return nil, err
+	}
+	clients := make(map[Link]routedLink, len(links))
+	structured := false
+	for _, link := range links {
+		client, err := newLinkClient(link)
+		if err != nil {
+			return nil, fmt.Errorf("LLM %s: %w", link, err)
+		}
+		if _, ok := client.(StructuredClient); ok {
+			structured = true
+		}
+		clients[link] = routedLink{Link: link, client: client}
+	}
+
+	router := &routerClient{chain: []routedLink{clients[selected]}}
+	for _, spec := range cfg.Fallback {
+		link, _ := ParseLink(spec)
+		router.chain = append(router.chain, clients[link])
+	}
+	for _, rule := range cfg.Routing {
+		link, _ := ParseLink(rule.LLM + ":" + rule.Model)
+		router.rules = append(router.rules, routingRule{task: rule.Task, maxTokens: rule.MaxTokens, link: clients[link]})
+	}
+	if structured {
+		return &routerStructuredClient{router}, nil
+	}
+	return router, nil
+}
+
+// links returns the links to try in order for a request
+func (r *routerClient) links(route Route) []routedLink {
+	var links []routedLink
+	seen := make(map[Link]bool)
+	add := func(link routedLink) {
+		if !seen[link.Link] {
+			seen[link.Link] = true
+			links = append(links, link)
+		}
+	}
+	for _, rule := range r.rules {
+		if rule.matches(route) {
+			add(rule.link)
+			break
+		}
+	}
+	for _, link := range r.chain {
+		add(link)
+	}
+	return links
+}
+
+// requestRoute returns the route of a request, measuring the prompt when the caller did not give its size
+func requestRoute(ctx context.Context, prompt string) Route {
+	route, _ := ctx.Value(routeKey{}).(Route)
+	if route.Tokens <= 0 {
+		route.Tokens = ApproximateTokens(prompt)
+	}
+	return route
+}
+
+// isContextOverflow reports whether a request failed because it is too long for the model
+func isContextOverflow(err error) bool {
+	if errors.Is(err, ErrContextTooLong) {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusBadRequest && apiErr.StatusCode != http.StatusRequestEntityTooLarge) {
+		return false
+	}
+	body := strings.ToLower(apiErr.Body)
+	for _, marker := range []string{"context_length_exceeded", "context length", "prompt is too long", "too many tokens"} {
+		if strings.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// do sends a request to each link in turn until one answers; structured requests skip the links
+// unable to answer them. The usage of the answer names the link that produced it.
+func (r *routerClient) do(ctx context.Context, route Route, structured bool, call func(Client) (string, Usage, error)) (string, Usage, error) {
+	var tried []string
+	var lastErr error
+	for _, link := range r.links(route) {
+		if _, ok := link.client.(StructuredClient); structured && !ok {
+			log.Debug("Skipping %s, which does not support structured output", link)
+			continue
+		}
+		if lastErr != nil {
+			if err := beforeRetry(ctx); err != nil {
+				return "", Usage{}, err
+			}
+			log.Warning("Falling back to %s", link)
+		}
+
+		result, usage, err := call(link.client)
+		if err == nil {
+			usage.Provider, usage.Model = link.Provider, link.Model
+			return result, usage, nil
+		}
+		if ctx.Err() != nil {
+			return "", Usage{}, ctx.Err()
+		}
+		if isContextOverflow(err) {
+			log.Warning("Request of %d tokens too long for %s: %v", route.Tokens, link, err)
+		} else {
+			log.Warning("%s %s request failed: %v", link, route.Task, err)
+		}
+		tried = append(tried, link.String())
+		lastErr = err
+	}
+	if lastErr == nil {
+		return "", Usage{}, fmt.Errorf("no LLM of the fallback chain supports structured output")
+	}
+	return "", Usage{}, fmt.Errorf("%w (%s): %w", ErrAllProvidersFailed, strings.Join(tried, ", "), lastErr)
+}
+
+func (r *routerClient) Translate(ctx context.Context, prompt string, systemContext string) (string, Usage, error) {
+	return r.do(ctx, requestRoute(ctx, prompt+systemContext), false, func(client Client) (string, Usage, error) {
+		return client.Translate(ctx, prompt, systemContext)
+	})
+}
+
+func (r *routerClient) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return r.do(ctx, requestRoute(ctx, formattedPrompt), false, func(client Client) (string, Usage, error) {
+		return client.ProcessWithPrompt(ctx, promptTemplate, values)
+	})
+}
+
+func (r *routerStructuredClient) ProcessWithPromptJSON(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string, schemaName string, schema map[string]interface{}) (string, Usage, error) {
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		return "", Usage{}, fmt.Errorf("error formatting prompt: %w", err)
+	}
+	return r.do(ctx, requestRoute(ctx, formattedPrompt), true, func(client Client) (string, Usage, error) {
+		return client.(StructuredClient).ProcessWithPromptJSON(ctx, promptTemplate, values, schemaName, schema)
+	})
+}
@@ -0,0 +1,152 @@
+// internal/llm/router_test.go
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRouter builds a router whose links are scripted clients, returned by link
+func newTestRouter(t *testing.T, cfg *config.Config, selected Link, clients map[Link]Client) Client {
+	router, err := NewRouterClient(cfg, selected, func(link Link) (Client, error) {
+		client, ok := clients[link]
+		if !ok {
+			return nil, ErrAPIKeyMissing
+		}
+		return client, nil
+	})
+	assert.NoError(t, err)
+	return router
+}
+
+func TestParseLink(t *testing.T) {
+	link, err := ParseLink("ollama:llama3.1:8B")
+	assert.NoError(t, err)
+	assert.Equal(t, Link{Provider: "ollama", Model: "llama3.1:8B"}, link)
+	assert.Equal(t, "ollama:llama3.1:8B", link.String())
+
+	link, err = ParseLink("aiyou")
+	assert.NoError(t, err)
+	assert.Equal(t, "aiyou", link.String())
+
+	_, err = ParseLink("openai")
+	assert.Error(t, err)
+	_, err = ParseLink("mistral:large")
+	assert.ErrorIs(t, err, ErrInvalidLLMType)
+}
+
+func TestRouterFallsBackAlongTheChain(t *testing.T) {
+	claude := Link{Provider: "claude", Model: "claude-3-5-sonnet-20240620"}
+	openai := Link{Provider: "openai", Model: "gpt-4o"}
+	ollama := Link{Provider: "ollama", Model: "llama3.1:8B"}
+	failing := &scriptedClient{errs: []error{ErrCircuitOpen}}
+	tooLong := &scriptedClient{errs: []error{&APIError{StatusCode: http.StatusBadRequest, Body: `{"error":{"code":"context_length_exceeded"}}`}}}
+	local := &scriptedClient{}
+	cfg := &config.Config{Fallback: []string{"openai:gpt-4o", "ollama:llama3.1:8B"}}
+	router := newTestRouter(t, cfg, claude, map[Link]Client{claude: failing, openai: tooLong, ollama: local})
+
+	result, usage, err := router.Translate(context.Background(), "prompt", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument", result)
+	assert.Equal(t, "ollama", usage.Provider)
+	assert.Equal(t, "llama3.1:8B", usage.Model)
+	assert.Equal(t, []int{1, 1, 1}, []int{failing.calls, tooLong.calls, local.calls})
+	assert.True(t, isContextOverflow(tooLong.errs[0]))
+
+	// Every link failed: the errors of the chain are reported
+	local.errs = []error{errors.New("connection refused"), errors.New("connection refused")}
+	failing.calls, tooLong.calls, local.calls = 0, 0, 0
+	_, _, err = router.Translate(context.Background(), "prompt", "")
+	assert.ErrorIs(t, err, ErrAllProvidersFailed)
+	assert.ErrorContains(t, err, "claude:claude-3-5-sonnet-20240620, openai:gpt-4o, ollama:llama3.1:8B")
+}
+
+func TestRouterAppliesRoutingRules(t *testing.T) {
+	sonnet := Link{Provider: "claude", Model: "claude-3-5-sonnet-20240620"}
+	haiku := Link{Provider: "claude", Model: "claude-3-haiku-20240307"}
+	opus := Link{Provider: "claude", Model: "claude-3-opus-20240229"}
+	clients := map[Link]Client{sonnet: &scriptedClient{}, haiku: &scriptedClient{}, opus: &scriptedClient{}}
+	cfg := &config.Config{Routing: []config.RoutingRule{
+		{Task: TaskSegment, MaxTokens: 500, LLM: "claude", Model: "claude-3-haiku-20240307"},
+		{Task: TaskMerge, LLM: "claude", Model: "claude-3-opus-20240229"},
+	}}
+	router := newTestRouter(t, cfg, sonnet, clients)
+
+	answeredBy := func(route Route) string {
+		_, usage, err := router.Translate(WithRoute(context.Background(), route), "prompt", "")
+		assert.NoError(t, err)
+		return usage.Model
+	}
+	assert.Equal(t, haiku.Model, answeredBy(Route{Task: TaskSegment, Tokens: 200}))
+	assert.Equal(t, sonnet.Model, answeredBy(Route{Task: TaskSegment, Tokens: 2000}))
+	assert.Equal(t, opus.Model, answeredBy(Route{Task: TaskMerge, Tokens: 200}))
+
+	// The selected model takes over when the routed one fails
+	clients[haiku].(*scriptedClient).errs = []error{&APIError{StatusCode: 529}}
+	clients[haiku].(*scriptedClient).calls = 0
+	assert.Equal(t, sonnet.Model, answeredBy(Route{Task: TaskSegment, Tokens: 200}))
+}
+
+func TestRouterSkipsLinksWithoutStructuredOutput(t *testing.T) {
+	aiyou := Link{Provider: "aiyou"}
+	ollama := Link{Provider: "ollama", Model: "llama3.1:8B"}
+	unstructured := &scriptedClient{}
+	structured := &structuredScriptedClient{}
+	cfg := &config.Config{Fallback: []string{"ollama:llama3.1:8B"}}
+	router := newTestRouter(t, cfg, aiyou, map[Link]Client{aiyou: unstructured, ollama: structured})
+
+	structuredRouter, ok := router.(StructuredClient)
+	if assert.True(t, ok) {
+		_, usage, err := structuredRouter.ProcessWithPromptJSON(context.Background(), prompt.NewPromptTemplate("{text}"), map[string]string{"text": "PSSI"}, "ontology", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "ollama", usage.Provider)
+		assert.Equal(t, 0, unstructured.calls)
+	}
+}
+
+func TestRouterAsksBeforeFallingBack(t *testing.T) {
+	claude := Link{Provider: "claude", Model: "claude-3-5-sonnet-20240620"}
+	openai := Link{Provider: "openai", Model: "gpt-4o"}
+	refused := errors.New("LLM budget exceeded")
+	first := &scriptedClient{errs: []error{ErrCircuitOpen}}
+	second := &scriptedClient{}
+	router := newTestRouter(t, &config.Config{Fallback: []string{"openai:gpt-4o"}}, claude, map[Link]Client{claude: first, openai: second})
+
+	ctx := WithRetryHook(context.Background(), func() error { return refused })
+	_, _, err := router.Translate(ctx, "prompt", "")
+	assert.ErrorIs(t, err, refused)
+	assert.Equal(t, 0, second.calls)
+}
+
+func TestRouterStopsWhenCancelled(t *testing.T) {
+	claude := Link{Provider: "claude", Model: "claude-3-5-sonnet-20240620"}
+	openai := Link{Provider: "openai", Model: "gpt-4o"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	first := &scriptedClient{errs: []error{context.Canceled}}
+	second := &scriptedClient{}
+	router := newTestRouter(t, &config.Config{Fallback: []string{"openai:gpt-4o"}}, claude, map[Link]Client{claude: first, openai: second})
+
+	_, _, err := router.Translate(ctx, "prompt", "")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, second.calls)
+}
+
+func TestRoutedModelsRejectsInvalidRules(t *testing.T) {
+	selected := Link{Provider: "claude", Model: "claude-3-5-sonnet-20240620"}
+	_, err := RoutedModels(&config.Config{Routing: []config.RoutingRule{{Task: "summary", LLM: "openai", Model: "gpt-4o"}}}, selected)
+	assert.Error(t, err)
+	_, err = RoutedModels(&config.Config{Fallback: []string{"gpt-4o"}}, selected)
+	assert.Error(t, err)
+
+	links, err := RoutedModels(&config.Config{Fallback: []string{"openai:gpt-4o", "claude:claude-3-5-sonnet-20240620"}}, selected)
+	assert.NoError(t, err)
+	assert.Equal(t, []Link{selected, {Provider: "openai", Model: "gpt-4o"}}, links)
+}
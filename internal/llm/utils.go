@@ -1,6 +1,9 @@
 package llm
 
 import (
+	"context"
+	"time"
+
 	"github.com/chrlesur/Ontology/internal/tokenizer"
 )
 
@@ -21,3 +24,15 @@ func CheckContextLength(model string, context string) error {
 
 	return nil
 }
+
+// sleepContext waits before a retry, returning the context's error if it is cancelled first
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,24 @@
+// metadata/coverage.go
+
+package metadata
+
+// FailedSegment est un segment dont le traitement a échoué au cours d'une passe
+type FailedSegment struct {
+	Pass     int    `json:"pass"`
+	Segment  int    `json:"segment"` // numéro du segment dans la passe, à partir de 1
+	Start    int    `json:"start"`   // position du segment dans le contenu de la passe, en octets
+	End      int    `json:"end"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+// CoverageReport indique quelle part des segments a été traitée, enregistré dans _meta.json
+type CoverageReport struct {
+	RunID          string          `json:"run_id,omitempty"` // checkpoint à reprendre avec --resume pour ne relancer que les segments en échec
+	Policy         string          `json:"policy"`           // politique appliquée aux segments en échec
+	Segments       int             `json:"segments"`         // segments de toutes les passes
+	Processed      int             `json:"processed"`
+	Coverage       float64         `json:"coverage"` // part des segments traités, de 0 à 1
+	MinCoverage    float64         `json:"min_coverage,omitempty"`
+	FailedSegments []FailedSegment `json:"failed_segments"`
+}
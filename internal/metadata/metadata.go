@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -34,6 +35,8 @@ type ProjectMetadata struct {
 	ContextFile    string                  `json:"context_file,omitempty"`
 	ProcessingDate time.Time               `json:"processing_date"`
 	Files          map[string]FileMetadata `json:"files"`
+	Usage          *UsageReport            `json:"usage,omitempty"`
+	Coverage       *CoverageReport         `json:"coverage,omitempty"`
 }
 
 type s3FileInfo struct {
@@ -143,7 +146,7 @@ func (g *Generator) GenerateSingleFileMetadata(sourcePath string) (*FileMetadata
         hash = ""
     }
 
-    id := generateUniqueID(sourcePath)
+    id := FileID(sourcePath)
     metadata := &FileMetadata{
         ID:             id,
         SourceFile:     filepath.Base(sourcePath),
@@ -213,6 +216,78 @@ func (g *Generator) calculateSHA256(filePath string) (string, error) {
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
 
+// LoadMetadata lit les métadonnées du projet enregistrées par une exécution précédente
+func (g *Generator) LoadMetadata(path string) (*ProjectMetadata, error) {
+	g.logger.Debug("Loading project metadata from file: %s", path)
+
+	data, err := g.storage.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project metadata file: %w", err)
+	}
+
+	var metadata ProjectMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal project metadata: %w", err)
+	}
+	return &metadata, nil
+}
+
+// Path retourne le chemin complet du fichier source
+func (f FileMetadata) Path() string {
+	return filepath.Join(f.Directory, f.SourceFile)
+}
+
+// FileChanges classe les fichiers sources par rapport à une exécution précédente
+type FileChanges struct {
+	Added     []string
+	Modified  []string
+	Unchanged []string
+	Deleted   []string
+}
+
+// Changed retourne les fichiers à retraiter : nouveaux ou modifiés
+func (c FileChanges) Changed() []string {
+	return append(append([]string{}, c.Added...), c.Modified...)
+}
+
+// CompareMetadata compare les empreintes SHA-256 des fichiers de deux exécutions.
+// Un fichier dont l'empreinte n'a pas pu être calculée est considéré comme modifié.
+func CompareMetadata(previous, current *ProjectMetadata) FileChanges {
+	previousFiles := make(map[string]FileMetadata)
+	if previous != nil {
+		for _, file := range previous.Files {
+			previousFiles[file.Path()] = file
+		}
+	}
+
+	var changes FileChanges
+	seen := make(map[string]bool)
+	for _, file := range current.Files {
+		path := file.Path()
+		seen[path] = true
+		old, ok := previousFiles[path]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, path)
+		case old.SHA256Hash == "" || old.SHA256Hash != file.SHA256Hash:
+			changes.Modified = append(changes.Modified, path)
+		default:
+			changes.Unchanged = append(changes.Unchanged, path)
+		}
+	}
+	for path := range previousFiles {
+		if !seen[path] {
+			changes.Deleted = append(changes.Deleted, path)
+		}
+	}
+
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Unchanged)
+	sort.Strings(changes.Deleted)
+	return changes
+}
+
 // GetMetadataFilename génère le nom du fichier de métadonnées
 func (g *Generator) GetMetadataFilename(sourcePath string) string {
 	baseFileName := filepath.Base(sourcePath)
@@ -221,7 +296,8 @@ func (g *Generator) GetMetadataFilename(sourcePath string) string {
 	return nameWithoutExt + "_meta.json"
 }
 
-// generateUniqueID génère un ID unique pour un fichier
-func generateUniqueID(sourcePath string) string {
-	return fmt.Sprintf("%x", sha256.Sum256([]byte(sourcePath+time.Now().String())))[:12]
+// FileID génère l'ID unique d'un fichier.
+// Il ne dépend que du chemin afin qu'un même fichier garde son ID d'une exécution à l'autre.
+func FileID(sourcePath string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(sourcePath)))[:12]
 }
@@ -0,0 +1,33 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareMetadata(t *testing.T) {
+	previous := &ProjectMetadata{Files: map[string]FileMetadata{
+		"a": {SourceFile: "kept.md", Directory: "/corpus", SHA256Hash: "111"},
+		"b": {SourceFile: "edited.md", Directory: "/corpus", SHA256Hash: "222"},
+		"c": {SourceFile: "removed.md", Directory: "/corpus", SHA256Hash: "333"},
+	}}
+	current := &ProjectMetadata{Files: map[string]FileMetadata{
+		"a": {SourceFile: "kept.md", Directory: "/corpus", SHA256Hash: "111"},
+		"b": {SourceFile: "edited.md", Directory: "/corpus", SHA256Hash: "999"},
+		"d": {SourceFile: "new.md", Directory: "/corpus", SHA256Hash: "444"},
+	}}
+
+	changes := CompareMetadata(previous, current)
+
+	assert.Equal(t, []string{"/corpus/new.md"}, changes.Added)
+	assert.Equal(t, []string{"/corpus/edited.md"}, changes.Modified)
+	assert.Equal(t, []string{"/corpus/kept.md"}, changes.Unchanged)
+	assert.Equal(t, []string{"/corpus/removed.md"}, changes.Deleted)
+	assert.Equal(t, []string{"/corpus/new.md", "/corpus/edited.md"}, changes.Changed())
+}
+
+func TestFileIDIsStable(t *testing.T) {
+	assert.Equal(t, FileID("/corpus/a.md"), FileID("/corpus/a.md"))
+	assert.NotEqual(t, FileID("/corpus/a.md"), FileID("/corpus/b.md"))
+}
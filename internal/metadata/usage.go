@@ -0,0 +1,49 @@
+// metadata/usage.go
+
+package metadata
+
+// TokenUsage cumule les tokens et le coût d'un ensemble d'appels au LLM
+type TokenUsage struct {
+	Requests       int     `json:"requests"`
+	CachedRequests int     `json:"cached_requests"`
+	InputTokens    int     `json:"input_tokens"`
+	OutputTokens   int     `json:"output_tokens"`
+	Cost           float64 `json:"cost"`
+	Estimated      bool    `json:"estimated,omitempty"` // tokens comptés localement, le fournisseur ne les retournant pas
+}
+
+// Add ajoute les tokens et le coût d'autres appels
+func (u *TokenUsage) Add(other TokenUsage) {
+	u.Requests += other.Requests
+	u.CachedRequests += other.CachedRequests
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.Cost += other.Cost
+	u.Estimated = u.Estimated || other.Estimated
+}
+
+// PassUsage détaille la consommation d'une passe entre le traitement des segments et la fusion
+type PassUsage struct {
+	Pass     int        `json:"pass"`
+	Segments TokenUsage `json:"segments"`
+	Merge    TokenUsage `json:"merge"`
+}
+
+// SegmentUsage est la consommation d'un segment au cours d'une passe
+type SegmentUsage struct {
+	Pass    int `json:"pass"`
+	Segment int `json:"segment"` // numéro du segment dans la passe, à partir de 1
+	TokenUsage
+}
+
+// UsageReport est le rapport de consommation d'une exécution enregistré dans _meta.json
+type UsageReport struct {
+	Provider string                `json:"provider"`
+	Model    string                `json:"model"`
+	Currency string                `json:"currency,omitempty"` // vide lorsqu'aucun prix n'est configuré pour le modèle
+	Budget   float64               `json:"budget,omitempty"`
+	Total    TokenUsage            `json:"total"`
+	Passes   []PassUsage           `json:"passes"`
+	Segments []SegmentUsage        `json:"segments"`
+	Models   map[string]TokenUsage `json:"models,omitempty"` // consommation par fournisseur:modèle lorsque les requêtes sont routées
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Directions autorisées pour une relation
+const (
+	DirectionForward       = "forward"
+	DirectionBackward      = "backward"
+	DirectionBidirectional = "bidirectional"
+)
+
+// ExtractedEntity représente une entité renvoyée par le LLM en mode structuré
+type ExtractedEntity struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// ExtractedRelation représente une relation renvoyée par le LLM en mode structuré
+type ExtractedRelation struct {
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Target      string `json:"target"`
+	Description string `json:"description"`
+	Weight      int    `json:"weight"`
+	Direction   string `json:"direction"`
+}
+
+// ExtractionResult est le contrat JSON attendu du LLM en mode structuré
+type ExtractionResult struct {
+	Entities  []ExtractedEntity   `json:"entities"`
+	Relations []ExtractedRelation `json:"relations"`
+}
+
+// ExtractionSchema est le schéma JSON envoyé aux LLM qui supportent les sorties structurées
+var ExtractionSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"entities": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":        map[string]interface{}{"type": "string"},
+					"type":        map[string]interface{}{"type": "string"},
+					"description": map[string]interface{}{"type": "string"},
+					"aliases": map[string]interface{}{
+						"type":  "array",
+						"items": map[string]interface{}{"type": "string"},
+					},
+				},
+				// Le mode strict d'OpenAI exige que chaque propriété soit requise : une entité sans alias renvoie []
+				"required":             []string{"name", "type", "description", "aliases"},
+				"additionalProperties": false,
+			},
+		},
+		"relations": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"source":      map[string]interface{}{"type": "string"},
+					"type":        map[string]interface{}{"type": "string"},
+					"target":      map[string]interface{}{"type": "string"},
+					"description": map[string]interface{}{"type": "string"},
+					"weight":      map[string]interface{}{"type": "integer", "minimum": 1},
+					"direction": map[string]interface{}{
+						"type": "string",
+						"enum": []string{DirectionForward, DirectionBackward, DirectionBidirectional},
+					},
+				},
+				"required":             []string{"source", "type", "target", "description", "weight", "direction"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"entities", "relations"},
+	"additionalProperties": false,
+}
+
+// Normalize nettoie les champs et remplace les espaces des noms par des underscores
+func (r *ExtractionResult) Normalize() {
+	for i := range r.Entities {
+		e := &r.Entities[i]
+		e.Name = normalizeExtractedName(e.Name)
+		e.Type = normalizeExtractedName(e.Type)
+		e.Description = strings.Join(strings.Fields(e.Description), " ")
+		for j, alias := range e.Aliases {
+			e.Aliases[j] = strings.Join(strings.Fields(alias), " ")
+		}
+	}
+	for i := range r.Relations {
+		rel := &r.Relations[i]
+		rel.Source = normalizeExtractedName(rel.Source)
+		rel.Target = normalizeExtractedName(rel.Target)
+		rel.Type = normalizeExtractedName(rel.Type)
+		rel.Description = strings.Join(strings.Fields(rel.Description), " ")
+		rel.Direction = strings.ToLower(strings.TrimSpace(rel.Direction))
+		if rel.Direction == "" {
+			rel.Direction = DirectionForward
+		}
+	}
+}
+
+// Validate vérifie que le résultat respecte le contrat et retourne toutes les violations trouvées
+func (r *ExtractionResult) Validate() error {
+	var problems []string
+	for i, e := range r.Entities {
+		if strings.TrimSpace(e.Name) == "" {
+			problems = append(problems, fmt.Sprintf("entities[%d].name is empty", i))
+		}
+		if strings.TrimSpace(e.Type) == "" {
+			problems = append(problems, fmt.Sprintf("entities[%d].type is empty", i))
+		}
+	}
+	for i, rel := range r.Relations {
+		if strings.TrimSpace(rel.Source) == "" {
+			problems = append(problems, fmt.Sprintf("relations[%d].source is empty", i))
+		}
+		if strings.TrimSpace(rel.Target) == "" {
+			problems = append(problems, fmt.Sprintf("relations[%d].target is empty", i))
+		}
+		if strings.TrimSpace(rel.Type) == "" {
+			problems = append(problems, fmt.Sprintf("relations[%d].type is empty", i))
+		}
+		if rel.Weight < 1 {
+			problems = append(problems, fmt.Sprintf("relations[%d].weight must be positive", i))
+		}
+		switch strings.ToLower(strings.TrimSpace(rel.Direction)) {
+		case "", DirectionForward, DirectionBackward, DirectionBidirectional:
+		default:
+			problems = append(problems, fmt.Sprintf("relations[%d].direction %q is not one of forward, backward, bidirectional", i, rel.Direction))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid extraction result: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ToTSV convertit le résultat au format TSV utilisé par le reste du pipeline
+func (r *ExtractionResult) ToTSV() string {
+	var builder strings.Builder
+	for _, e := range r.Entities {
+		builder.WriteString(fmt.Sprintf("%s\t%s\t%s\n", e.Name, e.Type, e.Description))
+		for _, alias := range e.Aliases {
+			if alias != "" {
+				builder.WriteString(fmt.Sprintf("%s\t%s\t%s\n", alias, AliasType, e.Name))
+			}
+		}
+	}
+	for _, rel := range r.Relations {
+		builder.WriteString(fmt.Sprintf("%s\t%s:%d\t%s\t%s\n", rel.Source, rel.Type, rel.Weight, rel.Target, rel.Description))
+	}
+	return builder.String()
+}
+
+// normalizeExtractedName remplace les espaces internes par des underscores
+func normalizeExtractedName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
@@ -0,0 +1,31 @@
+// model/extraction_test.go
+
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkStrictSchema vérifie récursivement que chaque objet du schéma déclare toutes ses propriétés
+// comme requises et n'en accepte pas d'autres, comme l'exige le mode strict des sorties structurées
+func checkStrictSchema(t *testing.T, path string, schema map[string]interface{}) {
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		required, _ := schema["required"].([]string)
+		for name, property := range properties {
+			assert.Contains(t, required, name, "%s.%s n'est pas requise", path, name)
+			checkStrictSchema(t, fmt.Sprintf("%s.%s", path, name), property.(map[string]interface{}))
+		}
+		assert.Len(t, required, len(properties), "%s requiert une propriété inconnue", path)
+		assert.Equal(t, false, schema["additionalProperties"], "%s accepte des propriétés supplémentaires", path)
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		checkStrictSchema(t, path+"[]", items)
+	}
+}
+
+func TestExtractionSchemaIsStrict(t *testing.T) {
+	checkStrictSchema(t, "$", ExtractionSchema)
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mention situe une occurrence d'un élément dans le texte extrait d'un fichier source
+type Mention struct {
+	FileID    string `json:"file_id"`             // metadata.FileMetadata.ID du fichier source
+	Start     int    `json:"start"`               // offset en octets du début de la mention dans le texte du fichier
+	End       int    `json:"end"`                 // offset en octets de la fin (exclue) de la mention
+	Page      int    `json:"page,omitempty"`      // page (PDF), 0 si inconnue
+	Paragraph int    `json:"paragraph,omitempty"` // paragraphe (DOCX, Markdown, HTML), 0 si inconnu
+}
+
+// String écrit la mention sous la forme fileID:start-end, suivie de :pN pour la page et :§N pour le paragraphe
+func (m Mention) String() string {
+	value := fmt.Sprintf("%s:%d-%d", m.FileID, m.Start, m.End)
+	if m.Page > 0 {
+		value += fmt.Sprintf(":p%d", m.Page)
+	}
+	if m.Paragraph > 0 {
+		value += fmt.Sprintf(":§%d", m.Paragraph)
+	}
+	return value
+}
+
+// ParseMention lit une mention écrite par String
+func ParseMention(value string) (Mention, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) < 2 {
+		return Mention{}, fmt.Errorf("invalid mention %q, expected fileID:start-end", value)
+	}
+	bounds := strings.SplitN(parts[1], "-", 2)
+	if len(bounds) != 2 {
+		return Mention{}, fmt.Errorf("invalid mention range %q, expected start-end", parts[1])
+	}
+	start, err := strconv.Atoi(bounds[0])
+	if err != nil {
+		return Mention{}, fmt.Errorf("invalid mention start %q: %w", bounds[0], err)
+	}
+	end, err := strconv.Atoi(bounds[1])
+	if err != nil {
+		return Mention{}, fmt.Errorf("invalid mention end %q: %w", bounds[1], err)
+	}
+
+	mention := Mention{FileID: parts[0], Start: start, End: end}
+	for _, part := range parts[2:] {
+		var target *int
+		var number string
+		switch {
+		case strings.HasPrefix(part, "p"):
+			target, number = &mention.Page, strings.TrimPrefix(part, "p")
+		case strings.HasPrefix(part, "§"):
+			target, number = &mention.Paragraph, strings.TrimPrefix(part, "§")
+		default:
+			return Mention{}, fmt.Errorf("invalid mention location %q", part)
+		}
+		if *target, err = strconv.Atoi(number); err != nil {
+			return Mention{}, fmt.Errorf("invalid mention location %q: %w", part, err)
+		}
+	}
+	return mention, nil
+}
+
+// FormatMentions écrit une liste de mentions séparées par des virgules
+func FormatMentions(mentions []Mention) string {
+	values := make([]string, len(mentions))
+	for i, m := range mentions {
+		values[i] = m.String()
+	}
+	return strings.Join(values, ",")
+}
+
+// ParseMentionList lit une liste écrite par FormatMentions en ignorant les valeurs invalides
+func ParseMentionList(value string) []Mention {
+	var mentions []Mention
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		if m, err := ParseMention(item); err == nil {
+			mentions = append(mentions, m)
+		}
+	}
+	return mentions
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 )
 
@@ -9,13 +10,19 @@ import (
 type OntologyElement struct {
 	Name        string // Nom de l'élément
 	Type        string // Type de l'élément
-	Positions   []int  // Positions de l'élément dans le document source
+	Positions   []int  // Positions héritées (index de mots) lues dans une ontologie importée
 	Description string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Source      string
+	Source      string       // Identifiants des fichiers sources, séparés par des virgules
+	Provenance  []Provenance // Fichiers, segments et passes ayant produit l'élément
+	Mentions    []Mention    // Occurrences de l'élément : fichier, octets de début et de fin, page ou paragraphe
+	Aliases     []string     // Autres noms de l'élément : sigles, variantes de surface, singulier ou pluriel
 }
 
+// AliasType est le type réservé des lignes TSV Alias\talias\tNom, qui rattachent un autre nom à un élément
+const AliasType = "alias"
+
 // Relation représente une relation entre deux éléments de l'ontologie
 type Relation struct {
 	Source      string
@@ -26,6 +33,7 @@ type Relation struct {
 	Direction   sql.NullString // "forward", "backward", or "bidirectional"
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Provenance  []Provenance // Fichiers, segments et passes ayant produit la relation
 }
 
 // Ontology représente une collection d'éléments d'ontologie
@@ -39,6 +47,31 @@ func (e *OntologyElement) SetPositions(positions []int) {
 	e.Positions = positions
 }
 
+// AddAlias ajoute un autre nom à l'élément ; elle retourne false si ce nom est vide ou déjà connu
+func (e *OntologyElement) AddAlias(alias string) bool {
+	alias = strings.TrimSpace(alias)
+	if alias == "" || e.HasName(alias) {
+		return false
+	}
+	e.Aliases = append(e.Aliases, alias)
+	return true
+}
+
+// HasName indique si le nom donné est celui de l'élément ou l'un de ses alias, sans tenir compte de la casse
+func (e *OntologyElement) HasName(name string) bool {
+	for _, known := range e.Names() {
+		if strings.EqualFold(known, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names retourne le nom de l'élément suivi de ses alias
+func (e *OntologyElement) Names() []string {
+	return append([]string{e.Name}, e.Aliases...)
+}
+
 // NewOntologyElement crée un nouvel élément d'ontologie
 func NewOntologyElement(name, elementType string) *OntologyElement {
 	return &OntologyElement{
@@ -70,7 +103,29 @@ func (o *Ontology) GetElementByName(name string) *OntologyElement {
 	return nil // Retourne nil si l'élément n'est pas trouvé
 }
 
+// GetElementByAlias recherche un élément dont l'un des alias correspond au nom donné
+func (o *Ontology) GetElementByAlias(alias string) *OntologyElement {
+	for _, element := range o.Elements {
+		for _, known := range element.Aliases {
+			if strings.EqualFold(known, alias) {
+				return element
+			}
+		}
+	}
+	return nil
+}
+
 // AddRelation ajoute une nouvelle relation à l'ontologie
 func (o *Ontology) AddRelation(relation *Relation) {
 	o.Relations = append(o.Relations, relation)
 }
+
+// GetRelation recherche une relation par sa source, son type et sa cible
+func (o *Ontology) GetRelation(source, relationType, target string) *Relation {
+	for _, relation := range o.Relations {
+		if relation.Source == source && relation.Type == relationType && relation.Target == target {
+			return relation
+		}
+	}
+	return nil
+}
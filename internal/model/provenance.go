@@ -0,0 +1,72 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Provenance indique le fichier, le segment et la passe ayant produit un élément ou une relation
+type Provenance struct {
+	FileID  string `json:"file_id"` // metadata.FileMetadata.ID du fichier source
+	Segment int    `json:"segment"` // index du segment dans la passe, -1 hors segmentation (ontologie importée)
+	Pass    int    `json:"pass"`    // numéro de passe, 0 pour une ontologie importée
+}
+
+// String écrit la provenance sous la forme fileID:segment:pass
+func (p Provenance) String() string {
+	return fmt.Sprintf("%s:%d:%d", p.FileID, p.Segment, p.Pass)
+}
+
+// ParseProvenance lit une provenance écrite par String
+func ParseProvenance(value string) (Provenance, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return Provenance{}, fmt.Errorf("invalid provenance %q, expected fileID:segment:pass", value)
+	}
+	segment, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Provenance{}, fmt.Errorf("invalid provenance segment %q: %w", parts[1], err)
+	}
+	pass, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return Provenance{}, fmt.Errorf("invalid provenance pass %q: %w", parts[2], err)
+	}
+	return Provenance{FileID: parts[0], Segment: segment, Pass: pass}, nil
+}
+
+// FormatProvenance écrit une liste de provenances séparées par des virgules
+func FormatProvenance(provenance []Provenance) string {
+	values := make([]string, len(provenance))
+	for i, p := range provenance {
+		values[i] = p.String()
+	}
+	return strings.Join(values, ",")
+}
+
+// ParseProvenanceList lit une liste écrite par FormatProvenance en ignorant les valeurs invalides
+func ParseProvenanceList(value string) []Provenance {
+	var provenance []Provenance
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		if p, err := ParseProvenance(item); err == nil {
+			provenance = append(provenance, p)
+		}
+	}
+	return provenance
+}
+
+// ProvenanceFileIDs retourne les identifiants de fichiers distincts d'une liste de provenances
+func ProvenanceFileIDs(provenance []Provenance) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, p := range provenance {
+		if p.FileID != "" && !seen[p.FileID] {
+			seen[p.FileID] = true
+			ids = append(ids, p.FileID)
+		}
+	}
+	return ids
+}
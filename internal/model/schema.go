@@ -0,0 +1,122 @@
+package model
+
+import "strings"
+
+// EntityTypeDef décrit un type d'entité autorisé par un template d'ontologie
+type EntityTypeDef struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	SubTypes    []string `json:"sub_types,omitempty"`
+	Attributes  []string `json:"attributes,omitempty"`
+	Relations   []string `json:"relations,omitempty"` // relations dont le type peut être la source
+}
+
+// RelationTypeDef décrit une relation autorisée et les types d'entités qu'elle peut relier.
+// Un domaine ou une portée vides n'imposent aucune contrainte.
+type RelationTypeDef struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Domain      []string `json:"domain,omitempty"`
+	Range       []string `json:"range,omitempty"`
+}
+
+// Schema regroupe les types d'entités et de relations qu'une ontologie doit respecter
+type Schema struct {
+	EntityTypes   []EntityTypeDef   `json:"entity_types"`
+	RelationTypes []RelationTypeDef `json:"relation_types"`
+}
+
+// NewSchema crée un schéma vide
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// IsEmpty indique si le schéma ne déclare aucun type d'entité
+func (s *Schema) IsEmpty() bool {
+	return s == nil || len(s.EntityTypes) == 0
+}
+
+// AddEntityType ajoute un type d'entité, ou complète un type déjà déclaré
+func (s *Schema) AddEntityType(def EntityTypeDef) {
+	if def.Name == "" {
+		return
+	}
+	existing := s.EntityType(def.Name)
+	if existing == nil {
+		s.EntityTypes = append(s.EntityTypes, def)
+		return
+	}
+	if existing.Description == "" {
+		existing.Description = def.Description
+	}
+	existing.SubTypes = appendMissing(existing.SubTypes, def.SubTypes...)
+	existing.Attributes = appendMissing(existing.Attributes, def.Attributes...)
+	existing.Relations = appendMissing(existing.Relations, def.Relations...)
+}
+
+// AddRelationType ajoute une relation, ou complète le domaine et la portée d'une relation déjà déclarée
+func (s *Schema) AddRelationType(def RelationTypeDef) {
+	if def.Name == "" {
+		return
+	}
+	existing := s.RelationType(def.Name)
+	if existing == nil {
+		s.RelationTypes = append(s.RelationTypes, def)
+		return
+	}
+	if existing.Description == "" {
+		existing.Description = def.Description
+	}
+	existing.Domain = appendMissing(existing.Domain, def.Domain...)
+	existing.Range = appendMissing(existing.Range, def.Range...)
+}
+
+// EntityType retourne le type d'entité portant ce nom, sans tenir compte de la casse
+func (s *Schema) EntityType(name string) *EntityTypeDef {
+	for i := range s.EntityTypes {
+		if strings.EqualFold(s.EntityTypes[i].Name, name) {
+			return &s.EntityTypes[i]
+		}
+	}
+	return nil
+}
+
+// RelationType retourne la relation portant ce nom, sans tenir compte de la casse
+func (s *Schema) RelationType(name string) *RelationTypeDef {
+	for i := range s.RelationTypes {
+		if strings.EqualFold(s.RelationTypes[i].Name, name) {
+			return &s.RelationTypes[i]
+		}
+	}
+	return nil
+}
+
+// Allows indique si un type d'entité appartient à une liste de types ; une liste vide autorise tous les types
+func Allows(types []string, name string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, allowed := range types {
+		if strings.EqualFold(allowed, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMissing ajoute les valeurs absentes d'une liste, sans tenir compte de la casse
+func appendMissing(values []string, additions ...string) []string {
+	for _, addition := range additions {
+		found := false
+		for _, value := range values {
+			if strings.EqualFold(value, addition) {
+				found = true
+				break
+			}
+		}
+		if !found && addition != "" {
+			values = append(values, addition)
+		}
+	}
+	return values
+}
@@ -0,0 +1,53 @@
+package model
+
+// VocabularyTerm représente une classe ou une propriété d'une ontologie de référence
+type VocabularyTerm struct {
+	Name        string
+	Description string
+	Parent      string // Classe ou propriété parente (rdfs:subClassOf, rdfs:subPropertyOf)
+}
+
+// Vocabulary regroupe les noms de classes et de propriétés que le LLM doit réutiliser
+type Vocabulary struct {
+	Classes    []VocabularyTerm
+	Properties []VocabularyTerm
+}
+
+// NewVocabulary crée un vocabulaire vide
+func NewVocabulary() *Vocabulary {
+	return &Vocabulary{}
+}
+
+// IsEmpty indique si le vocabulaire ne déclare aucune classe ni propriété
+func (v *Vocabulary) IsEmpty() bool {
+	return v == nil || (len(v.Classes) == 0 && len(v.Properties) == 0)
+}
+
+// AddClass ajoute une classe si elle n'est pas déjà déclarée
+func (v *Vocabulary) AddClass(term VocabularyTerm) {
+	v.Classes = addVocabularyTerm(v.Classes, term)
+}
+
+// AddProperty ajoute une propriété si elle n'est pas déjà déclarée
+func (v *Vocabulary) AddProperty(term VocabularyTerm) {
+	v.Properties = addVocabularyTerm(v.Properties, term)
+}
+
+// addVocabularyTerm ajoute un terme ou complète la description et le parent d'un terme existant
+func addVocabularyTerm(terms []VocabularyTerm, term VocabularyTerm) []VocabularyTerm {
+	if term.Name == "" {
+		return terms
+	}
+	for i := range terms {
+		if terms[i].Name == term.Name {
+			if terms[i].Description == "" {
+				terms[i].Description = term.Description
+			}
+			if terms[i].Parent == "" {
+				terms[i].Parent = term.Parent
+			}
+			return terms
+		}
+	}
+	return append(terms, term)
+}
@@ -0,0 +1,95 @@
+package ontology
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	llmclient "github.com/chrlesur/Ontology/internal/llm"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheDirectory string
+	cacheOlderThan time.Duration
+	cachePruneAll  bool
+)
+
+// cacheCmd groups the commands that inspect and clean the LLM response cache
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect and prune the LLM response cache",
+	Long: `enrich stores every LLM response under cache_directory, keyed by provider, model, temperature
+and a hash of the prompt, so re-running it on the same input with the same model and prompts sends
+no request. Entries older than cache_ttl_hours are ignored; use --no-cache on enrich to bypass the cache.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Print the number, size and age of the cached responses",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := openResponseCache()
+		stats, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Directory: %s\n", cacheDirectory)
+		fmt.Printf("Entries:   %d (%d expired)\n", stats.Entries, stats.Expired)
+		fmt.Printf("Size:      %.1f KiB\n", float64(stats.Bytes)/1024)
+		if stats.Entries == 0 {
+			return nil
+		}
+		fmt.Printf("Oldest:    %s\n", stats.Oldest.Format(time.RFC3339))
+		fmt.Printf("Newest:    %s\n", stats.Newest.Format(time.RFC3339))
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "\nPROVIDER/MODEL\tENTRIES")
+		for _, model := range stats.SortedModels() {
+			fmt.Fprintf(writer, "%s\t%d\n", model, stats.ByModel[model])
+		}
+		return writer.Flush()
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove expired cache entries, those older than --older-than, or all of them with --all",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache := openResponseCache()
+		var removed int
+		var err error
+		if cachePruneAll {
+			removed, err = cache.Clear()
+		} else {
+			removed, err = cache.Prune(cacheOlderThan)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d cache entries from %s\n", removed, cacheDirectory)
+		return nil
+	},
+}
+
+// openResponseCache opens the cache of --dir, or of the configuration
+func openResponseCache() *llmclient.ResponseCache {
+	cfg := config.GetConfig()
+	if cacheDirectory == "" {
+		cacheDirectory = cfg.CacheDirectory
+	}
+	return llmclient.NewResponseCache(cacheDirectory, time.Duration(cfg.CacheTTLHours)*time.Hour)
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd, cachePruneCmd)
+
+	cacheCmd.PersistentFlags().StringVar(&cacheDirectory, "dir", "", "Cache directory (default from config, .ontology_cache)")
+	cachePruneCmd.Flags().DurationVar(&cacheOlderThan, "older-than", 0, "Also remove entries older than this duration (e.g. 72h)")
+	cachePruneCmd.Flags().BoolVar(&cachePruneAll, "all", false, "Remove every entry")
+}
@@ -0,0 +1,110 @@
+package ontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	llmclient "github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/pipeline"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	clusterDatabase       string
+	clusterEmbedder       string
+	clusterEmbeddingModel string
+	clusterThreshold      float64
+	clusterOutput         string
+	clusterApply          bool
+)
+
+// clusterCmd groups semantically equivalent entities of a project database and proposes merges
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Propose merges of semantically equivalent entities using embeddings",
+	Long: `Compute an embedding for each entity of a project database (name, type and description),
+store the vectors in the database and group the entities whose cosine similarity to the kept entity
+reaches the threshold. Proposals are printed, or written as JSON with --output, for review;
+--apply merges them and records each merge in the database merge log.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := config.GetConfig()
+		if clusterDatabase == "" {
+			clusterDatabase = cfg.Database
+		}
+		if clusterDatabase == "" {
+			return fmt.Errorf("a project database is required (--db or database in the configuration)")
+		}
+		if clusterEmbedder == "" {
+			clusterEmbedder = cfg.EmbeddingProvider
+		}
+		if clusterEmbeddingModel == "" {
+			clusterEmbeddingModel = cfg.EmbeddingModel
+		}
+		if clusterThreshold <= 0 {
+			clusterThreshold = cfg.ClusterThreshold
+		}
+
+		embedder, err := llmclient.GetEmbedder(clusterEmbedder, clusterEmbeddingModel)
+		if err != nil {
+			return fmt.Errorf("failed to create embedder: %w", err)
+		}
+		db, err := pipeline.OpenDatabase(clusterDatabase)
+		if err != nil {
+			return fmt.Errorf("failed to open project database: %w", err)
+		}
+		defer db.Close()
+
+		computed, err := pipeline.EmbedEntities(db, embedder)
+		if err != nil {
+			return err
+		}
+		log.Info("Computed %d embeddings with %s", computed, embedder.Model())
+
+		proposals, err := pipeline.ClusterEntities(db, embedder.Model(), clusterThreshold)
+		if err != nil {
+			return err
+		}
+		log.Info("Found %d merge proposals at similarity %.2f", len(proposals), clusterThreshold)
+
+		if clusterOutput != "" {
+			content, err := json.MarshalIndent(proposals, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal proposals: %w", err)
+			}
+			if err := os.WriteFile(clusterOutput, content, 0644); err != nil {
+				return fmt.Errorf("failed to write proposals: %w", err)
+			}
+		} else {
+			for _, proposal := range proposals {
+				fmt.Printf("%s (%s)\n", proposal.Kept, proposal.Type)
+				for _, member := range proposal.Members {
+					fmt.Printf("  %.3f\t%s (%s)\n", member.Similarity, member.Name, member.Type)
+				}
+			}
+		}
+
+		if clusterApply {
+			records, err := pipeline.ApplyMergeProposals(db, proposals)
+			if err != nil {
+				return err
+			}
+			log.Info("Merged %d entities", len(records))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clusterCmd)
+
+	clusterCmd.Flags().StringVar(&clusterDatabase, "db", "", "SQLite project database (default from config)")
+	clusterCmd.Flags().StringVar(&clusterEmbedder, "embedder", "", "Embedding provider: ollama, openai (or any OpenAI-compatible API) or hash (default from config, ollama)")
+	clusterCmd.Flags().StringVar(&clusterEmbeddingModel, "embedding-model", "", "Embedding model (default: nomic-embed-text for ollama, text-embedding-3-small for openai)")
+	clusterCmd.Flags().Float64Var(&clusterThreshold, "threshold", 0, "Minimum cosine similarity to the kept entity (default from config, 0.85)")
+	clusterCmd.Flags().StringVar(&clusterOutput, "output", "", "Write the proposals as JSON to this file instead of printing them")
+	clusterCmd.Flags().BoolVar(&clusterApply, "apply", false, "Merge the proposed entities into the kept ones")
+}
@@ -0,0 +1,61 @@
+package ontology
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/pipeline"
+)
+
+// printRunPlan prints the segments, calls, tokens and estimated cost of a dry run
+func printRunPlan(plan *pipeline.RunPlan, cfg *config.Config) error {
+	fmt.Printf("LLM:            %s/%s\n", plan.Provider, plan.Model)
+	fmt.Printf("Mode:           %s, merge strategy %s\n", plan.Mode, plan.MergeStrategy)
+	fmt.Printf("Segmentation:   max_tokens %d, context_size %d\n", cfg.MaxTokens, cfg.ContextSize)
+	fmt.Printf("Content:        %d tokens in %d segments\n", plan.ContentTokens, len(plan.Segments))
+	fmt.Printf("Calls per pass: %d segment calls, %d merge calls\n", plan.SegmentCalls(), plan.MergeCalls)
+	fmt.Printf("Passes:         %d\n", plan.Passes)
+	fmt.Printf("Total:          %d calls, %d input tokens, at most %d output tokens\n", plan.Calls(), plan.InputTokens, plan.OutputTokens)
+
+	if len(plan.Segments) > 0 {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(writer, "\nSEGMENT\tSTART\tEND\tTOKENS\tCONTEXT\tPROMPT TOKENS\t")
+		for _, segment := range plan.Segments {
+			fmt.Fprintf(writer, "%d\t%d\t%d\t%d\t%d\t%s\t\n", segment.Number, segment.Start, segment.End, segment.Tokens, segment.ContextTokens, joinInts(segment.PromptTokens))
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(plan.Costs) == 0 {
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "\nMODEL\tESTIMATED COST (%s)\n", cfg.Currency)
+	for _, cost := range plan.Costs {
+		marker := ""
+		if cost.Selected {
+			marker = " (selected)"
+		}
+		fmt.Fprintf(writer, "%s%s\t%.4f\n", cost.Model, marker, cost.Cost)
+		if cost.Selected && cfg.Budget > 0 && cost.Cost > cfg.Budget {
+			fmt.Fprintf(writer, "  exceeds the budget of %.4f %s\n", cfg.Budget, cfg.Currency)
+		}
+	}
+	return writer.Flush()
+}
+
+// joinInts formats a list of token counts as "1200 + 300"
+func joinInts(values []int) string {
+	result := ""
+	for i, value := range values {
+		if i > 0 {
+			result += " + "
+		}
+		result += fmt.Sprint(value)
+	}
+	return result
+}
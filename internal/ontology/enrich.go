@@ -1,12 +1,18 @@
 package ontology
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/converter"
 	"github.com/chrlesur/Ontology/internal/i18n"
+	"github.com/chrlesur/Ontology/internal/language"
 	"github.com/chrlesur/Ontology/internal/logger"
 	"github.com/chrlesur/Ontology/internal/model"
 	"github.com/chrlesur/Ontology/internal/pipeline"
@@ -29,6 +35,27 @@ var (
 	maxThreads               int
 	aiyouAssistantID         string
 	enrichmentPromptFile     string
+	structuredOutput         bool
+	outputFormat             string
+	databasePath             string
+	incremental              bool
+	documentLanguage         string
+	noResolution             bool
+	resolutionThreshold      float64
+	noSchema                 bool
+	mode                     string
+	mergeStrategy            string
+	noCache                  bool
+	budget                   float64
+	dryRun                   bool
+	dumpPrompts              string
+	resume                   string
+	retryFailed              bool
+	onSegmentError           string
+	minCoverage              float64
+	timeout                  time.Duration
+	requestTimeout           time.Duration
+	fallback                 []string
 )
 
 // enrichCmd represents the enrich command
@@ -56,6 +83,102 @@ var enrichCmd = &cobra.Command{
 		if aiyouPassword != "" {
 			cfg.AIYOUPassword = aiyouPassword
 		}
+		if structuredOutput {
+			cfg.StructuredOutput = true
+		}
+		if outputFormat != "" {
+			cfg.OutputFormat = outputFormat
+		}
+		if databasePath != "" {
+			cfg.Database = databasePath
+		}
+		if incremental {
+			cfg.Incremental = true
+		}
+		if documentLanguage != "" {
+			cfg.Language = documentLanguage
+		}
+		if noResolution {
+			cfg.EntityResolution = false
+		}
+		if resolutionThreshold > 0 {
+			cfg.ResolutionThreshold = resolutionThreshold
+		}
+		if noSchema {
+			cfg.SchemaEnforcement = false
+		}
+		if noCache {
+			cfg.Cache = false
+		}
+		if mode != "" {
+			cfg.Mode = mode
+		}
+		if cfg.Mode == "" {
+			cfg.Mode = pipeline.ModeSingle
+		}
+		if !slices.Contains(pipeline.Modes(), cfg.Mode) {
+			return fmt.Errorf("unknown mode %q (supported: %s)", cfg.Mode, strings.Join(pipeline.Modes(), ", "))
+		}
+		if cfg.Mode == pipeline.ModeTwoStage && cfg.StructuredOutput {
+			return fmt.Errorf("--mode %s cannot be combined with --structured-output", pipeline.ModeTwoStage)
+		}
+		if mergeStrategy != "" {
+			cfg.MergeStrategy = mergeStrategy
+		}
+		if cfg.MergeStrategy == "" {
+			cfg.MergeStrategy = pipeline.MergeStrategyDB
+		}
+		if !slices.Contains(pipeline.MergeStrategies(), cfg.MergeStrategy) {
+			return fmt.Errorf("unknown merge strategy %q (supported: %s)", cfg.MergeStrategy, strings.Join(pipeline.MergeStrategies(), ", "))
+		}
+		if budget != 0 {
+			cfg.Budget = budget
+		}
+		if cfg.Budget < 0 {
+			return fmt.Errorf("invalid budget %v: must be positive, or 0 for no limit", cfg.Budget)
+		}
+		if onSegmentError != "" {
+			cfg.OnSegmentError = onSegmentError
+		}
+		if err := pipeline.ValidateSegmentErrorPolicy(cfg.OnSegmentError); err != nil {
+			return err
+		}
+		if minCoverage != 0 {
+			cfg.MinCoverage = minCoverage
+		}
+		if cfg.MinCoverage < 0 || cfg.MinCoverage > 1 {
+			return fmt.Errorf("invalid minimum coverage %v: must be between 0 and 1", cfg.MinCoverage)
+		}
+		if timeout != 0 {
+			cfg.TimeoutSeconds = int(math.Ceil(timeout.Seconds()))
+		}
+		if requestTimeout != 0 {
+			cfg.RequestTimeoutSeconds = int(math.Ceil(requestTimeout.Seconds()))
+		}
+		if cfg.TimeoutSeconds < 0 || cfg.RequestTimeoutSeconds < 0 {
+			return fmt.Errorf("invalid timeout: must be positive, or 0 for none")
+		}
+		if len(fallback) > 0 {
+			cfg.Fallback = fallback
+		}
+		cfg.Resume = resume
+		if cfg.Resume != "" && cfg.DryRun {
+			return fmt.Errorf("--resume cannot be combined with --dry-run")
+		}
+		cfg.RetryFailed = retryFailed
+		if cfg.RetryFailed && (cfg.Resume != "" || cfg.DryRun || cfg.Incremental || existingOntology != "") {
+			return fmt.Errorf("--retry-failed cannot be combined with --resume, --dry-run, --incremental or --existing-calculated-ontology")
+		}
+		if cfg.Language != "" && !strings.EqualFold(cfg.Language, language.Auto) {
+			if _, err := language.Get(cfg.Language); err != nil {
+				return fmt.Errorf("%w (supported: %s, %s)", err, language.Auto, strings.Join(language.Supported(), ", "))
+			}
+		}
+		exporter, err := converter.GetExporter(cfg.OutputFormat, cfg.BaseURI)
+		if err != nil {
+			return fmt.Errorf("%w (supported: %s)", err, strings.Join(converter.SupportedExportFormats(), ", "))
+		}
+		outputExt := exporter.Extension()
 
 		// Utiliser le chemin absolu pour l'entrée
 		var absInput string
@@ -73,20 +196,32 @@ var enrichCmd = &cobra.Command{
 		if output == "" {
 			if strings.HasPrefix(strings.ToLower(absInput), "s3://") {
 				// Pour les entrées S3, conserver le format S3 pour la sortie
-				output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + ".tsv"
+				output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + outputExt
 			} else {
 				// Pour les entrées locales, utiliser un chemin local
-				output = filepath.Join(filepath.Dir(absInput), filepath.Base(absInput)+".tsv")
+				output = filepath.Join(filepath.Dir(absInput), filepath.Base(absInput)+outputExt)
 			}
 		} else if !strings.HasPrefix(strings.ToLower(output), "s3://") && strings.HasPrefix(strings.ToLower(absInput), "s3://") {
 			// Si l'entrée est S3 mais pas la sortie, convertir la sortie en format S3
-			output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + ".tsv"
+			output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + outputExt
 		}
 
 		p, err := pipeline.NewPipeline(includePositions, contextOutput, contextWords, entityExtractionPrompt, relationExtractionPrompt, ontologyEnrichmentPrompt, ontologyMergePrompt, llm, llmModel, absInput, maxThreads, aiyouAssistantID, enrichmentPromptFile)
 		if err != nil {
 			return fmt.Errorf("%s: %w", i18n.Messages.ErrorCreatingPipeline, err)
 		}
+		defer p.Close()
+
+		if cfg.DryRun {
+			plan, err := p.PlanRun(absInput, output, passes, existingOntology, dumpPrompts)
+			if err != nil {
+				return fmt.Errorf("failed to plan the run: %w", err)
+			}
+			if dumpPrompts != "" {
+				log.Info("Rendered prompts written to %s", dumpPrompts)
+			}
+			return printRunPlan(plan, cfg)
+		}
 
 		p.SetProgressCallback(func(info pipeline.ProgressInfo) {
 			switch info.CurrentStep {
@@ -101,7 +236,9 @@ var enrichCmd = &cobra.Command{
 
 		ontology := model.NewOntology() // Utiliser le nouveau package model
 
-		err = p.ExecutePipeline(absInput, output, passes, existingOntology, ontology)
+		ctx, cancel := interruptibleContext(cmd.Context())
+		defer cancel()
+		err = p.ExecutePipelineContext(ctx, absInput, output, passes, existingOntology, ontology)
 		if err != nil {
 			return fmt.Errorf("%s: %w", i18n.Messages.ErrorExecutingPipeline, err)
 		}
@@ -123,21 +260,54 @@ func init() {
 	enrichCmd.Flags().BoolVar(&recursive, "recursive", false, i18n.Messages.RecursiveFlagUsage)
 	enrichCmd.Flags().StringVar(&existingOntology, "existing-calculated-ontology", "", i18n.Messages.ExistingOntologyFlagUsage)
 
-	enrichCmd.Flags().StringVarP(&entityExtractionPrompt, "entity-prompt", "e", "", "Additional prompt for entity extraction")
-	enrichCmd.Flags().StringVarP(&relationExtractionPrompt, "relation-prompt", "r", "", "Additional prompt for relation extraction")
+	enrichCmd.Flags().StringVar(&mode, "mode", "", "Segment processing mode: single (one enrichment prompt) or two-stage (entities, then relations between them) (default from config, single)")
+	enrichCmd.Flags().StringVarP(&entityExtractionPrompt, "entity-prompt", "e", "", "Additional prompt for entity extraction (two-stage mode)")
+	enrichCmd.Flags().StringVarP(&relationExtractionPrompt, "relation-prompt", "r", "", "Additional prompt for relation extraction (two-stage mode)")
 	enrichCmd.Flags().StringVarP(&enrichmentPromptFile, "ontology definition file", "o", "", "File path (local or S3) for custom ontology definition prompt")
-	enrichCmd.Flags().StringVarP(&ontologyMergePrompt, "merge-prompt", "m", "", "Additional prompt for ontology merging")
+	enrichCmd.Flags().StringVarP(&ontologyMergePrompt, "merge-prompt", "m", "", "Additional prompt for ontology merging (llm merge strategy)")
+	enrichCmd.Flags().StringVar(&mergeStrategy, "merge-strategy", "", "How segment results are merged: db (deterministic, by name) or llm (hierarchical LLM merge under merge_max_tokens, falling back to db) (default from config, db)")
 	enrichCmd.Flags().IntVarP(&maxThreads, "max-threads", "t", 10, "Maximum number of concurrent threads for processing")
+	enrichCmd.Flags().BoolVar(&structuredOutput, "structured-output", false, "Ask the LLM for schema-validated JSON instead of free-form TSV")
+	enrichCmd.Flags().BoolVar(&incremental, "incremental", false, "Only send files added or changed since the previous run (compared with the output's _meta.json)")
+	enrichCmd.Flags().StringVar(&databasePath, "db", "", "SQLite project database kept across runs (default: in-memory)")
+	enrichCmd.Flags().StringVar(&documentLanguage, "language", "", "Language of the documents for position matching: auto (detected per file), en, fr, de, es or it (default from config, auto)")
+	enrichCmd.Flags().BoolVar(&noResolution, "no-resolution", false, "Disable the local merge of near-duplicate entities after each pass")
+	enrichCmd.Flags().Float64Var(&resolutionThreshold, "resolution-threshold", 0, "Minimum score (0-1) for two entities to be merged by the local resolution (default from config, 0.9)")
+	enrichCmd.Flags().BoolVar(&noSchema, "no-schema", false, "Do not enforce the entity and relation types declared by the ontology definition file")
+	enrichCmd.Flags().Float64Var(&budget, "budget", 0, "Maximum LLM spend of the run, in the configured currency; the run aborts before a request that could exceed it (default from config, no limit)")
+	enrichCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Parse, segment and assemble the prompts, then print the segments, calls, tokens and estimated cost without calling the LLM")
+	enrichCmd.Flags().StringVar(&dumpPrompts, "dump-prompts", "", "Write the rendered prompt of each segment to this directory (implies --dry-run)")
+	enrichCmd.Flags().StringVar(&onSegmentError, "on-segment-error", "", "What to do when a segment fails: fail (abort the run), skip (leave it out and report it) or retry-N (retry it up to N times, then skip it) (default from config, skip)")
+	enrichCmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "Minimum share (0-1) of segments processed successfully; below it the run exits with an error after writing its output (default from config, 0)")
+	enrichCmd.Flags().DurationVar(&timeout, "timeout", 0, "Maximum duration of the run, e.g. 2h; when it is reached the run stops like on Ctrl-C and saves its partial results (default from config, none)")
+	enrichCmd.Flags().DurationVar(&requestTimeout, "request-timeout", 0, "Maximum duration of each LLM request, retries included, e.g. 90s (default from config, none)")
+	enrichCmd.Flags().StringSliceVar(&fallback, "fallback", nil, "Comma-separated provider:model links tried in order when the selected LLM fails, e.g. openai:gpt-4o,ollama:llama3.1:8B (default from config, none)")
+	enrichCmd.Flags().BoolVar(&retryFailed, "retry-failed", false, "Re-run, in a single pass, only the segments listed as failed in the _meta.json of --output, starting from the ontology of --output")
+	enrichCmd.Flags().StringVar(&resume, "resume", "", "Resume an interrupted run from its checkpoint, skipping the segments and passes already completed (run ID logged at the start of the run)")
+	enrichCmd.Flags().BoolVar(&noCache, "no-cache", false, "Send every request to the LLM instead of reusing the responses stored in the cache")
+	enrichCmd.Flags().StringVar(&outputFormat, "output-format", "", "Output format of the ontology: tsv, ttl, owl or jsonld (default from config, tsv)")
 }
 
 func ExecuteEnrichCommand(input, output string, passes int, existingOntology string, includePositions, contextOutput bool, contextWords int, entityPrompt, relationPrompt, enrichmentPrompt, mergePrompt string) error {
+	return ExecuteEnrichCommandContext(context.Background(), input, output, passes, existingOntology, includePositions, contextOutput, contextWords, entityPrompt, relationPrompt, enrichmentPrompt, mergePrompt)
+}
+
+// ExecuteEnrichCommandContext runs the enrichment until it completes or ctx is cancelled,
+// in which case the partial results are saved before the cancellation error is returned
+func ExecuteEnrichCommandContext(ctx context.Context, input, output string, passes int, existingOntology string, includePositions, contextOutput bool, contextWords int, entityPrompt, relationPrompt, enrichmentPrompt, mergePrompt string) error {
 	log := logger.GetLogger()
 	log.Info(i18n.Messages.StartingEnrichProcess)
 
+	cfg := config.GetConfig()
+	exporter, err := converter.GetExporter(cfg.OutputFormat, cfg.BaseURI)
+	if err != nil {
+		return fmt.Errorf("%w (supported: %s)", err, strings.Join(converter.SupportedExportFormats(), ", "))
+	}
+	outputExt := exporter.Extension()
+
 	absInput := input // Garder l'input tel quel s'il est déjà en format S3
 
 	if !strings.HasPrefix(strings.ToLower(input), "s3://") {
-		var err error
 		absInput, err = filepath.Abs(input)
 		if err != nil {
 			return fmt.Errorf("error getting absolute path: %w", err)
@@ -148,10 +318,10 @@ func ExecuteEnrichCommand(input, output string, passes int, existingOntology str
 		// Générer le nom de fichier de sortie en conservant le format S3 si l'entrée est S3
 		if strings.HasPrefix(strings.ToLower(absInput), "s3://") {
 			// Pour les entrées S3, construire un chemin de sortie S3
-			output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + ".tsv"
+			output = strings.TrimSuffix(absInput, filepath.Ext(absInput)) + outputExt
 		} else {
 			// Pour les entrées locales, utiliser le chemin local
-			output = filepath.Join(filepath.Dir(absInput), filepath.Base(absInput)+".tsv")
+			output = filepath.Join(filepath.Dir(absInput), filepath.Base(absInput)+outputExt)
 		}
 	} else if !strings.HasPrefix(strings.ToLower(output), "s3://") && strings.HasPrefix(strings.ToLower(absInput), "s3://") {
 		// Si l'entrée est S3 mais pas la sortie, convertir la sortie en format S3
@@ -162,6 +332,7 @@ func ExecuteEnrichCommand(input, output string, passes int, existingOntology str
 	if err != nil {
 		return fmt.Errorf("%s: %w", i18n.Messages.ErrorCreatingPipeline, err)
 	}
+	defer p.Close()
 
 	p.SetProgressCallback(func(info pipeline.ProgressInfo) {
 		switch info.CurrentStep {
@@ -175,7 +346,7 @@ func ExecuteEnrichCommand(input, output string, passes int, existingOntology str
 	})
 
 	onto := model.NewOntology()
-	err = p.ExecutePipeline(absInput, output, passes, existingOntology, onto)
+	err = p.ExecutePipelineContext(ctx, absInput, output, passes, existingOntology, onto)
 	if err != nil {
 		return fmt.Errorf("%s: %w", i18n.Messages.ErrorExecutingPipeline, err)
 	}
@@ -185,10 +356,10 @@ func ExecuteEnrichCommand(input, output string, passes int, existingOntology str
 	return nil
 }
 
-func generateOutputFilename(input string) string {
+func generateOutputFilename(input string, outputExt string) string {
 	dir := filepath.Dir(input)
 	baseName := filepath.Base(input)
 	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
 
-	return filepath.Join(dir, baseName+".tsv")
+	return filepath.Join(dir, baseName+outputExt)
 }
@@ -75,6 +75,7 @@ func initConfig() {
 	cfg := config.GetConfig()
 	cfg.ContextOutput = contextOutput
 	cfg.ContextWords = contextWords
+	cfg.DryRun = dryRun || dumpPrompts != ""
 
 	// Validate the config
 	if err := cfg.ValidateConfig(); err != nil {
@@ -0,0 +1,29 @@
+package ontology
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/chrlesur/Ontology/internal/logger"
+)
+
+// interruptibleContext returns a context cancelled on the first SIGINT or SIGTERM, so that the run can stop
+// its requests and save its partial results; the signal handler is then removed and a second signal exits immediately
+func interruptibleContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		defer signal.Stop(signals)
+		select {
+		case sig := <-signals:
+			logger.GetLogger().Warning("Received %v: stopping the run and saving partial results (repeat to exit immediately)", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
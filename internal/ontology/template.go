@@ -0,0 +1,130 @@
+package ontology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/chrlesur/Ontology/internal/prompt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateDirectory  string
+	templateShowSchema bool
+)
+
+// templateCmd groups the commands that inspect ontology definition templates (.ont)
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Validate, list and show ontology definition templates",
+	Long: `Ontology definition templates (.ont) are given to enrich with -o. A structured template starts
+with a YAML front matter declaring its format_version, name, version, naming rules, entity types,
+relation types and prompt fragments, from which the enrichment prompt and the enforced schema are built.
+A template without front matter is free-form prose used as the prompt as is.`,
+}
+
+var templateValidateCmd = &cobra.Command{
+	Use:   "validate [file...]",
+	Short: "Check that templates are well-formed and consistent",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invalid := 0
+		for _, path := range args {
+			template, err := prompt.LoadTemplate(path)
+			if err == nil {
+				err = template.Validate()
+			}
+			if err != nil {
+				invalid++
+				fmt.Printf("%s: %v\n", path, err)
+				continue
+			}
+			schema := template.Schema()
+			fmt.Printf("%s: ok (%s, %d entity types, %d relation types)\n", path, templateKind(template), len(schema.EntityTypes), len(schema.RelationTypes))
+		}
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d templates are invalid", invalid, len(args))
+		}
+		return nil
+	},
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the templates of a directory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths, err := filepath.Glob(filepath.Join(templateDirectory, "*.ont"))
+		if err != nil {
+			return fmt.Errorf("failed to list templates: %w", err)
+		}
+		sort.Strings(paths)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "FILE\tNAME\tVERSION\tFORMAT\tTYPES\tRELATIONS")
+		for _, path := range paths {
+			template, err := prompt.LoadTemplate(path)
+			if err != nil {
+				fmt.Fprintf(writer, "%s\t-\t-\terror: %v\t\t\n", filepath.Base(path), err)
+				continue
+			}
+			name, version := template.Name, template.Version
+			if name == "" {
+				name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+			}
+			if version == "" {
+				version = "-"
+			}
+			schema := template.Schema()
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%d\n", filepath.Base(path), name, version, templateKind(template), len(schema.EntityTypes), len(schema.RelationTypes))
+		}
+		return writer.Flush()
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:   "show [file]",
+	Short: "Print the enrichment prompt assembled from a template, or its schema with --schema",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template, err := prompt.LoadTemplate(args[0])
+		if err != nil {
+			return err
+		}
+		if err := template.Validate(); err != nil {
+			return err
+		}
+		if templateShowSchema {
+			content, err := json.MarshalIndent(template.Schema(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal schema: %w", err)
+			}
+			fmt.Println(string(content))
+			return nil
+		}
+		fmt.Print(template.PromptTemplate().Template)
+		return nil
+	},
+}
+
+// templateKind describes the format of a template for the listing
+func templateKind(template *prompt.Template) string {
+	if template.Structured {
+		return fmt.Sprintf("structured v%d", template.FormatVersion)
+	}
+	return "free-form"
+}
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateValidateCmd, templateListCmd, templateShowCmd)
+
+	templateListCmd.Flags().StringVar(&templateDirectory, "dir", "templates", "Directory containing the .ont templates")
+	templateShowCmd.Flags().BoolVar(&templateShowSchema, "schema", false, "Print the entity and relation types as JSON instead of the prompt")
+}
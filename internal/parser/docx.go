@@ -11,7 +11,8 @@ import (
 )
 
 type DOCXParser struct {
-	metadata map[string]string
+	metadata  map[string]string
+	locations []Location
 }
 
 func init() {
@@ -44,6 +45,7 @@ func (p *DOCXParser) Parse(reader io.Reader) ([]byte, error) {
 	log.Debug("Zip reader created successfully")
 
 	var textContent strings.Builder
+	p.locations = nil
 
 	for _, file := range zipReader.File {
 		log.Debug("Processing zip file: %s", file.Name)
@@ -97,6 +99,8 @@ func (p *DOCXParser) extractContent(file *zip.File, textContent *strings.Builder
 	decoder := xml.NewDecoder(bytes.NewReader(content))
 	var inTextElement bool
 	var currentText string
+	var recorder locationRecorder
+	paragraph, paragraphStart := 0, textContent.Len()
 
 	for {
 		token, err := decoder.Token()
@@ -112,6 +116,9 @@ func (p *DOCXParser) extractContent(file *zip.File, textContent *strings.Builder
 		case xml.StartElement:
 			if se.Name.Local == "t" {
 				inTextElement = true
+			} else if se.Name.Local == "p" {
+				paragraph++
+				paragraphStart = textContent.Len()
 			}
 		case xml.EndElement:
 			if se.Name.Local == "t" {
@@ -120,6 +127,7 @@ func (p *DOCXParser) extractContent(file *zip.File, textContent *strings.Builder
 				textContent.WriteString(" ")
 				currentText = ""
 			} else if se.Name.Local == "p" {
+				recorder.add(paragraphStart, textContent.Len(), 0, paragraph)
 				textContent.WriteString("\n")
 			}
 		case xml.CharData:
@@ -129,7 +137,8 @@ func (p *DOCXParser) extractContent(file *zip.File, textContent *strings.Builder
 		}
 	}
 
-	log.Debug("Total extracted content length: %d", textContent.Len())
+	p.locations = recorder.locations
+	log.Debug("Total extracted content length: %d, paragraphs: %d", textContent.Len(), paragraph)
 	return nil
 }
 
@@ -197,3 +206,8 @@ func (p *DOCXParser) extractMetadata(file *zip.File) error {
 func (p *DOCXParser) GetFormatMetadata() map[string]string {
 	return p.metadata
 }
+
+// Locations retourne le paragraphe (w:p) d'origine de chaque partie du texte extrait
+func (p *DOCXParser) Locations() []Location {
+	return p.locations
+}
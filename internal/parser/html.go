@@ -12,11 +12,19 @@ import (
 )
 
 type HTMLParser struct {
-	metadata map[string]string
+	metadata  map[string]string
+	locations []Location
+	paragraph int
 }
 
 func init() {
-	RegisterParser(".html", NewPDFParser)
+	RegisterParser(".html", NewHTMLParser)
+}
+
+// blockElements sont les éléments HTML qui ouvrent un nouveau paragraphe
+var blockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "td": true, "th": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "title": true,
 }
 
 func NewHTMLParser() Parser {
@@ -41,20 +49,28 @@ func (p *HTMLParser) Parse(reader io.Reader) ([]byte, error) {
 	}
 
 	var textContent strings.Builder
-	p.extractContent(doc, &textContent)
+	var recorder locationRecorder
+	p.paragraph = 0
+	p.extractContent(doc, &textContent, &recorder)
+	p.locations = recorder.locations
 	p.extractMetadata(doc)
 
 	log.Info(i18n.Messages.ParseCompleted, "HTML")
 	return []byte(textContent.String()), nil
 }
 
-func (p *HTMLParser) extractContent(n *html.Node, textContent *strings.Builder) {
+func (p *HTMLParser) extractContent(n *html.Node, textContent *strings.Builder, recorder *locationRecorder) {
+	if n.Type == html.ElementNode && blockElements[n.Data] {
+		p.paragraph++
+	}
 	if n.Type == html.TextNode {
+		start := textContent.Len()
 		textContent.WriteString(strings.TrimSpace(n.Data))
+		recorder.add(start, textContent.Len(), 0, p.paragraph)
 		textContent.WriteString(" ")
 	}
 	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		p.extractContent(c, textContent)
+		p.extractContent(c, textContent, recorder)
 	}
 }
 
@@ -102,3 +118,8 @@ func (p *HTMLParser) extractMetadata(n *html.Node) {
 func (p *HTMLParser) GetFormatMetadata() map[string]string {
 	return p.metadata
 }
+
+// Locations retourne le paragraphe (élément de bloc) d'origine de chaque partie du texte extrait
+func (p *HTMLParser) Locations() []Location {
+	return p.locations
+}
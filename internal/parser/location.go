@@ -0,0 +1,54 @@
+package parser
+
+// Location situe une page ou un paragraphe du document dans le texte retourné par Parse
+type Location struct {
+	Start     int // offset en octets du début de la zone dans le texte extrait
+	End       int // offset en octets de la fin (exclue) de la zone
+	Page      int // page (PDF), 0 si inconnue
+	Paragraph int // paragraphe (DOCX, Markdown, HTML), 0 si inconnu
+}
+
+// LocationParser est implémenté par les parsers capables de fournir, avec le texte extrait,
+// la carte des pages ou paragraphes du document
+type LocationParser interface {
+	// Locations retourne la carte du dernier document analysé, triée par offset
+	Locations() []Location
+}
+
+// GetLocations retourne la carte des pages ou paragraphes produite par un parser, nil s'il n'en fournit pas
+func GetLocations(p Parser) []Location {
+	if locationParser, ok := p.(LocationParser); ok {
+		return locationParser.Locations()
+	}
+	return nil
+}
+
+// FindLocation retourne la zone contenant l'offset donné
+func FindLocation(locations []Location, offset int) (Location, bool) {
+	for _, location := range locations {
+		if offset >= location.Start && offset < location.End {
+			return location, true
+		}
+	}
+	return Location{}, false
+}
+
+// locationRecorder construit la carte d'un document au fil de l'extraction du texte
+type locationRecorder struct {
+	locations []Location
+}
+
+// add enregistre une zone ; elle prolonge la précédente si elle appartient à la même page et au même paragraphe
+func (r *locationRecorder) add(start, end, page, paragraph int) {
+	if end <= start {
+		return
+	}
+	if n := len(r.locations); n > 0 {
+		last := &r.locations[n-1]
+		if last.Page == page && last.Paragraph == paragraph {
+			last.End = end
+			return
+		}
+	}
+	r.locations = append(r.locations, Location{Start: start, End: end, Page: page, Paragraph: paragraph})
+}
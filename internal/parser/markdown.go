@@ -12,7 +12,8 @@ import (
 )
 
 type MarkdownParser struct {
-	metadata map[string]string
+	metadata  map[string]string
+	locations []Location
 }
 
 func init() {
@@ -33,6 +34,8 @@ func (p *MarkdownParser) Parse(reader io.Reader) ([]byte, error) {
 	inFrontMatter := false
 	var frontMatter strings.Builder
 	lineCount, wordCount, charCount, headerCount := 0, 0, 0, 0
+	var recorder locationRecorder
+	paragraph, inParagraph := 0, false
 
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -52,12 +55,24 @@ func (p *MarkdownParser) Parse(reader io.Reader) ([]byte, error) {
 			continue
 		}
 
+		// Les paragraphes sont séparés par des lignes vides ; chaque titre forme un paragraphe
+		isHeader := strings.HasPrefix(line, "#")
+		if strings.TrimSpace(line) == "" {
+			inParagraph = false
+		} else if !inParagraph || isHeader {
+			paragraph++
+			inParagraph = !isHeader
+		}
+		start := content.Len()
 		content.WriteString(line + "\n")
+		if strings.TrimSpace(line) != "" {
+			recorder.add(start, content.Len(), 0, paragraph)
+		}
 		lineCount++
 		wordCount += len(strings.Fields(line))
 		charCount += len(line)
 
-		if strings.HasPrefix(line, "#") {
+		if isHeader {
 			headerCount++
 		}
 	}
@@ -65,6 +80,7 @@ func (p *MarkdownParser) Parse(reader io.Reader) ([]byte, error) {
 	if err := scanner.Err(); err != nil {
 		return nil, err
 	}
+	p.locations = recorder.locations
 
 	p.metadata["format"] = "Markdown"
 	p.metadata["lineCount"] = fmt.Sprintf("%d", lineCount)
@@ -92,3 +108,8 @@ func (p *MarkdownParser) parseFrontMatter(frontMatter string) {
 func (p *MarkdownParser) GetFormatMetadata() map[string]string {
 	return p.metadata
 }
+
+// Locations retourne le paragraphe d'origine de chaque partie du texte extrait
+func (p *MarkdownParser) Locations() []Location {
+	return p.locations
+}
@@ -153,6 +153,42 @@ It has some content and metadata.`
 	assert.Equal(t, "1", metadata["headerCount"])
 }
 
+func TestMarkdownParserLocations(t *testing.T) {
+	content := "# Titre\nPremier paragraphe\nsur deux lignes.\n\nSecond paragraphe."
+
+	parser := NewMarkdownParser()
+	result, err := parser.Parse(strings.NewReader(content))
+	assert.NoError(t, err)
+
+	locations := GetLocations(parser)
+	if assert.Len(t, locations, 3) {
+		assert.Equal(t, "# Titre\n", string(result[locations[0].Start:locations[0].End]))
+		assert.Equal(t, "Premier paragraphe\nsur deux lignes.\n", string(result[locations[1].Start:locations[1].End]))
+		assert.Equal(t, 2, locations[1].Paragraph)
+		assert.Equal(t, 3, locations[2].Paragraph)
+	}
+
+	location, ok := FindLocation(locations, strings.Index(string(result), "Second"))
+	assert.True(t, ok)
+	assert.Equal(t, 3, location.Paragraph)
+}
+
+func TestHTMLParserLocations(t *testing.T) {
+	content := `<html><body><h1>Titre</h1><p>Premier <b>paragraphe</b></p><p>Second</p></body></html>`
+
+	parser, err := GetParser(".html")
+	assert.NoError(t, err)
+	result, err := parser.Parse(strings.NewReader(content))
+	assert.NoError(t, err)
+
+	locations := GetLocations(parser)
+	if assert.Len(t, locations, 3) {
+		assert.Equal(t, "Premier paragraphe", string(result[locations[1].Start:locations[1].End]))
+		assert.Equal(t, 2, locations[1].Paragraph)
+		assert.Equal(t, "Second", string(result[locations[2].Start:locations[2].End]))
+	}
+}
+
 func TestParseDirectory(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := ioutil.TempDir("", "parser_test")
@@ -11,7 +11,8 @@ import (
 )
 
 type PDFParser struct {
-	metadata map[string]string
+	metadata  map[string]string
+	locations []Location
 }
 
 func init() {
@@ -40,6 +41,7 @@ func (p *PDFParser) Parse(reader io.Reader) ([]byte, error) {
 	}
 
 	var textContent bytes.Buffer
+	var recorder locationRecorder
 	for i := 1; i <= pdfReader.NumPage(); i++ {
 		page := pdfReader.Page(i)
 		if page.V.IsNull() {
@@ -50,8 +52,11 @@ func (p *PDFParser) Parse(reader io.Reader) ([]byte, error) {
 			log.Warning("Failed to extract text from page %d: %v", i, err)
 			continue
 		}
+		start := textContent.Len()
 		textContent.WriteString(text)
+		recorder.add(start, textContent.Len(), i, 0)
 	}
+	p.locations = recorder.locations
 
 	p.extractMetadata(pdfReader)
 
@@ -93,3 +98,8 @@ func (p *PDFParser) extractMetadata(pdfReader *pdf.Reader) {
 func (p *PDFParser) GetFormatMetadata() map[string]string {
 	return p.metadata
 }
+
+// Locations retourne la page d'origine de chaque partie du texte extrait
+func (p *PDFParser) Locations() []Location {
+	return p.locations
+}
@@ -0,0 +1,146 @@
+// aliases.go
+
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/Ontology/internal/language"
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+// acronymRegexp repère un sigle défini entre parenthèses après sa forme développée, par exemple « (PSSI) »
+var acronymRegexp = regexp.MustCompile(`\((\p{Lu}[\p{Lu}\d&]{1,9})\)`)
+
+// maxExpansionWords borne le nombre de mots examinés avant un sigle pour retrouver sa forme développée
+const maxExpansionWords = 15
+
+// isAliasLine indique si une ligne TSV est une ligne Alias\talias\tNom
+func isAliasLine(parts []string) bool {
+	return len(parts) == 3 && strings.EqualFold(strings.TrimSpace(parts[1]), model.AliasType)
+}
+
+// formatAliasLine écrit un alias au format TSV canonique
+func formatAliasLine(alias, name string) string {
+	return fmt.Sprintf("%s\t%s\t%s", alias, model.AliasType, name)
+}
+
+// formatAliasLines écrit les lignes d'alias d'un élément
+func formatAliasLines(element *model.OntologyElement) []string {
+	lines := make([]string, 0, len(element.Aliases))
+	for _, alias := range element.Aliases {
+		lines = append(lines, formatAliasLine(alias, element.Name))
+	}
+	return lines
+}
+
+// addElementAlias rattache un autre nom à un élément et l'enregistre dans la base de données
+func (p *Pipeline) addElementAlias(element *model.OntologyElement, alias string) bool {
+	if !element.AddAlias(alias) {
+		return false
+	}
+	log.Debug("Added alias %s to element %s", alias, element.Name)
+	if p.db != nil {
+		if err := UpsertAliases(p.db, element.Name, []string{strings.TrimSpace(alias)}); err != nil {
+			log.Warning("Failed to upsert alias %s of %s: %v", alias, element.Name, err)
+		}
+	}
+	return true
+}
+
+// addSurfaceForm conserve comme alias le nom sous lequel un élément a été désigné,
+// s'il diffère de son nom autrement que par la casse, les accents ou les séparateurs
+func (p *Pipeline) addSurfaceForm(element *model.OntologyElement, name string) {
+	if element == nil || normalizeElementKey(name) == normalizeElementKey(element.Name) {
+		return
+	}
+	p.addElementAlias(element, strings.ReplaceAll(strings.TrimSpace(name), "_", " "))
+}
+
+// applyAliasLine rattache l'alias d'une ligne Alias\talias\tNom à l'élément désigné
+func (p *Pipeline) applyAliasLine(parts []string) *model.OntologyElement {
+	element := p.resolveElement(strings.TrimSpace(parts[2]))
+	if element == nil {
+		log.Warning("Alias %s references an unknown element: %s", parts[0], parts[2])
+		return nil
+	}
+	p.addElementAlias(element, parts[0])
+	return element
+}
+
+// linkAcronym rattache à un élément le sigle ou la forme développée définis pour lui dans le contenu de la passe
+func (p *Pipeline) linkAcronym(element *model.OntologyElement) {
+	if expansion, ok := p.acronyms[strings.ToUpper(element.Name)]; ok {
+		p.addElementAlias(element, expansion)
+		return
+	}
+	key := normalizeElementKey(element.Name)
+	for acronym, expansion := range p.acronyms {
+		if normalizeElementKey(expansion) == key {
+			p.addElementAlias(element, acronym)
+		}
+	}
+}
+
+// detectAcronyms relève les sigles définis entre parenthèses dans le contenu de la passe,
+// avec la forme développée dont les initiales des mots significatifs les composent
+func (p *Pipeline) detectAcronyms(content []byte) {
+	p.acronyms = make(map[string]string)
+	text := string(content)
+	for _, match := range acronymRegexp.FindAllStringSubmatchIndex(text, -1) {
+		acronym := text[match[2]:match[3]]
+		if _, known := p.acronyms[acronym]; known {
+			continue
+		}
+		words := strings.Fields(text[max(0, match[0]-maxExpansionWords*20):match[0]])
+		if expansion := findExpansion(acronym, words, p.languageAt(match[0])); expansion != "" {
+			p.acronyms[acronym] = expansion
+			log.Debug("Detected acronym %s: %s", acronym, expansion)
+		}
+	}
+}
+
+// findExpansion recherche, parmi les mots qui précèdent un sigle, la plus courte suite dont les initiales forment le sigle
+func findExpansion(acronym string, words []string, analyzer language.Analyzer) string {
+	target := strings.ToUpper(language.Normalize(acronym))
+	for n := 1; n <= min(len(words), maxExpansionWords); n++ {
+		candidate := words[len(words)-n:]
+		if initials(candidate, analyzer) == target {
+			return strings.TrimFunc(strings.Join(candidate, " "), func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+			})
+		}
+	}
+	return ""
+}
+
+// initials retourne les initiales des mots significatifs : les mots vides et les élisions (d', l') sont ignorés
+func initials(words []string, analyzer language.Analyzer) string {
+	var builder strings.Builder
+	for _, word := range words {
+		pieces := strings.FieldsFunc(word, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		for _, piece := range pieces {
+			normalized := language.Normalize(piece)
+			if len([]rune(normalized)) < 2 || analyzer.IsStopWord(normalized) {
+				continue
+			}
+			builder.WriteRune(unicode.ToUpper([]rune(normalized)[0]))
+		}
+	}
+	return builder.String()
+}
+
+// languageAt retourne l'analyseur de la langue du fichier contenant l'offset donné
+func (p *Pipeline) languageAt(offset int) language.Analyzer {
+	for _, file := range p.fileSpans {
+		if offset >= file.Start && offset < file.End {
+			return language.Lookup(file.Language)
+		}
+	}
+	return language.Lookup(p.contentLanguage)
+}
@@ -0,0 +1,67 @@
+// pipeline/aliases_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrichOntologyWithPositionsRecordsAliases(t *testing.T) {
+	p := newTestPipeline()
+	input := "Politique_de_sécurité\tDocument\tPolitique de sécurité du SI\n" +
+		"PSSI\talias\tPolitique_de_sécurité\n" +
+		"RSSI\tRole\tResponsable de la sécurité\n" +
+		"RSSI\tpilote:2\tPolitiques_de_sécurité\tLe RSSI pilote les politiques"
+
+	result := p.enrichOntologyWithPositions(input, false, "", 0)
+
+	assert.Len(t, p.ontology.Elements, 2)
+	politique := p.ontology.GetElementByName("Politique_de_sécurité")
+	if assert.NotNil(t, politique) {
+		assert.Equal(t, []string{"PSSI", "Politiques de sécurité"}, politique.Aliases)
+	}
+	assert.Same(t, politique, p.resolveElement("pssi"))
+	assert.NotNil(t, p.ontology.GetRelation("RSSI", "pilote", "Politique_de_sécurité"))
+	assert.Contains(t, result, "PSSI\talias\tPolitique_de_sécurité")
+
+	// Une entité renvoyée sous son alias met à jour l'élément existant
+	p.enrichOntologyWithPositions("PSSI\tDocument\tPolitique de sécurité des systèmes d'information", false, "", 0)
+	assert.Len(t, p.ontology.Elements, 2)
+	assert.Equal(t, "Politique de sécurité des systèmes d'information", politique.Description)
+
+	assert.NoError(t, p.insertResults(p.db, result))
+	entities, err := GetAllEntities(p.db)
+	assert.NoError(t, err)
+	for _, entity := range entities {
+		if entity.Name == "Politique_de_sécurité" {
+			assert.Equal(t, []string{"PSSI", "Politiques de sécurité"}, entity.Aliases)
+		}
+	}
+	merged, err := p.getMergedResults(p.db)
+	assert.NoError(t, err)
+	assert.Contains(t, merged, "PSSI\talias\tPolitique_de_sécurité\n")
+}
+
+func TestAcronymAliasesAreMatchedInMentions(t *testing.T) {
+	p := newTestPipeline()
+	text := "La Politique de Sécurité des Systèmes d'Information (PSSI) est validée. Le RSSI applique la PSSI."
+	content := []byte(text)
+	p.fileSpans = []fileSpan{{Path: "corpus/charte.md", Start: 0, End: len(text)}}
+	p.fullContent = content
+	p.createInvertedIndex(content)
+
+	assert.Equal(t, "Politique de Sécurité des Systèmes d'Information", p.acronyms["PSSI"])
+
+	p.ontologyMu.Lock()
+	element := p.upsertOntologyElement("PSSI", "Document", "Politique de sécurité", true)
+	p.ontologyMu.Unlock()
+
+	assert.Equal(t, []string{"Politique de Sécurité des Systèmes d'Information"}, element.Aliases)
+	var surfaces []string
+	for _, mention := range element.Mentions {
+		surfaces = append(surfaces, text[mention.Start:mention.End])
+	}
+	assert.Equal(t, []string{"Politique de Sécurité des Systèmes d'Information", "PSSI", "PSSI"}, surfaces)
+}
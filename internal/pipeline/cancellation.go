@@ -0,0 +1,30 @@
+// cancellation.go
+
+package pipeline
+
+import (
+	"context"
+	"time"
+)
+
+// runContext retourne le contexte de l'exécution en cours, context.Background() hors d'une exécution
+func (p *Pipeline) runContext() context.Context {
+	if p.ctx == nil {
+		return context.Background()
+	}
+	return p.ctx
+}
+
+// requestContext retourne le contexte d'un appel au LLM, borné par request_timeout_seconds
+func (p *Pipeline) requestContext() (context.Context, context.CancelFunc) {
+	ctx := p.runContext()
+	if p.config.RequestTimeoutSeconds > 0 {
+		return context.WithTimeout(ctx, time.Duration(p.config.RequestTimeoutSeconds)*time.Second)
+	}
+	return context.WithCancel(ctx)
+}
+
+// interrupted indique si l'exécution a été annulée ou a dépassé son délai
+func (p *Pipeline) interrupted() bool {
+	return p.runContext().Err() != nil
+}
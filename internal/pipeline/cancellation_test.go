@@ -0,0 +1,58 @@
+// pipeline/cancellation_test.go
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingLLM attend l'annulation du contexte de la requête et consigne son échéance
+type blockingLLM struct {
+	calls       int
+	hasDeadline bool
+}
+
+func (b *blockingLLM) Translate(ctx context.Context, prompt string, systemContext string) (string, llm.Usage, error) {
+	b.calls++
+	_, b.hasDeadline = ctx.Deadline()
+	<-ctx.Done()
+	return "", llm.Usage{}, ctx.Err()
+}
+
+func (b *blockingLLM) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, llm.Usage, error) {
+	return b.Translate(ctx, "", "")
+}
+
+func TestRequestTimeoutBoundsLLMCall(t *testing.T) {
+	p := newTestPipeline()
+	p.config.RequestTimeoutSeconds = 1
+	client := &blockingLLM{}
+	p.llm = client
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, client.hasDeadline)
+	assert.False(t, p.interrupted())
+}
+
+func TestCancelledRunIsNotRetried(t *testing.T) {
+	p := newTestPipeline()
+	ctx, cancel := context.WithCancel(context.Background())
+	p.ctx = ctx
+	client := &blockingLLM{}
+	p.llm = client
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, outcome := p.processSegmentWithPolicy(segmentErrorPolicy{retries: 3}, []byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.ErrorIs(t, outcome.err, context.Canceled)
+	assert.Equal(t, 1, outcome.attempts)
+	assert.Equal(t, 1, client.calls)
+	assert.False(t, client.hasDeadline)
+	assert.True(t, p.interrupted())
+}
@@ -0,0 +1,283 @@
+// checkpoint.go
+
+package pipeline
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/storage"
+)
+
+// CheckpointState est l'état d'une exécution enregistré dans state.json, à côté des résultats des segments
+type CheckpointState struct {
+	RunID           string            `json:"run_id"`
+	Input           string            `json:"input"`
+	Output          string            `json:"output"`
+	Settings        map[string]string `json:"settings"`       // réglages dont dépendent les résultats, comparés à la reprise
+	ContentSHA256   string            `json:"content_sha256"` // empreinte du contenu segmenté
+	Segments        int               `json:"segments"`
+	CompletedPasses int               `json:"completed_passes"` // passes terminées sans segment en échec
+	Completed       bool              `json:"completed"`
+	StartedAt       time.Time         `json:"started_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+}
+
+// runCheckpoint enregistre les résultats des segments et des passes d'une exécution dans le stockage
+// (local ou S3) afin qu'une exécution interrompue puisse être reprise sans refaire les appels terminés.
+// Un checkpoint nil n'enregistre rien, ce qui désactive la reprise.
+type runCheckpoint struct {
+	storage storage.Storage
+	dir     string
+	state   CheckpointState
+	files   map[string]bool // fichiers présents au moment de la reprise
+}
+
+// checkpointDir retourne le répertoire des checkpoints d'une exécution, à côté du fichier de sortie
+func checkpointDir(output string, runID string) string {
+	return strings.TrimSuffix(output, filepath.Ext(output)) + "_checkpoints/" + runID
+}
+
+// newRunID retourne un identifiant d'exécution horodaté, par exemple 20240615-142530-3fa2
+func newRunID() string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// startCheckpoint crée le checkpoint d'une nouvelle exécution
+func (p *Pipeline) startCheckpoint(input string, output string) (*runCheckpoint, error) {
+	now := time.Now()
+	runID := newRunID()
+	checkpoint := &runCheckpoint{
+		storage: p.storage,
+		dir:     checkpointDir(output, runID),
+		state: CheckpointState{
+			RunID:     runID,
+			Input:     input,
+			Output:    output,
+			Settings:  p.checkpointSettings(),
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+		files: make(map[string]bool),
+	}
+	if err := checkpoint.saveState(); err != nil {
+		return nil, err
+	}
+	p.logger.Info("Checkpointing run %s to %s (resume with --resume %s)", runID, checkpoint.dir, runID)
+	return checkpoint, nil
+}
+
+// resumeCheckpoint relit le checkpoint d'une exécution interrompue et vérifie qu'elle peut être reprise
+// avec la même entrée, la même sortie et les mêmes réglages
+func (p *Pipeline) resumeCheckpoint(runID string, input string, output string) (*runCheckpoint, error) {
+	checkpoint := &runCheckpoint{
+		storage: p.storage,
+		dir:     checkpointDir(output, runID),
+		files:   make(map[string]bool),
+	}
+	files, err := p.storage.List(checkpoint.dir)
+	if err != nil || len(files) == 0 {
+		return nil, fmt.Errorf("no checkpoint found for run %s in %s", runID, checkpoint.dir)
+	}
+	for _, file := range files {
+		checkpoint.files[checkpoint.relativePath(file)] = true
+	}
+
+	content, err := p.storage.Read(checkpoint.path("state.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint state of run %s: %w", runID, err)
+	}
+	if err := json.Unmarshal(content, &checkpoint.state); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint state of run %s: %w", runID, err)
+	}
+
+	state := checkpoint.state
+	if state.Completed {
+		return nil, fmt.Errorf("run %s already completed", runID)
+	}
+	if state.Input != input || state.Output != output {
+		return nil, fmt.Errorf("run %s was started with input %s and output %s", runID, state.Input, state.Output)
+	}
+	if changed := changedSettings(state.Settings, p.checkpointSettings()); len(changed) > 0 {
+		return nil, fmt.Errorf("run %s cannot be resumed with different settings: %s", runID, strings.Join(changed, ", "))
+	}
+	p.logger.Info("Resuming run %s: %d passes completed, %d checkpoint files", runID, state.CompletedPasses, len(files))
+	return checkpoint, nil
+}
+
+// checkpointSettings retourne les réglages dont dépendent les résultats des segments et des fusions
+func (p *Pipeline) checkpointSettings() map[string]string {
+	prompts := sha256.Sum256([]byte(strings.Join([]string{
+		p.entityExtractionPrompt, p.relationExtractionPrompt, p.ontologyEnrichmentPrompt, p.ontologyMergePrompt,
+	}, "\x00")))
+	return map[string]string{
+		"llm":                 p.llmProvider,
+		"model":               p.llmModel,
+		"mode":                p.config.Mode,
+		"structured_output":   fmt.Sprint(p.config.StructuredOutput),
+		"max_tokens":          fmt.Sprint(p.config.MaxTokens),
+		"context_size":        fmt.Sprint(p.config.ContextSize),
+		"merge_strategy":      p.config.MergeStrategy,
+		"ontology_definition": p.enrichmentPromptFile,
+		"prompts":             hex.EncodeToString(prompts[:8]),
+		"routing":             p.routingSettings(),
+	}
+}
+
+// changedSettings retourne, par ordre alphabétique, les réglages dont la valeur a changé
+func changedSettings(previous, current map[string]string) []string {
+	var changed []string
+	for key, value := range current {
+		if previous[key] != value {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func (c *runCheckpoint) path(name string) string {
+	return c.dir + "/" + name
+}
+
+// relativePath retourne le chemin d'un fichier listé par le stockage relativement au répertoire du checkpoint
+func (c *runCheckpoint) relativePath(file string) string {
+	file = filepath.ToSlash(file)
+	if relative, ok := strings.CutPrefix(file, filepath.ToSlash(c.dir)+"/"); ok {
+		return relative
+	}
+	// Le stockage peut retourner les chemins sous une autre forme : repérer le répertoire de l'exécution
+	runDir := "/" + c.dir[strings.LastIndex(c.dir, "/")+1:] + "/"
+	if i := strings.LastIndex(file, runDir); i >= 0 {
+		return file[i+len(runDir):]
+	}
+	return file
+}
+
+func segmentCheckpointName(pass int, segment int) string {
+	return fmt.Sprintf("pass_%d/segment_%04d.tsv", pass, segment+1)
+}
+
+func passCheckpointName(pass int) string {
+	return fmt.Sprintf("pass_%d/merged.tsv", pass)
+}
+
+// usable indique si les résultats enregistrés d'une passe peuvent être repris : les passes précédentes
+// doivent être terminées, sinon ils ont été calculés à partir d'un autre résultat précédent
+func (c *runCheckpoint) usable(pass int) bool {
+	return c != nil && pass <= c.state.CompletedPasses+1
+}
+
+// read retourne le contenu d'un fichier présent au moment de la reprise
+func (c *runCheckpoint) read(name string) (string, bool) {
+	if !c.files[name] {
+		return "", false
+	}
+	content, err := c.storage.Read(c.path(name))
+	if err != nil {
+		log.Warning("Failed to read checkpoint %s: %v", name, err)
+		return "", false
+	}
+	return string(content), true
+}
+
+// checkContent enregistre l'empreinte du contenu segmenté, ou vérifie qu'elle n'a pas changé depuis l'interruption
+func (c *runCheckpoint) checkContent(content []byte, segments int) error {
+	if c == nil {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if c.state.ContentSHA256 == "" {
+		c.state.ContentSHA256 = hash
+		c.state.Segments = segments
+		return c.saveState()
+	}
+	if c.state.ContentSHA256 != hash || c.state.Segments != segments {
+		return fmt.Errorf("input content of run %s changed since it was checkpointed (%d segments, now %d)", c.state.RunID, c.state.Segments, segments)
+	}
+	return nil
+}
+
+// segmentResult retourne le résultat enregistré d'un segment, s'il peut être repris
+func (c *runCheckpoint) segmentResult(pass int, segment int) (string, bool) {
+	if !c.usable(pass) {
+		return "", false
+	}
+	return c.read(segmentCheckpointName(pass, segment))
+}
+
+// passResult retourne le résultat fusionné enregistré d'une passe terminée
+func (c *runCheckpoint) passResult(pass int) (string, bool) {
+	if c == nil || pass > c.state.CompletedPasses {
+		return "", false
+	}
+	return c.read(passCheckpointName(pass))
+}
+
+// saveSegment enregistre le résultat d'un segment traité ; un échec d'écriture n'interrompt pas l'exécution
+func (c *runCheckpoint) saveSegment(pass int, segment int, result string) {
+	if c == nil {
+		return
+	}
+	if err := c.storage.Write(c.path(segmentCheckpointName(pass, segment)), []byte(result)); err != nil {
+		log.Warning("Failed to checkpoint segment %d of pass %d: %v", segment+1, pass, err)
+	}
+}
+
+// completePass enregistre le résultat fusionné d'une passe. La passe n'est marquée terminée que si
+// aucun segment n'a échoué et que les passes précédentes le sont, pour que la reprise retraite les échecs.
+func (c *runCheckpoint) completePass(pass int, mergedResult string, failedSegments int) {
+	if c == nil {
+		return
+	}
+	if failedSegments > 0 || pass != c.state.CompletedPasses+1 {
+		log.Warning("Pass %d is not checkpointed as completed: %d segments failed; resume with --resume %s to retry them", pass, failedSegments, c.state.RunID)
+		return
+	}
+	if err := c.storage.Write(c.path(passCheckpointName(pass)), []byte(mergedResult)); err != nil {
+		log.Warning("Failed to checkpoint pass %d: %v", pass, err)
+		return
+	}
+	c.files[passCheckpointName(pass)] = true
+	c.state.CompletedPasses = pass
+	if err := c.saveState(); err != nil {
+		log.Warning("Failed to checkpoint pass %d: %v", pass, err)
+	}
+}
+
+// complete marque l'exécution terminée lorsque toutes ses passes le sont
+func (c *runCheckpoint) complete(passes int) {
+	if c == nil {
+		return
+	}
+	if c.state.CompletedPasses < passes {
+		log.Warning("Run %s completed with failed segments; resume with --resume %s to retry them", c.state.RunID, c.state.RunID)
+		return
+	}
+	c.state.Completed = true
+	if err := c.saveState(); err != nil {
+		log.Warning("Failed to mark run %s as completed: %v", c.state.RunID, err)
+	}
+}
+
+func (c *runCheckpoint) saveState() error {
+	c.state.UpdatedAt = time.Now()
+	content, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint state: %w", err)
+	}
+	if err := c.storage.Write(c.path("state.json"), content); err != nil {
+		return fmt.Errorf("failed to write checkpoint state: %w", err)
+	}
+	return nil
+}
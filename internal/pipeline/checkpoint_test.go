@@ -0,0 +1,130 @@
+// pipeline/checkpoint_test.go
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/converter"
+	"github.com/chrlesur/Ontology/internal/logger"
+	"github.com/chrlesur/Ontology/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCheckpointPipeline(t *testing.T) (*Pipeline, string) {
+	p := newTestPipeline()
+	dir := t.TempDir()
+	p.storage = storage.NewLocalStorage(dir, logger.GetLogger())
+	p.llmProvider = "test-llm"
+	p.llmModel = "test-model"
+	return p, filepath.Join(dir, "out.tsv")
+}
+
+func TestCheckpointResumesCompletedSegmentsAndPasses(t *testing.T) {
+	p, output := newTestCheckpointPipeline(t)
+	checkpoint, err := p.startCheckpoint("doc.txt", output)
+	assert.NoError(t, err)
+	assert.NoError(t, checkpoint.checkContent([]byte("Le RSSI rédige la PSSI."), 2))
+
+	// Passe 1 terminée, passe 2 interrompue après son premier segment
+	checkpoint.saveSegment(1, 0, "RSSI\tRole\tResponsable")
+	checkpoint.saveSegment(1, 1, "PSSI\tDocument\tPolitique")
+	checkpoint.completePass(1, "RSSI\tRole\tResponsable\nPSSI\tDocument\tPolitique", 0)
+	checkpoint.saveSegment(2, 0, "RSSI\tRole\tResponsable sécurité")
+
+	resumed, err := p.resumeCheckpoint(checkpoint.state.RunID, "doc.txt", output)
+	assert.NoError(t, err)
+	assert.NoError(t, resumed.checkContent([]byte("Le RSSI rédige la PSSI."), 2))
+	assert.Equal(t, 1, resumed.state.CompletedPasses)
+
+	merged, ok := resumed.passResult(1)
+	assert.True(t, ok)
+	assert.Equal(t, "RSSI\tRole\tResponsable\nPSSI\tDocument\tPolitique", merged)
+	_, ok = resumed.passResult(2)
+	assert.False(t, ok)
+
+	result, ok := resumed.segmentResult(2, 0)
+	assert.True(t, ok)
+	assert.Equal(t, "RSSI\tRole\tResponsable sécurité", result)
+	_, ok = resumed.segmentResult(2, 1)
+	assert.False(t, ok)
+}
+
+func TestCheckpointDoesNotCompletePassWithFailedSegments(t *testing.T) {
+	p, output := newTestCheckpointPipeline(t)
+	checkpoint, err := p.startCheckpoint("doc.txt", output)
+	assert.NoError(t, err)
+
+	checkpoint.saveSegment(1, 0, "RSSI\tRole\tResponsable")
+	checkpoint.completePass(1, "RSSI\tRole\tResponsable", 1)
+	checkpoint.saveSegment(2, 0, "PSSI\tDocument\tPolitique")
+	checkpoint.complete(2)
+
+	resumed, err := p.resumeCheckpoint(checkpoint.state.RunID, "doc.txt", output)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, resumed.state.CompletedPasses)
+	_, ok := resumed.segmentResult(1, 0)
+	assert.True(t, ok)
+	// Les résultats de la passe 2 ont été calculés à partir d'une passe 1 incomplète
+	_, ok = resumed.segmentResult(2, 0)
+	assert.False(t, ok)
+}
+
+func TestCheckpointRefusesChangedRun(t *testing.T) {
+	p, output := newTestCheckpointPipeline(t)
+	checkpoint, err := p.startCheckpoint("doc.txt", output)
+	assert.NoError(t, err)
+	assert.NoError(t, checkpoint.checkContent([]byte("Le RSSI rédige la PSSI."), 1))
+	runID := checkpoint.state.RunID
+
+	_, err = p.resumeCheckpoint("unknown", "doc.txt", output)
+	assert.ErrorContains(t, err, "no checkpoint found")
+
+	_, err = p.resumeCheckpoint(runID, "other.txt", output)
+	assert.Error(t, err)
+
+	p.llmModel = "other-model"
+	p.config.MaxTokens = 2000
+	_, err = p.resumeCheckpoint(runID, "doc.txt", output)
+	assert.ErrorContains(t, err, "max_tokens, model")
+	p.llmModel = "test-model"
+	p.config.MaxTokens = 1000
+
+	resumed, err := p.resumeCheckpoint(runID, "doc.txt", output)
+	assert.NoError(t, err)
+	assert.Error(t, resumed.checkContent([]byte("Le RSSI a rédigé la PSSI."), 1))
+
+	checkpoint.completePass(1, "PSSI\tDocument\tPolitique", 0)
+	checkpoint.complete(1)
+	_, err = p.resumeCheckpoint(runID, "doc.txt", output)
+	assert.ErrorContains(t, err, "already completed")
+}
+
+func TestResumedPassRebuildsExportedOntology(t *testing.T) {
+	p, output := newTestCheckpointPipeline(t)
+	checkpoint, err := p.startCheckpoint("doc.txt", output)
+	assert.NoError(t, err)
+	assert.NoError(t, checkpoint.checkContent([]byte("Le RSSI rédige la PSSI."), 2))
+	checkpoint.saveSegment(1, 0, "RSSI\tRole\tResponsable")
+	checkpoint.saveSegment(1, 1, "PSSI\tDocument\tPolitique")
+	checkpoint.completePass(1, "RSSI\tRole\tResponsable sécurité\nPSSI\tDocument\tPolitique\nRSSI\trédige:3\tPSSI\tRédaction", 0)
+
+	// L'exécution reprise ne connaît que les segments rejoués, pas le résultat fusionné de la passe
+	p.checkpoint, err = p.resumeCheckpoint(checkpoint.state.RunID, "doc.txt", output)
+	assert.NoError(t, err)
+	p.currentPass = 1
+	p.upsertOntologyElement("RSSI", "Role", "Responsable", false)
+
+	merged, err := p.mergePass("", []string{"RSSI\tRole\tResponsable", "PSSI\tDocument\tPolitique"}, make([]segmentOutcome, 2))
+	assert.NoError(t, err)
+	assert.Contains(t, merged, "PSSI\tDocument\tPolitique")
+
+	exporter, err := converter.GetExporter("tsv", "")
+	assert.NoError(t, err)
+	exported, err := exporter.Export(p.ontology)
+	assert.NoError(t, err)
+	assert.Contains(t, string(exported), "RSSI\tRole\tResponsable sécurité")
+	assert.Contains(t, string(exported), "PSSI\tDocument\tPolitique")
+	assert.Contains(t, string(exported), "RSSI\trédige:3\tPSSI")
+}
@@ -0,0 +1,200 @@
+// clustering.go
+
+package pipeline
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+// reasonEmbedding est la raison inscrite au journal des fusions acceptées depuis le regroupement sémantique
+const reasonEmbedding = "embedding similarity"
+
+// MergeProposal propose de fusionner dans une entité conservée les entités sémantiquement proches
+type MergeProposal struct {
+	Kept    string          `json:"kept"`
+	Type    string          `json:"type"`
+	Members []ClusterMember `json:"members"`
+}
+
+// ClusterMember est une entité proposée à la fusion, avec sa similarité cosinus à l'entité conservée
+type ClusterMember struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Similarity float64 `json:"similarity"`
+}
+
+// EmbedEntities calcule et enregistre les vecteurs des entités de la base.
+// Seules les entités nouvelles, ou dont le nom, le type ou la description ont changé, sont envoyées à l'embedder.
+// Elle retourne le nombre de vecteurs calculés.
+func EmbedEntities(db *sql.DB, embedder llm.Embedder) (int, error) {
+	entities, err := GetAllEntities(db)
+	if err != nil {
+		return 0, err
+	}
+	stored, err := GetEmbeddings(db, embedder.Model())
+	if err != nil {
+		return 0, err
+	}
+
+	var pending []Embedding
+	var texts []string
+	for _, entity := range entities {
+		text := embeddingText(entity)
+		sum := sha256.Sum256([]byte(text))
+		hash := hex.EncodeToString(sum[:])
+		if stored[entity.Name].TextHash == hash {
+			continue
+		}
+		pending = append(pending, Embedding{Entity: entity.Name, Model: embedder.Model(), TextHash: hash})
+		texts = append(texts, text)
+	}
+	if len(texts) == 0 {
+		log.Info("All %d entities already have an embedding for %s", len(entities), embedder.Model())
+		return 0, nil
+	}
+
+	log.Info("Computing %d embeddings with %s", len(texts), embedder.Model())
+	vectors, err := embedder.Embed(texts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute embeddings: %w", err)
+	}
+	if len(vectors) != len(pending) {
+		return 0, fmt.Errorf("expected %d embeddings, got %d", len(pending), len(vectors))
+	}
+	for i := range pending {
+		pending[i].Vector = vectors[i]
+		if err := UpsertEmbedding(db, pending[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// embeddingText construit le texte représentant une entité : son nom, son type et sa description
+func embeddingText(entity *model.OntologyElement) string {
+	text := strings.ReplaceAll(entity.Name, "_", " ")
+	if entity.Type != "" {
+		text += " (" + strings.ReplaceAll(entity.Type, "_", " ") + ")"
+	}
+	if entity.Description != "" {
+		text += ": " + entity.Description
+	}
+	return text
+}
+
+// ClusterEntities regroupe les entités dont les vecteurs calculés par le modèle sont proches de l'entité conservée
+// d'au moins le seuil donné (similarité cosinus). Chaque groupe est une proposition de fusion à relire.
+func ClusterEntities(db *sql.DB, embeddingModel string, threshold float64) ([]MergeProposal, error) {
+	entities, err := GetAllEntities(db)
+	if err != nil {
+		return nil, err
+	}
+	embeddings, err := GetEmbeddings(db, embeddingModel)
+	if err != nil {
+		return nil, err
+	}
+
+	var embedded []*model.OntologyElement
+	for _, entity := range entities {
+		if _, ok := embeddings[entity.Name]; ok {
+			embedded = append(embedded, entity)
+		}
+	}
+	if len(embedded) < len(entities) {
+		log.Warning("%d entities have no embedding for %s and are not clustered", len(entities)-len(embedded), embeddingModel)
+	}
+	sort.SliceStable(embedded, func(i, j int) bool { return preferredEntity(embedded[i], embedded[j]) })
+
+	// Regroupement autour de l'entité préférée, sans enchaînement : chaque membre est proche de l'entité conservée
+	assigned := make([]bool, len(embedded))
+	var proposals []MergeProposal
+	for i, kept := range embedded {
+		if assigned[i] {
+			continue
+		}
+		proposal := MergeProposal{Kept: kept.Name, Type: kept.Type}
+		for j := i + 1; j < len(embedded); j++ {
+			if assigned[j] {
+				continue
+			}
+			similarity := cosineSimilarity(embeddings[kept.Name].Vector, embeddings[embedded[j].Name].Vector)
+			if similarity >= threshold {
+				assigned[j] = true
+				proposal.Members = append(proposal.Members, ClusterMember{Name: embedded[j].Name, Type: embedded[j].Type, Similarity: similarity})
+			}
+		}
+		if len(proposal.Members) > 0 {
+			proposals = append(proposals, proposal)
+		}
+	}
+	return proposals, nil
+}
+
+// ApplyMergeProposals fusionne les membres de chaque proposition dans son entité conservée et journalise les fusions
+func ApplyMergeProposals(db *sql.DB, proposals []MergeProposal) ([]MergeRecord, error) {
+	entities, err := GetAllEntities(db)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*model.OntologyElement, len(entities))
+	for _, entity := range entities {
+		byName[entity.Name] = entity
+	}
+
+	var records []MergeRecord
+	for _, proposal := range proposals {
+		kept := byName[proposal.Kept]
+		if kept == nil {
+			log.Warning("Entity %s no longer exists, proposal skipped", proposal.Kept)
+			continue
+		}
+		for _, member := range proposal.Members {
+			other := byName[member.Name]
+			if other == nil || other == kept {
+				continue
+			}
+			record := MergeRecord{
+				Kept:      kept.Name,
+				Merged:    other.Name,
+				Score:     member.Similarity,
+				Reason:    reasonEmbedding,
+				CreatedAt: time.Now(),
+			}
+			mergeElementInto(kept, other)
+			if err := MergeEntity(db, kept, record); err != nil {
+				return records, err
+			}
+			delete(byName, other.Name)
+			records = append(records, record)
+			log.Info("Merged entity %s into %s (similarity %.2f)", other.Name, kept.Name, member.Similarity)
+		}
+	}
+	return records, nil
+}
+
+// cosineSimilarity retourne la similarité cosinus de deux vecteurs, 0 si leurs dimensions diffèrent
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
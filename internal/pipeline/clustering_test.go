@@ -0,0 +1,57 @@
+// pipeline/clustering_test.go
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterEntitiesProposesMerges(t *testing.T) {
+	p := newTestPipeline()
+	now := time.Now()
+	for _, entity := range []*model.OntologyElement{
+		{Name: "Politique_de_sécurité_du_SI", Type: "Document", Description: "Politique de sécurité du système d'information",
+			Mentions: []model.Mention{{FileID: "a1b2c3d4e5f6", Start: 0, End: 27}}},
+		{Name: "Politique_sécurité_SI", Type: "Document", Description: "Politique de sécurité du système d'information"},
+		{Name: "Responsable_des_achats", Type: "Role", Description: "Pilote les achats de l'entreprise"},
+	} {
+		entity.CreatedAt, entity.UpdatedAt = now, now
+		assert.NoError(t, UpsertEntity(p.db, entity))
+	}
+
+	embedder := llm.NewHashingEmbedder(0)
+	computed, err := EmbedEntities(p.db, embedder)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, computed)
+
+	// Les vecteurs enregistrés sont réutilisés tant que l'entité ne change pas
+	computed, err = EmbedEntities(p.db, embedder)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, computed)
+
+	embeddings, err := GetEmbeddings(p.db, embedder.Model())
+	assert.NoError(t, err)
+	assert.Len(t, embeddings, 3)
+	assert.Len(t, embeddings["Responsable_des_achats"].Vector, 256)
+
+	proposals, err := ClusterEntities(p.db, embedder.Model(), 0.8)
+	assert.NoError(t, err)
+	if assert.Len(t, proposals, 1) && assert.Len(t, proposals[0].Members, 1) {
+		assert.Equal(t, "Politique_de_sécurité_du_SI", proposals[0].Kept)
+		assert.Equal(t, "Politique_sécurité_SI", proposals[0].Members[0].Name)
+	}
+
+	records, err := ApplyMergeProposals(p.db, proposals)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, reasonEmbedding, records[0].Reason)
+	}
+	entities, err := GetAllEntities(p.db)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+}
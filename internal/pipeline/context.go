@@ -2,17 +2,24 @@ package pipeline
 
 import (
 	"github.com/chrlesur/Ontology/internal/logger"
+	"github.com/chrlesur/Ontology/internal/model"
 )
 
 var log = logger.GetLogger()
 
-// ContextEntry représente le contexte pour une position spécifique dans le document
+// ContextEntry représente le contexte d'une mention dans le document.
+// Position et Length sont exprimés en octets dans le contenu de la passe, FilePosition et FileEnd
+// en octets dans le texte extrait du fichier source.
 type ContextEntry struct {
     Position       int      `json:"position"`
     FileID         string   `json:"file_id"`
     FilePosition   int      `json:"file_position"`
+    FileEnd        int      `json:"file_end"`
+    Page           int      `json:"page,omitempty"`
+    Paragraph      int      `json:"paragraph,omitempty"`
     Before         []string `json:"before"`
     After          []string `json:"after"`
     Element        string   `json:"element"`
     Length         int      `json:"length"`
+    Provenance     []model.Provenance `json:"provenance,omitempty"`
 }
@@ -5,31 +5,30 @@ package pipeline
 import (
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 	"sort"
 	"strings"
 
-	"github.com/chrlesur/Ontology/internal/metadata"
-	"github.com/chrlesur/Ontology/internal/storage"
+	"github.com/chrlesur/Ontology/internal/model"
 )
 
-// GenerateContextJSON génère un JSON contenant le contexte pour chaque position donnée
-func GenerateContextJSON(content []byte, positions []int, contextWords int, positionRanges []PositionRange, fileMetadata map[string]metadata.FileMetadata, storage storage.Storage) (string, error) {
+// GenerateContextJSON génère un JSON contenant le contexte de chaque plage d'octets donnée.
+// locate situe une plage du contenu dans son fichier source ; le paramètre provenance associe
+// à chaque élément les fichiers, segments et passes qui l'ont produit.
+func GenerateContextJSON(content []byte, contextWords int, positionRanges []PositionRange, locate func(start, end int) model.Mention, provenance map[string][]model.Provenance) (string, error) {
 	log.Debug("Starting GenerateContextJSON")
-	log.Debug("Number of positions: %d, Context words: %d", len(positions), contextWords)
-	log.Debug("Number of files in metadata: %d", len(fileMetadata))
+	log.Debug("Number of position ranges: %d, Context words: %d", len(positionRanges), contextWords)
 
-	words := strings.Fields(string(content))
-	log.Debug("Total words in content: %d", len(words))
+	spans := fieldSpans(content)
+	log.Debug("Total words in content: %d", len(spans))
 
-	// Afficher les détails de chaque fichier
-	for id, fileMeta := range fileMetadata {
-		log.Debug("File %s: %s, Directory: %s", id, fileMeta.SourceFile, fileMeta.Directory)
+	words := func(from, to int) []string {
+		result := []string{}
+		for _, span := range spans[from:to] {
+			result = append(result, string(content[span.Start:span.End]))
+		}
+		return result
 	}
 
-	log.Debug("First 10 words of content: %v", words[:min(10, len(words))])
-	log.Debug("Last 10 words of content: %v", words[max(0, len(words)-10):])
-
 	sort.Slice(positionRanges, func(i, j int) bool {
 		return positionRanges[i].Start < positionRanges[j].Start
 	})
@@ -37,105 +36,55 @@ func GenerateContextJSON(content []byte, positions []int, contextWords int, posi
 	var entries []ContextEntry
 	var lastContextEnd int = -1
 
-	fileOffsets := make(map[string]int)
-	var currentOffset int = 0
-	for id, fileMeta := range fileMetadata {
-		fullPath := filepath.Join(fileMeta.Directory, fileMeta.SourceFile)
-		log.Debug("Attempting to read file: %s", fullPath)
-		fileContent, err := storage.Read(fullPath)
-		if err != nil {
-			log.Warning("Failed to read file content for %s: %v", fullPath, err)
-			continue
-		}
-		fileWords := strings.Fields(string(fileContent))
-		log.Debug("File %s: %d words, offset: %d", id, len(fileWords), currentOffset)
-		fileOffsets[id] = currentOffset
-		currentOffset += len(fileWords)
-	}
-	log.Debug("Total words across all files: %d", currentOffset)
-	log.Debug("File offsets: %+v", fileOffsets)
-
-	// Convertir la map en slice pour pouvoir la trier
-	type fileOffsetPair struct {
-		id     string
-		offset int
-	}
-	
-	var sortedOffsets []fileOffsetPair
-	for id, offset := range fileOffsets {
-		sortedOffsets = append(sortedOffsets, fileOffsetPair{id, offset})
-	}
-
-	// Trier les offsets
-	sort.Slice(sortedOffsets, func(i, j int) bool {
-		return sortedOffsets[i].offset < sortedOffsets[j].offset
-	})
-
-	log.Debug("Processing %d position ranges", len(positionRanges))
 	for _, pr := range positionRanges {
-		start := pr.Start
-		end := pr.End
 		element := pr.Element
 
-		if start < 0 || end >= len(words) {
-			log.Warning("Invalid position range for element %s: [%d, %d]", element, start, end)
+		if pr.Start < 0 || pr.End > len(content) || pr.End <= pr.Start {
+			log.Warning("Invalid position range for element %s: [%d, %d)", element, pr.Start, pr.End)
 			continue
 		}
 
-		// Trouver le fichier correspondant à cette position
-		var fileID string
-		var fileStartOffset int
-		for i, pair := range sortedOffsets {
-			nextOffset := currentOffset // Par défaut, considérez que c'est le dernier fichier
-			if i < len(sortedOffsets)-1 {
-				nextOffset = sortedOffsets[i+1].offset
-			}
-			if start >= pair.offset && start < nextOffset {
-				fileID = pair.id
-				fileStartOffset = pair.offset
-				break
-			}
-		}
-
-		if fileID == "" {
-			log.Warning("Could not find corresponding file for position %d", start)
+		// Premier et dernier mots couverts par la plage
+		first := sort.Search(len(spans), func(i int) bool { return spans[i].End > pr.Start })
+		last := sort.Search(len(spans), func(i int) bool { return spans[i].Start >= pr.End }) - 1
+		if first >= len(spans) || last < first {
+			log.Warning("No word found for element %s in range [%d, %d)", element, pr.Start, pr.End)
 			continue
 		}
 
-		log.Debug("Found file %s for position %d (file offset: %d)", fileID, start, fileStartOffset)
-
-		beforeStart := max(0, start-contextWords)
-		afterEnd := min(len(words), end+contextWords+1)
+		mention := locate(pr.Start, pr.End)
+		if mention.FileID == "" {
+			log.Warning("Could not find corresponding file for position %d", pr.Start)
+		}
 
-		var before, after []string
+		beforeStart := max(0, first-contextWords)
+		afterEnd := min(len(spans), last+contextWords+1)
 
+		var before []string
 		if beforeStart > lastContextEnd {
-			before = words[beforeStart:start]
-		} else if lastContextEnd < start {
-			before = words[lastContextEnd+1 : start]
+			before = words(beforeStart, first)
+		} else if lastContextEnd < first {
+			before = words(lastContextEnd+1, first)
 		} else {
 			before = []string{}
 		}
-
-		if end+1 < afterEnd {
-			after = words[end+1 : afterEnd]
-		} else {
-			after = []string{}
-		}
-
-		filePosition := start - fileStartOffset
+		after := words(last+1, afterEnd)
 
 		entry := ContextEntry{
-			Position:     start,
-			FileID:       fileID,
-			FilePosition: filePosition,
+			Position:     pr.Start,
+			FileID:       mention.FileID,
+			FilePosition: mention.Start,
+			FileEnd:      mention.End,
+			Page:         mention.Page,
+			Paragraph:    mention.Paragraph,
 			Before:       before,
 			After:        after,
 			Element:      element,
-			Length:       end - start + 1,
+			Length:       pr.End - pr.Start,
+			Provenance:   provenance[element],
 		}
 		entries = append(entries, entry)
-		log.Debug("Generated context for element %s at position %d, file position %d in file %s", element, start, filePosition, fileID)
+		log.Debug("Generated context for element %s at byte %d, file bytes [%d, %d) in file %s", element, pr.Start, mention.Start, mention.End, mention.FileID)
 
 		lastContextEnd = max(lastContextEnd, afterEnd-1)
 	}
@@ -2,20 +2,37 @@ package pipeline
 
 import (
 	"database/sql"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/chrlesur/Ontology/internal/metadata"
 	"github.com/chrlesur/Ontology/internal/model"
 	_ "modernc.org/sqlite"
 )
 
-func initDB() (*sql.DB, error) {
-	log.Debug("Initializing in-memory SQLite database")
-	db, err := sql.Open("sqlite", ":memory:")
+// initDB ouvre la base de projet SQLite : en mémoire si path est vide, sur disque sinon afin que
+// les entités et relations soient conservées d'une exécution à l'autre.
+func initDB(path string) (*sql.DB, error) {
+	dsn := ":memory:"
+	if path != "" {
+		log.Debug("Opening SQLite project database: %s", path)
+		dsn = path
+	} else {
+		log.Debug("Initializing in-memory SQLite database")
+	}
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		log.Error("Failed to open database: %v", err)
 		return nil, fmt.Errorf("échec de l'ouverture de la base de données : %w", err)
 	}
+	// Chaque connexion à ":memory:" ouvre une base distincte : les segments traités en parallèle
+	// doivent partager la même connexion pour voir les mêmes tables.
+	db.SetMaxOpenConns(1)
 
 	// Création de la table des entités et de l'index
 	_, err = db.Exec(`
@@ -60,33 +77,421 @@ func initDB() (*sql.DB, error) {
 	}
 	log.Debug("Relations table and indexes created successfully")
 
+	if err := migrateDB(db); err != nil {
+		log.Error("Failed to migrate database: %v", err)
+		return nil, fmt.Errorf("échec de la migration de la base de données : %w", err)
+	}
+
 	log.Debug("Database initialized successfully")
 	return db, nil
 }
 
+// migrations contient les évolutions successives du schéma, appliquées une seule fois grâce à PRAGMA user_version
+var migrations = []string{
+	// 1 : exécutions et provenance des entités et relations
+	`
+        CREATE TABLE IF NOT EXISTS runs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            input TEXT NOT NULL,
+            passes INTEGER,
+            started_at DATETIME,
+            completed_at DATETIME
+        );
+        CREATE TABLE IF NOT EXISTS provenance (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            run_id INTEGER NOT NULL REFERENCES runs(id),
+            pass INTEGER NOT NULL,
+            kind TEXT NOT NULL,
+            item TEXT NOT NULL,
+            source_files TEXT,
+            created_at DATETIME,
+            UNIQUE(run_id, pass, kind, item)
+        );
+        CREATE INDEX IF NOT EXISTS idx_provenance_item ON provenance(kind, item);
+    `,
+	// 2 : provenance par segment et identifiants des fichiers sources
+	`
+        ALTER TABLE provenance RENAME TO provenance_v1;
+        CREATE TABLE provenance (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            run_id INTEGER NOT NULL REFERENCES runs(id),
+            pass INTEGER NOT NULL,
+            segment INTEGER NOT NULL DEFAULT -1,
+            kind TEXT NOT NULL,
+            item TEXT NOT NULL,
+            file_ids TEXT,
+            source_files TEXT,
+            created_at DATETIME,
+            UNIQUE(run_id, pass, segment, kind, item)
+        );
+        INSERT INTO provenance (run_id, pass, segment, kind, item, source_files, created_at)
+            SELECT run_id, pass, -1, kind, item, source_files, created_at FROM provenance_v1;
+        DROP TABLE provenance_v1;
+        CREATE INDEX IF NOT EXISTS idx_provenance_item ON provenance(kind, item);
+    `,
+	// 3 : mentions des entités (fichier, plage d'octets, page ou paragraphe)
+	`
+        ALTER TABLE entities ADD COLUMN mentions TEXT;
+    `,
+	// 4 : alias des entités (sigles, variantes de surface)
+	`
+        CREATE TABLE IF NOT EXISTS aliases (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            entity TEXT NOT NULL,
+            alias TEXT NOT NULL,
+            created_at DATETIME,
+            UNIQUE(entity, alias)
+        );
+        CREATE INDEX IF NOT EXISTS idx_aliases_alias ON aliases(alias);
+    `,
+	// 5 : journal des fusions d'entités de la résolution locale
+	`
+        CREATE TABLE IF NOT EXISTS merges (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            run_id INTEGER,
+            pass INTEGER,
+            kept TEXT NOT NULL,
+            merged TEXT NOT NULL,
+            score REAL,
+            reason TEXT,
+            created_at DATETIME
+        );
+    `,
+	// 6 : vecteurs d'embedding des entités, par modèle
+	`
+        CREATE TABLE IF NOT EXISTS embeddings (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            entity TEXT NOT NULL,
+            model TEXT NOT NULL,
+            text_hash TEXT NOT NULL,
+            dimensions INTEGER NOT NULL,
+            vector BLOB NOT NULL,
+            created_at DATETIME,
+            UNIQUE(entity, model)
+        );
+    `,
+}
+
+// OpenDatabase ouvre une base de projet existante, ou la crée, et applique les migrations en attente
+func OpenDatabase(path string) (*sql.DB, error) {
+	return initDB(path)
+}
+
+// migrateDB applique les migrations qui n'ont pas encore été appliquées à la base
+func migrateDB(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	for i := version; i < len(migrations); i++ {
+		log.Debug("Applying database migration %d", i+1)
+		if _, err := db.Exec(migrations[i]); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			return fmt.Errorf("failed to update schema version: %w", err)
+		}
+	}
+	return nil
+}
+
+// Types d'éléments suivis dans la table provenance
+const (
+	provenanceEntity   = "entity"
+	provenanceRelation = "relation"
+)
+
+// ProvenanceRecord indique quelle exécution, quelle passe, quel segment et quels fichiers sources ont produit une ligne
+type ProvenanceRecord struct {
+	RunID       int64
+	Pass        int
+	Segment     int
+	FileIDs     []string
+	SourceFiles []string
+	CreatedAt   time.Time
+}
+
+// Entries convertit l'enregistrement en une provenance par fichier source
+func (r ProvenanceRecord) Entries() []model.Provenance {
+	entries := make([]model.Provenance, len(r.FileIDs))
+	for i, fileID := range r.FileIDs {
+		entries[i] = model.Provenance{FileID: fileID, Segment: r.Segment, Pass: r.Pass}
+	}
+	return entries
+}
+
+// StartRun enregistre le début d'une exécution du pipeline et retourne son identifiant
+func StartRun(db *sql.DB, input string, passes int) (int64, error) {
+	res, err := db.Exec(`INSERT INTO runs (input, passes, started_at) VALUES (?, ?, ?)`, input, passes, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to record run: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// CompleteRun enregistre la fin d'une exécution du pipeline
+func CompleteRun(db *sql.DB, runID int64) error {
+	if _, err := db.Exec(`UPDATE runs SET completed_at = ? WHERE id = ?`, time.Now(), runID); err != nil {
+		return fmt.Errorf("failed to complete run: %w", err)
+	}
+	return nil
+}
+
+// RecordProvenance enregistre les fichiers sources, le segment et la passe ayant produit une entité ou une relation.
+// Les identifiants des fichiers sont ceux de metadata.FileMetadata.
+func RecordProvenance(db *sql.DB, runID int64, pass, segment int, kind, item string, sourceFiles []string) error {
+	fileIDs := make([]string, len(sourceFiles))
+	for i, sourceFile := range sourceFiles {
+		fileIDs[i] = metadata.FileID(sourceFile)
+	}
+	fileIDsJSON, err := json.Marshal(fileIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal file IDs: %w", err)
+	}
+	sourcesJSON, err := json.Marshal(sourceFiles)
+	if err != nil {
+		return fmt.Errorf("failed to marshal source files: %w", err)
+	}
+	_, err = db.Exec(`
+        INSERT INTO provenance (run_id, pass, segment, kind, item, file_ids, source_files, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(run_id, pass, segment, kind, item) DO UPDATE SET
+        file_ids = excluded.file_ids,
+        source_files = excluded.source_files
+    `, runID, pass, segment, kind, item, fileIDsJSON, sourcesJSON, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record provenance: %w", err)
+	}
+	return nil
+}
+
+// GetProvenance retourne l'historique de production d'une entité ou d'une relation
+func GetProvenance(db *sql.DB, kind, item string) ([]ProvenanceRecord, error) {
+	all, err := GetAllProvenance(db, kind)
+	if err != nil {
+		return nil, err
+	}
+	return all[item], nil
+}
+
+// GetAllProvenance retourne l'historique de production de tous les éléments d'un type, indexé par élément
+func GetAllProvenance(db *sql.DB, kind string) (map[string][]ProvenanceRecord, error) {
+	rows, err := db.Query(`
+        SELECT item, run_id, pass, segment, file_ids, source_files, created_at FROM provenance
+        WHERE kind = ? ORDER BY run_id, pass, segment
+    `, kind)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query provenance: %w", err)
+	}
+	defer rows.Close()
+
+	records := make(map[string][]ProvenanceRecord)
+	for rows.Next() {
+		var item string
+		var record ProvenanceRecord
+		var fileIDsJSON, sourcesJSON []byte
+		if err := rows.Scan(&item, &record.RunID, &record.Pass, &record.Segment, &fileIDsJSON, &sourcesJSON, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan provenance: %w", err)
+		}
+		if len(fileIDsJSON) > 0 {
+			if err := json.Unmarshal(fileIDsJSON, &record.FileIDs); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal file IDs: %w", err)
+			}
+		}
+		if len(sourcesJSON) > 0 {
+			if err := json.Unmarshal(sourcesJSON, &record.SourceFiles); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal source files: %w", err)
+			}
+		}
+		// Les lignes migrées depuis le schéma 1 n'ont pas d'identifiants de fichiers
+		if len(record.FileIDs) == 0 {
+			for _, sourceFile := range record.SourceFiles {
+				record.FileIDs = append(record.FileIDs, metadata.FileID(sourceFile))
+			}
+		}
+		records[item] = append(records[item], record)
+	}
+	return records, rows.Err()
+}
+
+// RetractSourceFiles supprime les entités et relations qui n'ont été produites qu'à partir des fichiers supprimés,
+// ainsi que les relations qui référencent une entité supprimée. Elle retourne le nombre d'entités et de relations retirées.
+func RetractSourceFiles(db *sql.DB, deletedFiles []string) (int, int, error) {
+	deleted := make(map[string]bool)
+	for _, file := range deletedFiles {
+		deleted[filepath.Clean(file)] = true
+	}
+
+	rows, err := db.Query(`SELECT kind, item, source_files FROM provenance`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query provenance: %w", err)
+	}
+	// Un élément n'est retiré que si toutes ses provenances désignent des fichiers supprimés
+	solelyDeleted := make(map[[2]string]bool)
+	for rows.Next() {
+		var kind, item string
+		var sourcesJSON []byte
+		if err := rows.Scan(&kind, &item, &sourcesJSON); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("failed to scan provenance: %w", err)
+		}
+		var sources []string
+		if len(sourcesJSON) > 0 {
+			if err := json.Unmarshal(sourcesJSON, &sources); err != nil {
+				rows.Close()
+				return 0, 0, fmt.Errorf("failed to unmarshal source files: %w", err)
+			}
+		}
+		key := [2]string{kind, item}
+		onlyDeleted := len(sources) > 0
+		for _, source := range sources {
+			if !deleted[filepath.Clean(source)] {
+				onlyDeleted = false
+				break
+			}
+		}
+		if previous, seen := solelyDeleted[key]; seen {
+			solelyDeleted[key] = previous && onlyDeleted
+		} else {
+			solelyDeleted[key] = onlyDeleted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("failed to read provenance: %w", err)
+	}
+
+	var entities, relations int
+	for key, retract := range solelyDeleted {
+		if !retract {
+			continue
+		}
+		kind, item := key[0], key[1]
+		switch kind {
+		case provenanceEntity:
+			res, err := db.Exec(`DELETE FROM entities WHERE name = ?`, item)
+			if err != nil {
+				return entities, relations, fmt.Errorf("failed to retract entity %s: %w", item, err)
+			}
+			n, _ := res.RowsAffected()
+			entities += int(n)
+			res, err = db.Exec(`DELETE FROM relations WHERE source = ? OR target = ?`, item, item)
+			if err != nil {
+				return entities, relations, fmt.Errorf("failed to retract relations of %s: %w", item, err)
+			}
+			n, _ = res.RowsAffected()
+			relations += int(n)
+			if _, err := db.Exec(`DELETE FROM aliases WHERE entity = ?`, item); err != nil {
+				return entities, relations, fmt.Errorf("failed to retract aliases of %s: %w", item, err)
+			}
+			if _, err := db.Exec(`DELETE FROM embeddings WHERE entity = ?`, item); err != nil {
+				return entities, relations, fmt.Errorf("failed to retract embeddings of %s: %w", item, err)
+			}
+		case provenanceRelation:
+			parts := strings.SplitN(item, "\t", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			res, err := db.Exec(`DELETE FROM relations WHERE source = ? AND type = ? AND target = ?`, parts[0], parts[1], parts[2])
+			if err != nil {
+				return entities, relations, fmt.Errorf("failed to retract relation %s: %w", item, err)
+			}
+			n, _ := res.RowsAffected()
+			relations += int(n)
+		}
+		if _, err := db.Exec(`DELETE FROM provenance WHERE kind = ? AND item = ?`, kind, item); err != nil {
+			return entities, relations, fmt.Errorf("failed to delete provenance of %s: %w", item, err)
+		}
+	}
+	return entities, relations, nil
+}
+
+// relationProvenanceKey identifie une relation dans la table provenance
+func relationProvenanceKey(relation *model.Relation) string {
+	return relation.Source + "\t" + relation.Type + "\t" + relation.Target
+}
+
 func UpsertEntity(db *sql.DB, entity *model.OntologyElement) error {
     log.Debug("Upserting entity: %+v", entity)
     positionsJSON, err := json.Marshal(entity.Positions)
     if err != nil {
         return fmt.Errorf("failed to marshal positions: %w", err)
     }
+    mentionsJSON, err := json.Marshal(entity.Mentions)
+    if err != nil {
+        return fmt.Errorf("failed to marshal mentions: %w", err)
+    }
     _, err = db.Exec(`
-        INSERT INTO entities (name, type, description, positions, created_at, updated_at, source)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
+        INSERT INTO entities (name, type, description, positions, mentions, created_at, updated_at, source)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
         ON CONFLICT(name) DO UPDATE SET
         type = ?,
         description = ?,
         positions = ?,
+        mentions = ?,
         updated_at = ?,
         source = ?
-    `, entity.Name, entity.Type, entity.Description, positionsJSON, entity.CreatedAt, entity.UpdatedAt, entity.Source,
-       entity.Type, entity.Description, positionsJSON, entity.UpdatedAt, entity.Source)
+    `, entity.Name, entity.Type, entity.Description, positionsJSON, mentionsJSON, entity.CreatedAt, entity.UpdatedAt, entity.Source,
+       entity.Type, entity.Description, positionsJSON, mentionsJSON, entity.UpdatedAt, entity.Source)
     if err != nil {
         log.Error("Failed to upsert entity: %v", err)
         return fmt.Errorf("failed to upsert entity: %w", err)
     }
     log.Debug("Entity upserted successfully with positions: %v", entity.Positions)
-    return nil
+    return UpsertAliases(db, entity.Name, entity.Aliases)
+}
+
+// UpsertAliases enregistre les alias d'une entité ; les alias déjà connus sont ignorés
+func UpsertAliases(db *sql.DB, entity string, aliases []string) error {
+	for _, alias := range aliases {
+		_, err := db.Exec(`INSERT OR IGNORE INTO aliases (entity, alias, created_at) VALUES (?, ?, ?)`,
+			entity, alias, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to upsert alias %s of %s: %w", alias, entity, err)
+		}
+	}
+	return nil
+}
+
+// GetAliases retourne les alias d'une entité, dans leur ordre d'enregistrement
+func GetAliases(db *sql.DB, entity string) ([]string, error) {
+	rows, err := db.Query(`SELECT alias FROM aliases WHERE entity = ? ORDER BY id`, entity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aliases of %s: %w", entity, err)
+	}
+	defer rows.Close()
+
+	var aliases []string
+	for rows.Next() {
+		var alias string
+		if err := rows.Scan(&alias); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, rows.Err()
+}
+
+// GetAllAliases retourne les alias de chaque entité, dans leur ordre d'enregistrement
+func GetAllAliases(db *sql.DB) (map[string][]string, error) {
+	rows, err := db.Query(`SELECT entity, alias FROM aliases ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := make(map[string][]string)
+	for rows.Next() {
+		var entity, alias string
+		if err := rows.Scan(&entity, &alias); err != nil {
+			return nil, fmt.Errorf("failed to scan alias: %w", err)
+		}
+		aliases[entity] = append(aliases[entity], alias)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+	return aliases, nil
 }
 
 func UpsertRelation(db *sql.DB, relation *model.Relation) error {
@@ -97,7 +502,7 @@ func UpsertRelation(db *sql.DB, relation *model.Relation) error {
         ON CONFLICT(source, type, target) DO UPDATE SET
         description = ?,
         weight = ?,
-        direction = ?,
+        direction = COALESCE(?, direction),
         updated_at = ?
     `, relation.Source, relation.Type, relation.Target, relation.Description, relation.Weight, relation.Direction,
         relation.CreatedAt, relation.UpdatedAt,
@@ -109,10 +514,194 @@ func UpsertRelation(db *sql.DB, relation *model.Relation) error {
     return nil
 }
 
+// MergeRecord décrit la fusion d'une entité dans une autre par la résolution locale
+type MergeRecord struct {
+	RunID     int64     `json:"run_id,omitempty"`
+	Pass      int       `json:"pass"`
+	Kept      string    `json:"kept"`
+	Merged    string    `json:"merged"`
+	Score     float64   `json:"score"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MergeEntity fusionne une entité dans l'entité conservée, déjà complétée par l'appelant :
+// l'entité fusionnée est supprimée, ses relations, sa provenance et ses alias sont reportés sur l'entité conservée,
+// son nom devient un alias, et la fusion est inscrite au journal
+func MergeEntity(db *sql.DB, kept *model.OntologyElement, record MergeRecord) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin merge of %s: %w", record.Merged, err)
+	}
+	defer tx.Rollback()
+
+	positionsJSON, err := json.Marshal(kept.Positions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal positions: %w", err)
+	}
+	mentionsJSON, err := json.Marshal(kept.Mentions)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mentions: %w", err)
+	}
+	merged := record.Merged
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE entities SET type = ?, description = ?, positions = ?, mentions = ?, source = ?, updated_at = ? WHERE name = ?`,
+			[]interface{}{kept.Type, kept.Description, positionsJSON, mentionsJSON, kept.Source, kept.UpdatedAt, kept.Name}},
+		{`DELETE FROM entities WHERE name = ?`, []interface{}{merged}},
+		// Les relations déjà connues pour l'entité conservée l'emportent sur celles de l'entité fusionnée
+		{`UPDATE OR IGNORE relations SET source = ? WHERE source = ?`, []interface{}{kept.Name, merged}},
+		{`UPDATE OR IGNORE relations SET target = ? WHERE target = ?`, []interface{}{kept.Name, merged}},
+		{`DELETE FROM relations WHERE source = ? OR target = ?`, []interface{}{merged, merged}},
+		{`DELETE FROM relations WHERE source = ? AND target = ?`, []interface{}{kept.Name, kept.Name}},
+		{`UPDATE OR IGNORE provenance SET item = ? WHERE kind = ? AND item = ?`, []interface{}{kept.Name, provenanceEntity, merged}},
+		{`DELETE FROM provenance WHERE kind = ? AND item = ?`, []interface{}{provenanceEntity, merged}},
+		{`DELETE FROM embeddings WHERE entity = ? OR entity = ?`, []interface{}{merged, kept.Name}},
+		{`UPDATE OR IGNORE aliases SET entity = ? WHERE entity = ?`, []interface{}{kept.Name, merged}},
+		{`DELETE FROM aliases WHERE entity = ? OR (entity = ? AND alias = ?)`, []interface{}{merged, kept.Name, kept.Name}},
+		{`INSERT OR IGNORE INTO aliases (entity, alias, created_at) VALUES (?, ?, ?)`, []interface{}{kept.Name, merged, record.CreatedAt}},
+		{`INSERT INTO merges (run_id, pass, kept, merged, score, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			[]interface{}{record.RunID, record.Pass, kept.Name, merged, record.Score, record.Reason, record.CreatedAt}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return fmt.Errorf("failed to merge %s into %s: %w", merged, kept.Name, err)
+		}
+	}
+	if err := renameRelationProvenance(tx, merged, kept.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// renameRelationProvenance reporte la provenance des relations d'une entité fusionnée sur l'entité conservée
+func renameRelationProvenance(tx *sql.Tx, from, to string) error {
+	rows, err := tx.Query(`SELECT id, item FROM provenance WHERE kind = ?`, provenanceRelation)
+	if err != nil {
+		return fmt.Errorf("failed to query relation provenance: %w", err)
+	}
+	renamed := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var item string
+		if err := rows.Scan(&id, &item); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan relation provenance: %w", err)
+		}
+		parts := strings.SplitN(item, "\t", 3)
+		if len(parts) != 3 || (parts[0] != from && parts[2] != from) {
+			continue
+		}
+		for _, i := range []int{0, 2} {
+			if parts[i] == from {
+				parts[i] = to
+			}
+		}
+		renamed[id] = strings.Join(parts, "\t")
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read relation provenance: %w", err)
+	}
+
+	for id, item := range renamed {
+		if _, err := tx.Exec(`UPDATE OR IGNORE provenance SET item = ? WHERE id = ?`, item, id); err != nil {
+			return fmt.Errorf("failed to rename relation provenance: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM provenance WHERE id = ? AND item != ?`, id, item); err != nil {
+			return fmt.Errorf("failed to delete relation provenance: %w", err)
+		}
+	}
+	return nil
+}
+
+// Embedding est le vecteur d'une entité calculé par un modèle, avec l'empreinte du texte qui l'a produit
+type Embedding struct {
+	Entity   string
+	Model    string
+	TextHash string
+	Vector   []float32
+}
+
+// UpsertEmbedding enregistre le vecteur d'une entité pour un modèle, en remplaçant le précédent
+func UpsertEmbedding(db *sql.DB, embedding Embedding) error {
+	blob := make([]byte, 4*len(embedding.Vector))
+	for i, value := range embedding.Vector {
+		binary.LittleEndian.PutUint32(blob[4*i:], math.Float32bits(value))
+	}
+	_, err := db.Exec(`
+        INSERT INTO embeddings (entity, model, text_hash, dimensions, vector, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)
+        ON CONFLICT(entity, model) DO UPDATE SET
+        text_hash = excluded.text_hash,
+        dimensions = excluded.dimensions,
+        vector = excluded.vector,
+        created_at = excluded.created_at
+    `, embedding.Entity, embedding.Model, embedding.TextHash, len(embedding.Vector), blob, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to upsert embedding of %s: %w", embedding.Entity, err)
+	}
+	return nil
+}
+
+// GetEmbeddings retourne les vecteurs calculés par un modèle, indexés par entité
+func GetEmbeddings(db *sql.DB, model string) (map[string]Embedding, error) {
+	rows, err := db.Query(`SELECT entity, text_hash, dimensions, vector FROM embeddings WHERE model = ?`, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	embeddings := make(map[string]Embedding)
+	for rows.Next() {
+		embedding := Embedding{Model: model}
+		var dimensions int
+		var blob []byte
+		if err := rows.Scan(&embedding.Entity, &embedding.TextHash, &dimensions, &blob); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		if len(blob) != 4*dimensions {
+			return nil, fmt.Errorf("corrupted embedding for %s: %d bytes for %d dimensions", embedding.Entity, len(blob), dimensions)
+		}
+		embedding.Vector = make([]float32, dimensions)
+		for i := range embedding.Vector {
+			embedding.Vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(blob[4*i:]))
+		}
+		embeddings[embedding.Entity] = embedding
+	}
+	return embeddings, rows.Err()
+}
+
+// GetMerges retourne le journal des fusions d'entités, dans l'ordre où elles ont eu lieu
+func GetMerges(db *sql.DB) ([]MergeRecord, error) {
+	rows, err := db.Query(`SELECT run_id, pass, kept, merged, score, reason, created_at FROM merges ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query merges: %w", err)
+	}
+	defer rows.Close()
+
+	var merges []MergeRecord
+	for rows.Next() {
+		var record MergeRecord
+		if err := rows.Scan(&record.RunID, &record.Pass, &record.Kept, &record.Merged, &record.Score, &record.Reason, &record.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan merge: %w", err)
+		}
+		merges = append(merges, record)
+	}
+	return merges, rows.Err()
+}
+
 func GetAllEntities(db *sql.DB) ([]*model.OntologyElement, error) {
 	log.Debug("Starting GetAllEntities")
 
-	rows, err := db.Query("SELECT name, type, description, positions, created_at, updated_at, source FROM entities")
+	aliases, err := GetAllAliases(db)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT name, type, description, positions, mentions, created_at, updated_at, source FROM entities")
 	if err != nil {
 		log.Error("Failed to query entities: %v", err)
 		return nil, fmt.Errorf("échec de la récupération des entités : %w", err)
@@ -122,8 +711,8 @@ func GetAllEntities(db *sql.DB) ([]*model.OntologyElement, error) {
 	var entities []*model.OntologyElement
 	for rows.Next() {
 		var e model.OntologyElement
-		var positionsJSON []byte
-        err := rows.Scan(&e.Name, &e.Type, &e.Description, &positionsJSON, &e.CreatedAt, &e.UpdatedAt, &e.Source)
+		var positionsJSON, mentionsJSON []byte
+        err := rows.Scan(&e.Name, &e.Type, &e.Description, &positionsJSON, &mentionsJSON, &e.CreatedAt, &e.UpdatedAt, &e.Source)
 		if err != nil {
 			log.Error("Failed to scan entity: %v", err)
 			return nil, fmt.Errorf("échec du scan d'une entité : %w", err)
@@ -143,6 +732,14 @@ func GetAllEntities(db *sql.DB) ([]*model.OntologyElement, error) {
 			log.Debug("No positions found for entity %s", e.Name)
 		}
 
+		if len(mentionsJSON) > 0 {
+			if err := json.Unmarshal(mentionsJSON, &e.Mentions); err != nil {
+				log.Error("Failed to unmarshal mentions for entity %s: %v", e.Name, err)
+				return nil, fmt.Errorf("échec du décodage des mentions pour l'entité %s : %w", e.Name, err)
+			}
+		}
+
+		e.Aliases = aliases[e.Name]
 		entities = append(entities, &e)
 	}
 
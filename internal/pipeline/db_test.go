@@ -0,0 +1,139 @@
+// pipeline/db_test.go
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/metadata"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPersistentDatabaseKeepsOntologyAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "project.sqlite")
+
+	db, err := initDB(path)
+	assert.NoError(t, err)
+	runID, err := StartRun(db, "corpus/", 1)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.NoError(t, UpsertEntity(db, &model.OntologyElement{Name: "PSSI", Type: "Document", Description: "Politique de sécurité", CreatedAt: now, UpdatedAt: now}))
+	relation := &model.Relation{Source: "RSSI", Type: "pilote", Target: "PSSI", Weight: 2, CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, UpsertRelation(db, relation))
+	assert.NoError(t, RecordProvenance(db, runID, 1, 0, provenanceEntity, "PSSI", []string{"corpus/pssi.md"}))
+	assert.NoError(t, RecordProvenance(db, runID, 1, 0, provenanceRelation, relationProvenanceKey(relation), []string{"corpus/pssi.md"}))
+	assert.NoError(t, CompleteRun(db, runID))
+	assert.NoError(t, db.Close())
+
+	// Réouverture : le schéma n'est pas réappliqué et les données sont conservées
+	db, err = initDB(path)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	var version int
+	assert.NoError(t, db.QueryRow("PRAGMA user_version").Scan(&version))
+	assert.Equal(t, len(migrations), version)
+
+	p := newTestPipeline()
+	p.db = db
+	result, err := p.loadDatabaseOntology(p.db)
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument\tPolitique de sécurité\nRSSI\tpilote:2\tPSSI\t", result)
+	assert.NotNil(t, p.ontology.GetElementByName("PSSI"))
+
+	records, err := GetProvenance(db, provenanceEntity, "PSSI")
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, runID, records[0].RunID)
+		assert.Equal(t, 1, records[0].Pass)
+		assert.Equal(t, []string{"corpus/pssi.md"}, records[0].SourceFiles)
+		assert.Equal(t, []model.Provenance{{FileID: metadata.FileID("corpus/pssi.md"), Segment: 0, Pass: 1}}, records[0].Entries())
+	}
+
+	secondRun, err := StartRun(db, "corpus/", 1)
+	assert.NoError(t, err)
+	assert.Greater(t, secondRun, runID)
+}
+
+func TestMergeResultsRecordsProvenance(t *testing.T) {
+	p := newTestPipeline()
+	runID, err := StartRun(p.db, "doc.md", 2)
+	assert.NoError(t, err)
+	p.runID, p.currentPass, p.sourceFiles = runID, 2, []string{"doc.md"}
+
+	_, err = p.mergeResultsWithDB("Ancien\tConcept\tIssu d'une passe précédente", []string{"RSSI\tRole\tResponsable\nRSSI\tpilote:3\tPSSI\tPilotage"})
+	assert.NoError(t, err)
+
+	records, err := GetProvenance(p.db, provenanceRelation, "RSSI\tpilote\tPSSI")
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	records, err = GetProvenance(p.db, provenanceEntity, "Ancien")
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestRetractSourceFiles(t *testing.T) {
+	db, err := initDB("")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	for _, name := range []string{"Charte", "PSSI"} {
+		assert.NoError(t, UpsertEntity(db, &model.OntologyElement{Name: name, Type: "Document", CreatedAt: now, UpdatedAt: now}))
+	}
+	assert.NoError(t, UpsertRelation(db, &model.Relation{Source: "PSSI", Type: "référence", Target: "Charte", Weight: 1, CreatedAt: now, UpdatedAt: now}))
+
+	runID, err := StartRun(db, "/corpus", 1)
+	assert.NoError(t, err)
+	assert.NoError(t, RecordProvenance(db, runID, 1, 0, provenanceEntity, "Charte", []string{"/corpus/charte.md"}))
+	assert.NoError(t, RecordProvenance(db, runID, 1, 0, provenanceEntity, "PSSI", []string{"/corpus/charte.md", "/corpus/pssi.md"}))
+
+	entities, relations, err := RetractSourceFiles(db, []string{"/corpus/charte.md"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, entities)
+	assert.Equal(t, 1, relations)
+
+	remaining, err := GetAllEntities(db)
+	assert.NoError(t, err)
+	if assert.Len(t, remaining, 1) {
+		assert.Equal(t, "PSSI", remaining[0].Name)
+	}
+}
+
+func TestProvenanceFollowsSegmentFiles(t *testing.T) {
+	p := newTestPipeline()
+	runID, err := StartRun(p.db, "corpus/", 1)
+	assert.NoError(t, err)
+	p.runID, p.currentPass, p.sourceFiles = runID, 1, []string{"corpus/a.md", "corpus/b.md"}
+	p.fileSpans = []fileSpan{{Path: "corpus/a.md", Start: 0, End: 100}, {Path: "corpus/b.md", Start: 101, End: 200}}
+	p.segmentSpans = []fileSpan{{Start: 0, End: 80}, {Start: 60, End: 150}}
+
+	assert.Equal(t, []string{"corpus/a.md"}, p.segmentSourceFiles(0))
+	assert.Equal(t, []string{"corpus/a.md", "corpus/b.md"}, p.segmentSourceFiles(1))
+
+	_, err = p.mergeResultsWithDB("", []string{"PSSI\tDocument\tPolitique", "RSSI\tRole\tResponsable\nRSSI\tpilote:2\tPSSI\tPilotage"})
+	assert.NoError(t, err)
+
+	p.ontology.AddElement(&model.OntologyElement{Name: "PSSI", Type: "Document"})
+	p.ontology.AddElement(&model.OntologyElement{Name: "RSSI", Type: "Role"})
+	p.ontology.AddRelation(&model.Relation{Source: "RSSI", Type: "pilote", Target: "PSSI", Weight: 2})
+
+	provenance, err := p.attachProvenance()
+	assert.NoError(t, err)
+
+	idA, idB := metadata.FileID("corpus/a.md"), metadata.FileID("corpus/b.md")
+	assert.Equal(t, []model.Provenance{{FileID: idA, Segment: 0, Pass: 1}}, provenance["PSSI"])
+	assert.Equal(t, idA, p.ontology.GetElementByName("PSSI").Source)
+	assert.Equal(t, idA+","+idB, p.ontology.GetElementByName("RSSI").Source)
+	assert.ElementsMatch(t, []model.Provenance{{FileID: idA, Segment: 1, Pass: 1}, {FileID: idB, Segment: 1, Pass: 1}},
+		p.ontology.GetRelation("RSSI", "pilote", "PSSI").Provenance)
+
+	var source string
+	assert.NoError(t, p.db.QueryRow(`SELECT source FROM entities WHERE name = ?`, "RSSI").Scan(&source))
+	assert.Equal(t, idA+","+idB, source)
+}
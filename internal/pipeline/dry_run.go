@@ -0,0 +1,248 @@
+// dry_run.go
+
+package pipeline
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/chrlesur/Ontology/internal/segmenter"
+)
+
+// RunPlan est l'estimation d'une exécution calculée par --dry-run, sans aucun appel au LLM.
+// Les passes suivant la première sont estimées comme la première, avec la même ontologie de départ.
+type RunPlan struct {
+	Provider      string
+	Model         string
+	Mode          string
+	MergeStrategy string
+	Passes        int
+	ContentTokens int
+	Segments      []SegmentPlan
+	MergeCalls    int // appels de fusion projetés par passe, chaque résultat de segment comptant max_tokens
+	InputTokens   int // tokens des prompts de toutes les passes
+	OutputTokens  int // borne haute : max_tokens par appel
+	Costs         []ModelCost
+}
+
+// SegmentPlan décrit un segment et les prompts qui lui seraient envoyés
+type SegmentPlan struct {
+	Number        int // numéro du segment, à partir de 1
+	Start         int
+	End           int
+	Tokens        int
+	ContextTokens int
+	PromptTokens  []int // tokens de chaque prompt du segment : deux en mode two-stage, un sinon
+}
+
+// ModelCost est le coût estimé de l'exécution avec un modèle de la table des prix
+type ModelCost struct {
+	Model    string
+	Cost     float64
+	Selected bool // modèle choisi pour l'exécution
+}
+
+// renderedPrompt est un prompt d'un segment rendu pour l'estimation
+type renderedPrompt struct {
+	name string
+	text string
+}
+
+// SegmentCalls retourne le nombre d'appels au LLM d'une passe pour le traitement des segments
+func (plan *RunPlan) SegmentCalls() int {
+	calls := 0
+	for _, segment := range plan.Segments {
+		calls += len(segment.PromptTokens)
+	}
+	return calls
+}
+
+// Calls retourne le nombre total d'appels au LLM projetés pour l'exécution
+func (plan *RunPlan) Calls() int {
+	return plan.Passes * (plan.SegmentCalls() + plan.MergeCalls)
+}
+
+// PlanRun lit et segmente l'entrée, assemble le prompt de chaque segment et estime les appels, les tokens
+// et le coût de l'exécution, sans appeler le LLM ni modifier la base de projet.
+// Lorsque promptDir n'est pas vide, les prompts rendus y sont écrits, un fichier par prompt.
+func (p *Pipeline) PlanRun(input string, output string, passes int, existingOntology string, promptDir string) (*RunPlan, error) {
+	p.inputPath = input
+	countTokens, err := p.tokenCounterFunc()
+	if err != nil {
+		return nil, err
+	}
+	p.tokenCounter = countTokens
+
+	projectDB, err := openProjectDBReadOnly(p.config.Database)
+	if err != nil {
+		return nil, err
+	}
+	if projectDB != nil {
+		defer projectDB.Close()
+	}
+	previousResult, err := p.prepareRun(output, existingOntology, projectDB)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RunPlan{
+		Provider:      p.llmProvider,
+		Model:         p.llmModel,
+		Mode:          p.config.Mode,
+		MergeStrategy: p.config.MergeStrategy,
+		Passes:        passes,
+	}
+	if p.incrementalFiles != nil && len(p.incrementalFiles) == 0 {
+		log.Info("No new or changed files since the previous run, nothing would be sent to the LLM")
+		plan.Passes = 0
+		return plan, nil
+	}
+
+	content, err := p.readInput(input)
+	if err != nil {
+		return nil, err
+	}
+	plan.ContentTokens = countTokens(string(content))
+
+	segmentConfig := segmenter.SegmentConfig{
+		MaxTokens:   p.config.MaxTokens,
+		ContextSize: p.config.ContextSize,
+		Model:       p.config.DefaultModel,
+	}
+	segments, _, err := segmenter.Segment(content, segmentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to segment content: %w", err)
+	}
+
+	if promptDir != "" {
+		if err := os.MkdirAll(promptDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create prompt directory: %w", err)
+		}
+	}
+
+	promptTokens := 0
+	for i, segment := range segments {
+		context := segmenter.GetContext(segments, i, segmentConfig)
+		prompts, err := p.segmentPrompts(segment.Content, context, previousResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render prompts of segment %d: %w", i+1, err)
+		}
+
+		segmentPlan := SegmentPlan{
+			Number:        i + 1,
+			Start:         segment.Start,
+			End:           segment.End,
+			Tokens:        countTokens(string(segment.Content)),
+			ContextTokens: countTokens(context),
+		}
+		for _, rendered := range prompts {
+			tokens := countTokens(rendered.text)
+			segmentPlan.PromptTokens = append(segmentPlan.PromptTokens, tokens)
+			promptTokens += tokens
+			if promptDir == "" {
+				continue
+			}
+			path := filepath.Join(promptDir, fmt.Sprintf("segment_%03d_%s.txt", i+1, rendered.name))
+			if err := os.WriteFile(path, []byte(rendered.text), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write prompt: %w", err)
+			}
+		}
+		plan.Segments = append(plan.Segments, segmentPlan)
+	}
+
+	// Fusion hiérarchique : chaque résultat de segment est compté à max_tokens, chaque appel à merge_max_tokens
+	mergeTokens := 0
+	if p.config.MergeStrategy == MergeStrategyLLM {
+		budget := p.config.MergeMaxTokens
+		if budget <= 0 {
+			budget = defaultMergeMaxTokens
+		}
+		var resultTokens []int
+		if previousResult != "" {
+			resultTokens = append(resultTokens, countTokens(previousResult))
+		}
+		for range segments {
+			resultTokens = append(resultTokens, p.config.MaxTokens)
+		}
+		plan.MergeCalls = projectMergeCalls(groupMergeBatches(resultTokens, budget))
+		mergeTokens = plan.MergeCalls * budget
+	}
+
+	plan.InputTokens = passes * (promptTokens + mergeTokens)
+	plan.OutputTokens = plan.Calls() * p.config.MaxTokens
+	plan.Costs = p.planCosts(plan.InputTokens, plan.OutputTokens)
+	return plan, nil
+}
+
+// segmentPrompts rend les prompts qui seraient envoyés pour un segment selon le mode de traitement.
+// En mode two-stage, la liste des entités du prompt des relations, inconnue avant l'extraction, est vide.
+func (p *Pipeline) segmentPrompts(segment []byte, context string, previousResult string) ([]renderedPrompt, error) {
+	type request struct {
+		name     string
+		template *prompt.PromptTemplate
+		values   map[string]string
+	}
+	var requests []request
+	if p.config.Mode == ModeTwoStage {
+		entityPrompt, entityValues := p.entityExtractionRequest(segment, context)
+		relationPrompt, relationValues := p.relationExtractionRequest(segment, nil)
+		requests = append(requests, request{"entities", entityPrompt, entityValues}, request{"relations", relationPrompt, relationValues})
+	} else {
+		enrichmentPrompt, enrichmentValues, err := p.enrichmentRequest(segment, context, previousResult)
+		if err != nil {
+			return nil, err
+		}
+		if p.config.StructuredOutput {
+			enrichmentPrompt = structuredPromptTemplate(enrichmentPrompt)
+		}
+		requests = append(requests, request{"enrichment", enrichmentPrompt, enrichmentValues})
+	}
+
+	var prompts []renderedPrompt
+	for _, req := range requests {
+		text, err := req.template.Format(req.values)
+		if err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, renderedPrompt{name: req.name, text: text})
+	}
+	return prompts, nil
+}
+
+// planCosts estime le coût des tokens avec chaque modèle de la table des prix, par ordre alphabétique
+func (p *Pipeline) planCosts(inputTokens, outputTokens int) []ModelCost {
+	var costs []ModelCost
+	for model, pricing := range p.config.Pricing {
+		costs = append(costs, ModelCost{
+			Model:    model,
+			Cost:     (float64(inputTokens)*pricing.Input + float64(outputTokens)*pricing.Output) / 1e6,
+			Selected: model == p.llmModel,
+		})
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		return costs[i].Model < costs[j].Model
+	})
+	return costs
+}
+
+// openProjectDBReadOnly ouvre la base de projet en lecture seule, nil si aucune n'est configurée ou si elle n'existe pas encore
+func openProjectDBReadOnly(path string) (*sql.DB, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		log.Info("Project database %s does not exist yet, the run would start from an empty database", path)
+		return nil, nil
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open project database: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,64 @@
+// pipeline/dry_run_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectMergeCallsFollowsBatchesThenPairs(t *testing.T) {
+	// 5 résultats de 400 tokens sous un budget de 1000 : lots [0,2) [2,4) [4,5)
+	batches := groupMergeBatches([]int{400, 400, 400, 400, 400}, 1000)
+	assert.Equal(t, [][2]int{{0, 2}, {2, 4}, {4, 5}}, batches)
+
+	// 2 lots fusionnés, puis 3 nœuds réduits en 2 appels
+	assert.Equal(t, 4, projectMergeCalls(batches))
+	assert.Equal(t, 0, projectMergeCalls(groupMergeBatches([]int{400}, 1000)))
+}
+
+func TestSegmentPromptsFollowMode(t *testing.T) {
+	p := newTestPipeline()
+
+	prompts, err := p.segmentPrompts([]byte("Le RSSI rédige la PSSI."), "", "PSSI\tDocument\tPolitique")
+	assert.NoError(t, err)
+	if assert.Len(t, prompts, 1) {
+		assert.Equal(t, "enrichment", prompts[0].name)
+		assert.Contains(t, prompts[0].text, "Le RSSI rédige la PSSI.")
+	}
+
+	p.config.StructuredOutput = true
+	prompts, err = p.segmentPrompts([]byte("Le RSSI rédige la PSSI."), "", "")
+	assert.NoError(t, err)
+	if assert.Len(t, prompts, 1) {
+		assert.Contains(t, prompts[0].text, prompt.StructuredOutputInstructions)
+	}
+
+	p.config.StructuredOutput = false
+	p.config.Mode = ModeTwoStage
+	prompts, err = p.segmentPrompts([]byte("Le RSSI rédige la PSSI."), "", "")
+	assert.NoError(t, err)
+	if assert.Len(t, prompts, 2) {
+		assert.Equal(t, "entities", prompts[0].name)
+		assert.Equal(t, "relations", prompts[1].name)
+	}
+}
+
+func TestPlanCostsPricesEveryModel(t *testing.T) {
+	p := newTestPipeline()
+	p.llmModel = "small"
+	p.config.Pricing = map[string]config.ModelPricing{
+		"small": {Input: 1, Output: 2},
+		"large": {Input: 10, Output: 20},
+	}
+
+	costs := p.planCosts(2_000_000, 500_000)
+
+	assert.Equal(t, []ModelCost{
+		{Model: "large", Cost: 30},
+		{Model: "small", Cost: 3, Selected: true},
+	}, costs)
+}
@@ -3,42 +3,44 @@
 package pipeline
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/chrlesur/Ontology/internal/language"
+	"github.com/chrlesur/Ontology/internal/llm"
 	"github.com/chrlesur/Ontology/internal/model"
 	"github.com/chrlesur/Ontology/internal/prompt"
 )
 
 // processSegment traite un segment individuel du contenu
-func (p *Pipeline) processSegment(segment []byte, context string, previousResult string, includePositions bool, offset int) (string, error) {
-	log.Debug("Processing segment of length %d, context length %d, previous result length %d, offset %d", len(segment), len(context), len(previousResult), offset)
+func (p *Pipeline) processSegment(segment []byte, segmentContext string, previousResult string, includePositions bool, offset int) (string, error) {
+	log.Debug("Processing segment of length %d, context length %d, previous result length %d, offset %d", len(segment), len(segmentContext), len(previousResult), offset)
 	log.Debug("Segment content preview: %s", truncateString(string(segment), 200))
-	log.Debug("Context preview: %s", truncateString(context, 200))
+	log.Debug("Context preview: %s", truncateString(segmentContext, 200))
 
-	enrichmentValues := map[string]string{
-		"text":            string(segment),
-		"context":         context,
-		"previous_result": previousResult,
+	if p.config.Mode == ModeTwoStage {
+		return p.processSegmentTwoStage(segment, segmentContext, includePositions, offset)
 	}
 
-	var enrichmentPrompt *prompt.PromptTemplate
-	if p.enrichmentPromptFile != "" {
-		customPrompt, err := p.readPromptFile(p.enrichmentPromptFile)
-		if err != nil {
-			log.Error("Failed to read custom prompt file: %v", err)
-			return "", fmt.Errorf("failed to read custom prompt file: %w", err)
-		}
-		enrichmentPrompt = prompt.NewCustomPromptTemplate(customPrompt)
-		log.Debug("Using custom enrichment prompt from file: %s", p.enrichmentPromptFile)
-	} else {
-		enrichmentPrompt = prompt.OntologyEnrichmentPrompt
-		log.Debug("Using default enrichment prompt")
+	enrichmentPrompt, enrichmentValues, err := p.enrichmentRequest(segment, segmentContext, previousResult)
+	if err != nil {
+		return "", err
+	}
+
+	if p.config.StructuredOutput {
+		log.Debug("Calling LLM with OntologyEnrichmentPrompt in structured output mode")
+		return p.processSegmentStructured(enrichmentPrompt, enrichmentValues, includePositions, offset)
 	}
 
 	log.Debug("Calling LLM with OntologyEnrichmentPrompt")
 
-	enrichedResult, err := p.llm.ProcessWithPrompt(enrichmentPrompt, enrichmentValues)
+	enrichedResult, err := p.callLLM(offset, enrichmentPrompt, enrichmentValues, func(ctx context.Context) (string, llm.Usage, error) {
+		return p.llm.ProcessWithPrompt(ctx, enrichmentPrompt, enrichmentValues)
+	})
 	if err != nil {
 		log.Error("Ontology enrichment failed: %v", err)
 		return "", fmt.Errorf("ontology enrichment failed: %w", err)
@@ -49,66 +51,305 @@ func (p *Pipeline) processSegment(segment []byte, context string, previousResult
 
 	log.Info("Enriched result length: %d, preview: %s", len(normalizedResult), truncateString(normalizedResult, 100))
 
-	p.enrichOntologyWithPositions(normalizedResult, includePositions, string(segment), offset)
+	canonicalResult := p.enrichOntologyWithPositions(normalizedResult, includePositions, string(segment), offset)
 
-	return normalizedResult, nil
+	return canonicalResult, nil
 }
 
-// enrichOntologyWithPositions enrichit l'ontologie avec les positions des éléments
-func (p *Pipeline) enrichOntologyWithPositions(enrichedResult string, includePositions bool, content string, offset int) {
+// enrichmentRequest assemble le prompt d'enrichissement d'un segment et les valeurs qui le remplissent
+func (p *Pipeline) enrichmentRequest(segment []byte, context string, previousResult string) (*prompt.PromptTemplate, map[string]string, error) {
+	enrichmentValues := map[string]string{
+		"text":            string(segment),
+		"context":         context,
+		"previous_result": previousResult,
+	}
+
+	var enrichmentPrompt *prompt.PromptTemplate
+	if p.enrichmentPromptFile != "" {
+		template := p.enrichmentTemplate
+		if template == nil {
+			var err error
+			template, err = p.readEnrichmentTemplate()
+			if err != nil {
+				log.Error("Failed to read custom prompt file: %v", err)
+				return nil, nil, fmt.Errorf("failed to read custom prompt file: %w", err)
+			}
+		}
+		enrichmentPrompt = template.PromptTemplate()
+		log.Debug("Using custom enrichment prompt from file: %s", p.enrichmentPromptFile)
+	} else {
+		enrichmentPrompt = prompt.OntologyEnrichmentPrompt
+		log.Debug("Using default enrichment prompt")
+	}
+
+	return p.applySeedVocabulary(enrichmentPrompt, enrichmentValues), enrichmentValues, nil
+}
+
+// enrichOntologyWithPositions enrichit l'ontologie avec les entités, les relations et les positions des éléments.
+// Elle retourne le résultat sous forme canonique, les relations étant écrites au format Source\tType:Poids\tCible\tDescription.
+func (p *Pipeline) enrichOntologyWithPositions(enrichedResult string, includePositions bool, content string, offset int) string {
 	log.Debug("Starting enrichOntologyWithPositions with offset %d", offset)
 	log.Debug("Include positions: %v", includePositions)
 
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
 	lines := strings.Split(enrichedResult, "\n")
 	log.Debug("Number of lines to process: %d", len(lines))
 
+	var canonicalLines []string
+	var deferredLines, aliasLines [][]string
+	var touched []*model.OntologyElement
+	rejected := make(map[string]bool) // entités dont le type est rejeté par le schéma
+
+	// Première passe : les entités, afin que les alias et les relations puissent être résolus ensuite
 	for i, line := range lines {
 		log.Debug("Processing line %d: %s", i, line)
-		parts := strings.Fields(line)
-		if len(parts) >= 3 {
-			name := parts[0]
-			elementType := parts[1]
-			description := strings.Join(parts[2:], " ")
+		parts := splitTSVLine(line)
+		if len(parts) < 3 {
+			log.Warning("Skipping invalid line: %s", line)
+			continue
+		}
+		if isAliasLine(parts) {
+			aliasLines = append(aliasLines, parts)
+			continue
+		}
+		if len(parts) >= 4 || strings.Contains(parts[1], ":") {
+			deferredLines = append(deferredLines, parts)
+			continue
+		}
 
-			element := p.ontology.GetElementByName(name)
-			if element == nil {
-				element = model.NewOntologyElement(name, elementType)
-				p.ontology.AddElement(element)
-				log.Debug("Added new element: %v", element)
-			} else {
-				log.Debug("Updated existing element: %v", element)
-			}
-			element.Description = description
-
-			if includePositions {
-				log.Debug("Searching for positions of entity: %s", name)
-				allPositions := p.findPositions(name, string(p.fullContent))
-				log.Debug("Found %d positions for entity %s: %v", len(allPositions), name, allPositions)
-				if len(allPositions) > 0 {
-					// Garder toutes les positions trouvées
-					element.SetPositions(uniqueIntSlice(allPositions))
-					log.Debug("Set %d positions for element %s: %v", len(allPositions), name, allPositions)
-				} else {
-					log.Debug("No positions found for element %s", name)
-				}
+		elementType, ok := p.enforceEntityType(parts[0], parts[1])
+		if !ok {
+			rejected[normalizeElementKey(parts[0])] = true
+			continue
+		}
+		element := p.upsertOntologyElement(parts[0], elementType, parts[2], includePositions)
+		canonicalLines = append(canonicalLines, formatEntityLine(element))
+		touched = append(touched, element)
+	}
+
+	// Les alias rattachent d'autres noms aux entités connues, avant la résolution des relations
+	for _, parts := range aliasLines {
+		if element := p.applyAliasLine(parts); element != nil {
+			touched = append(touched, element)
+		}
+	}
+
+	// Seconde passe : les lignes à quatre colonnes sont des relations si la source et la cible sont connues
+	for _, parts := range deferredLines {
+		relation := p.parseRelationLine(parts)
+		if relation == nil {
+			elementType, ok := p.enforceEntityType(parts[0], parts[1])
+			if !ok {
+				rejected[normalizeElementKey(parts[0])] = true
+				continue
 			}
+			description := strings.Join(parts[2:], " ")
+			element := p.upsertOntologyElement(parts[0], elementType, description, includePositions)
+			canonicalLines = append(canonicalLines, formatEntityLine(element))
+			touched = append(touched, element)
+			continue
+		}
+		if p.dropRejectedRelation(parts[0], relation.Type, parts[2], rejected) {
+			continue
+		}
 
-			// Traitement des relations (si nécessaire)
-			if len(parts) >= 4 {
-				// Code pour traiter les relations...
+		// Le nom employé par la relation devient un alias de l'élément auquel il a été résolu
+		for _, endpoint := range [][2]string{{parts[0], relation.Source}, {parts[2], relation.Target}} {
+			if element := p.ontology.GetElementByName(endpoint[1]); element != nil {
+				p.addSurfaceForm(element, endpoint[0])
+				touched = append(touched, element)
 			}
-		} else {
-			log.Warning("Skipping invalid line: %s", line)
+		}
+		p.checkRelationSchema(relation)
+		p.upsertOntologyRelation(relation)
+		canonicalLines = append(canonicalLines, formatRelationLine(relation))
+	}
+
+	seen := make(map[*model.OntologyElement]bool)
+	for _, element := range touched {
+		if !seen[element] {
+			seen[element] = true
+			canonicalLines = append(canonicalLines, formatAliasLines(element)...)
 		}
 	}
 
 	log.Debug("Ontology after enrichment:")
 	for _, element := range p.ontology.Elements {
-		log.Debug("Element: %s, Type: %s, Description: %s, Positions: %v",
-			element.Name, element.Type, element.Description, element.Positions)
+		log.Debug("Element: %s, Type: %s, Description: %s, Mentions: %v",
+			element.Name, element.Type, element.Description, element.Mentions)
 	}
 	log.Debug("Final ontology state - Elements: %d, Relations: %d",
 		len(p.ontology.Elements), len(p.ontology.Relations))
+
+	return strings.Join(canonicalLines, "\n")
+}
+
+// parseRelationLine interprète une ligne Source\tType[:Poids]\tCible\tDescription[\tDirection].
+// Elle retourne nil si la ligne ne décrit pas une relation entre éléments connus.
+func (p *Pipeline) parseRelationLine(parts []string) *model.Relation {
+	relationType, weight, hasWeight := parseRelationType(parts[1])
+
+	source := p.resolveElement(parts[0])
+	target := p.resolveElement(parts[2])
+	if source == nil || target == nil {
+		if !hasWeight {
+			log.Debug("Line is not a relation between known elements: %v", parts)
+			return nil
+		}
+		// La syntaxe Type:Poids est explicite, on conserve la relation avec les noms bruts
+		log.Warning("Relation %s references unknown elements (%s, %s)", relationType, parts[0], parts[2])
+	}
+
+	sourceName, targetName := parts[0], parts[2]
+	if source != nil {
+		sourceName = source.Name
+	}
+	if target != nil {
+		targetName = target.Name
+	}
+
+	direction := model.DirectionForward
+	description := ""
+	if len(parts) >= 4 {
+		descriptionParts := parts[3:]
+		if last := strings.ToLower(descriptionParts[len(descriptionParts)-1]); len(descriptionParts) > 1 && isRelationDirection(last) {
+			direction = last
+			descriptionParts = descriptionParts[:len(descriptionParts)-1]
+		}
+		description = strings.Join(descriptionParts, " ")
+	}
+
+	now := time.Now()
+	return &model.Relation{
+		Source:      sourceName,
+		Type:        relationType,
+		Target:      targetName,
+		Description: description,
+		Weight:      weight,
+		Direction:   sql.NullString{String: direction, Valid: true},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// upsertOntologyRelation ajoute ou met à jour une relation dans l'ontologie et dans la base de données
+func (p *Pipeline) upsertOntologyRelation(relation *model.Relation) {
+	relation.Type = p.canonicalPropertyName(relation.Type)
+	existing := p.ontology.GetRelation(relation.Source, relation.Type, relation.Target)
+	if existing == nil {
+		p.ontology.AddRelation(relation)
+		log.Debug("Added new relation: %s -%s-> %s", relation.Source, relation.Type, relation.Target)
+	} else {
+		existing.Description = relation.Description
+		existing.Weight = relation.Weight
+		existing.Direction = relation.Direction
+		existing.UpdatedAt = relation.UpdatedAt
+		log.Debug("Updated existing relation: %s -%s-> %s", relation.Source, relation.Type, relation.Target)
+	}
+
+	if p.db != nil {
+		if err := UpsertRelation(p.db, relation); err != nil {
+			log.Warning("Failed to upsert relation %s -%s-> %s: %v", relation.Source, relation.Type, relation.Target, err)
+		}
+	}
+}
+
+// resolveElement recherche un élément par son nom exact, puis par son nom normalisé ou l'un de ses alias,
+// enfin par la racine de son nom afin de rapprocher le singulier et le pluriel
+func (p *Pipeline) resolveElement(name string) *model.OntologyElement {
+	if element := p.ontology.GetElementByName(name); element != nil {
+		return element
+	}
+	key := normalizeElementKey(name)
+	for _, element := range p.ontology.Elements {
+		for _, known := range element.Names() {
+			if normalizeElementKey(known) == key {
+				return element
+			}
+		}
+	}
+	analyzer := language.Lookup(p.contentLanguage)
+	if stem := normalizeAndStem(name, analyzer); stem != "" {
+		for _, element := range p.ontology.Elements {
+			if normalizeAndStem(element.Name, analyzer) == stem {
+				return element
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeElementKey produit une clé de comparaison insensible à la casse, aux accents et aux séparateurs
+func normalizeElementKey(name string) string {
+	name = strings.ReplaceAll(name, "_", " ")
+	return strings.ToLower(removeAccents(strings.Join(strings.Fields(name), "_")))
+}
+
+// parseRelationType sépare le type de relation de son poids optionnel (Type:Poids)
+func parseRelationType(field string) (string, int, bool) {
+	idx := strings.LastIndex(field, ":")
+	if idx == -1 {
+		return strings.TrimSpace(field), 1, false
+	}
+	weightStr := strings.TrimSpace(field[idx+1:])
+	weightStr = strings.TrimPrefix(weightStr, "%!f(int=")
+	weightStr = strings.TrimSuffix(weightStr, ")")
+	weight, err := strconv.Atoi(weightStr)
+	if err != nil {
+		return strings.TrimSpace(field), 1, false
+	}
+	return strings.TrimSpace(field[:idx]), weight, true
+}
+
+func isRelationDirection(value string) bool {
+	switch value {
+	case model.DirectionForward, model.DirectionBackward, model.DirectionBidirectional:
+		return true
+	}
+	return false
+}
+
+// formatEntityLine écrit une entité au format TSV canonique
+func formatEntityLine(element *model.OntologyElement) string {
+	return fmt.Sprintf("%s\t%s\t%s", element.Name, element.Type, element.Description)
+}
+
+// formatRelationLine écrit une relation au format TSV canonique compris par insertResults
+func formatRelationLine(relation *model.Relation) string {
+	return fmt.Sprintf("%s\t%s:%d\t%s\t%s", relation.Source, relation.Type, relation.Weight, relation.Target, relation.Description)
+}
+
+// upsertOntologyElement crée ou met à jour un élément de l'ontologie et recherche ses mentions dans les fichiers sources
+func (p *Pipeline) upsertOntologyElement(name, elementType, description string, includePositions bool) *model.OntologyElement {
+	elementType = p.canonicalClassName(elementType)
+	element := p.ontology.GetElementByName(name)
+	if element == nil {
+		element = p.ontology.GetElementByAlias(name)
+	}
+	if element == nil {
+		element = model.NewOntologyElement(name, elementType)
+		p.ontology.AddElement(element)
+		log.Debug("Added new element: %v", element)
+	} else {
+		log.Debug("Updated existing element: %v", element)
+	}
+	element.Description = description
+	p.linkAcronym(element)
+
+	if includePositions {
+		log.Debug("Searching for mentions of entity: %s", name)
+		mentions := p.findMentions(element.Name, element.Aliases...)
+		if len(mentions) > 0 {
+			// Garder toutes les mentions trouvées
+			element.Mentions = mentions
+			log.Debug("Set %d mentions for element %s: %v", len(mentions), name, mentions)
+		} else {
+			log.Debug("No mentions found for element %s", name)
+		}
+	}
+	return element
 }
 
 // uniquePositions supprime les doublons dans une slice d'entiers
@@ -124,13 +365,20 @@ func uniquePositions(positions []int) []int {
 	return list
 }
 
-// normalizeTSV normalise une chaîne TSV
+// normalizeTSV normalise une chaîne TSV.
+// Les lignes tabulées conservent leurs colonnes ; les lignes séparées par des espaces sont ramenées à Nom\tType\tDescription.
 func normalizeTSV(input string) string {
 	lines := strings.Split(input, "\n")
 	var normalizedLines []string
 	for _, line := range lines {
-		line = strings.ReplaceAll(line, "\\t", " ")
-		line = strings.ReplaceAll(line, "\t", " ")
+		line = strings.ReplaceAll(line, "\\t", "\t")
+		if strings.Contains(line, "\t") {
+			fields := splitTSVLine(line)
+			if len(fields) >= 3 {
+				normalizedLines = append(normalizedLines, strings.Join(fields, "\t"))
+			}
+			continue
+		}
 		fields := strings.Fields(line)
 		if len(fields) >= 3 {
 			normalizedLine := strings.Join(fields[:2], "\t") + "\t" + strings.Join(fields[2:], " ")
@@ -139,3 +387,15 @@ func normalizeTSV(input string) string {
 	}
 	return strings.Join(normalizedLines, "\n")
 }
+
+// splitTSVLine découpe une ligne sur les tabulations en ignorant les colonnes vides
+func splitTSVLine(line string) []string {
+	var fields []string
+	for _, field := range strings.Split(line, "\t") {
+		field = strings.Join(strings.Fields(field), " ")
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
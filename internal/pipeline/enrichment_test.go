@@ -0,0 +1,49 @@
+// pipeline/enrichment_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTSVKeepsRelationColumns(t *testing.T) {
+	input := "RSSI\\tRole\\tResponsable de la sécurité\nRSSI\tpilote\tPSSI\tLe RSSI pilote la PSSI\nligne invalide"
+
+	assert.Equal(t,
+		"RSSI\tRole\tResponsable de la sécurité\nRSSI\tpilote\tPSSI\tLe RSSI pilote la PSSI",
+		normalizeTSV(input))
+}
+
+func TestEnrichOntologyWithPositionsExtractsRelations(t *testing.T) {
+	p := newTestPipeline()
+	input := "RSSI\tpilote\tPolitique_de_sécurité\tLe RSSI pilote la PSSI\n" +
+		"RSSI\tRole\tResponsable de la sécurité\n" +
+		"Politique_de_Securite\tDocument\tPolitique de sécurité du SI\n" +
+		"Direction\tvalide:4\tPolitique_de_sécurité\tValidation annuelle\tbidirectional\n" +
+		"Annexe\tDocument\tContient\tplusieurs colonnes"
+
+	result := p.enrichOntologyWithPositions(input, false, "", 0)
+
+	assert.Len(t, p.ontology.Elements, 3)
+	assert.Equal(t, "Contient plusieurs colonnes", p.ontology.GetElementByName("Annexe").Description)
+
+	assert.Len(t, p.ontology.Relations, 2)
+	pilote := p.ontology.GetRelation("RSSI", "pilote", "Politique_de_Securite")
+	if assert.NotNil(t, pilote) {
+		assert.Equal(t, 1, pilote.Weight)
+		assert.Equal(t, "forward", pilote.Direction.String)
+	}
+	valide := p.ontology.GetRelation("Direction", "valide", "Politique_de_Securite")
+	if assert.NotNil(t, valide) {
+		assert.Equal(t, 4, valide.Weight)
+		assert.Equal(t, "bidirectional", valide.Direction.String)
+		assert.Equal(t, "Validation annuelle", valide.Description)
+	}
+	assert.Contains(t, result, "RSSI\tpilote:1\tPolitique_de_Securite\tLe RSSI pilote la PSSI")
+
+	relations, err := GetAllRelations(p.db)
+	assert.NoError(t, err)
+	assert.Len(t, relations, 2)
+}
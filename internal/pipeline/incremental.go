@@ -0,0 +1,74 @@
+// incremental.go
+
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/converter"
+	"github.com/chrlesur/Ontology/internal/metadata"
+)
+
+// planIncrementalRun compare les fichiers sources avec le _meta.json de l'exécution précédente.
+// Seuls les fichiers nouveaux ou modifiés seront segmentés et envoyés au LLM ; les entités et relations
+// issues uniquement de fichiers supprimés sont retirées de la base de projet.
+// Elle retourne l'ontologie précédente au format TSV canonique lorsqu'elle est reprise du fichier de sortie.
+func (p *Pipeline) planIncrementalRun(output string) (string, error) {
+	metadataGen := metadata.NewGenerator(p.storage)
+	metaFilePath := strings.TrimSuffix(output, filepath.Ext(output)) + "_meta.json"
+
+	previous, err := metadataGen.LoadMetadata(metaFilePath)
+	if err != nil {
+		p.logger.Info("No previous metadata found at %s, processing all files: %v", metaFilePath, err)
+		return "", nil
+	}
+
+	current, err := metadataGen.GenerateMetadata(p.sourceFiles, filepath.Base(output), "")
+	if err != nil {
+		return "", fmt.Errorf("failed to generate metadata: %w", err)
+	}
+
+	changes := metadata.CompareMetadata(previous, current)
+	p.logger.Info("Incremental run: %d added, %d modified, %d unchanged, %d deleted files",
+		len(changes.Added), len(changes.Modified), len(changes.Unchanged), len(changes.Deleted))
+
+	p.incrementalFiles = make(map[string]bool)
+	for _, path := range changes.Changed() {
+		p.incrementalFiles[filepath.Clean(path)] = true
+	}
+	p.sourceFiles = changes.Changed()
+
+	if len(changes.Deleted) > 0 {
+		entities, relations, err := RetractSourceFiles(p.db, changes.Deleted)
+		if err != nil {
+			return "", fmt.Errorf("failed to retract deleted files: %w", err)
+		}
+		p.logger.Info("Retracted %d entities and %d relations coming only from deleted files", entities, relations)
+	}
+
+	if p.config.Database != "" {
+		return "", nil
+	}
+
+	// Sans base de projet persistante, l'ontologie précédente est reprise du fichier de sortie
+	if len(changes.Deleted) > 0 {
+		p.logger.Warning("Retracting deleted files requires a project database (--db); their entities are kept")
+	}
+	content, err := p.storage.Read(output)
+	if err != nil {
+		p.logger.Warning("Previous output %s cannot be read, only changed files will be in the ontology: %v", output, err)
+		return "", nil
+	}
+	previousOntology, _, err := converter.ImportOntology(content, converter.DetectImportFormat(output, content))
+	if err != nil {
+		return "", fmt.Errorf("failed to import previous output: %w", err)
+	}
+	return p.seedOntology(previousOntology, nil)
+}
+
+// isIncrementalSkip indique si un fichier inchangé doit être ignoré lors d'une exécution incrémentale
+func (p *Pipeline) isIncrementalSkip(path string) bool {
+	return p.incrementalFiles != nil && !p.incrementalFiles[filepath.Clean(path)]
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/chrlesur/Ontology/internal/converter"
 	"github.com/chrlesur/Ontology/internal/i18n"
 	"github.com/chrlesur/Ontology/internal/parser"
 	"github.com/chrlesur/Ontology/internal/storage"
@@ -91,7 +92,8 @@ func (p *Pipeline) parseDirectory(dir string) ([]byte, error) {
 	return content, nil
 }
 
-// loadExistingOntology charge une ontologie existante à partir d'un fichier
+// loadExistingOntology charge une ontologie existante (TSV, Turtle, RDF/XML, OWL ou JSON-LD) à partir d'un fichier.
+// Ses éléments et relations sont chargés en base et ses classes et propriétés contraignent l'enrichissement.
 func (p *Pipeline) loadExistingOntology(path string) (string, error) {
 	p.logger.Debug("Loading existing ontology from: %s", path)
 
@@ -100,7 +102,20 @@ func (p *Pipeline) loadExistingOntology(path string) (string, error) {
 		p.logger.Error("Error reading existing ontology: %v", err)
 		return "", fmt.Errorf("%s: %w", i18n.GetMessage("ErrReadExistingOntology"), err)
 	}
+	p.logger.Debug("Successfully read existing ontology, content length: %d bytes", len(content))
 
-	p.logger.Debug("Successfully loaded existing ontology, content length: %d bytes", len(content))
-	return string(content), nil
+	format := converter.DetectImportFormat(path, content)
+	p.logger.Info("Importing existing ontology %s as %s", path, format)
+	seed, vocabulary, err := converter.ImportOntology(content, format)
+	if err != nil {
+		p.logger.Error("Error importing existing ontology: %v", err)
+		return "", fmt.Errorf("failed to import existing ontology: %w", err)
+	}
+
+	result, err := p.seedOntology(seed, vocabulary)
+	if err != nil {
+		return "", err
+	}
+	p.recordSeedProvenance(seed, path)
+	return result, nil
 }
@@ -0,0 +1,260 @@
+// merge.go
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Stratégies de fusion des résultats des segments
+const (
+	MergeStrategyDB  = "db"  // fusion déterministe par nom dans la base SQLite
+	MergeStrategyLLM = "llm" // fusion hiérarchique par le LLM, la base reprenant les lots en échec
+)
+
+// defaultMergeMaxTokens est le budget d'un appel de fusion lorsque la configuration n'en fixe pas
+const defaultMergeMaxTokens = 8000
+
+// MergeStrategies retourne les stratégies de fusion reconnues
+func MergeStrategies() []string {
+	return []string{MergeStrategyDB, MergeStrategyLLM}
+}
+
+// mergeSegmentResults fusionne les résultats des segments selon la stratégie configurée.
+// Avec la stratégie llm, le résultat de la fusion hiérarchique et les lots qu'elle n'a pu fusionner
+// sont insérés dans la base, qui les fusionne par nom comme les résultats bruts de la stratégie db.
+func (p *Pipeline) mergeSegmentResults(previousResult string, results []string) (string, error) {
+	if p.config.MergeStrategy != MergeStrategyLLM {
+		return p.mergeResultsWithDB(previousResult, results)
+	}
+
+	merged, fallback, err := p.mergeResults(previousResult, results)
+	if err != nil {
+		log.Error("Hierarchical merge failed: %v", err)
+		return "", err
+	}
+	if err := p.budgetExceeded(); err != nil {
+		return "", err
+	}
+	if len(fallback) > 0 {
+		log.Warning("%d results could not be merged by the LLM and are merged in the database", len(fallback))
+	}
+	for _, result := range append([]string{merged}, fallback...) {
+		if err := p.insertResults(p.db, result); err != nil {
+			log.Error("Failed to insert merged result: %v", err)
+			return "", err
+		}
+	}
+	for i, result := range results {
+		if err := p.recordResultProvenance(result, i); err != nil {
+			log.Warning("Failed to record provenance of result %d: %v", i, err)
+		}
+	}
+	return p.resolveMergedResults()
+}
+
+// mergeResults fusionne le résultat précédent et ceux des segments par le LLM, sans dépasser le budget
+// de tokens d'un appel. Les résultats consécutifs sont regroupés en lots sous le budget et chaque lot est
+// fusionné (map), puis les résultats intermédiaires sont fusionnés deux à deux jusqu'à n'en garder
+// qu'un (reduce). Un lot qui dépasse le budget ou dont la fusion échoue est retourné dans fallback.
+func (p *Pipeline) mergeResults(previousResult string, newResults []string) (string, []string, error) {
+	countTokens, err := p.tokenCounterFunc()
+	if err != nil {
+		return "", nil, err
+	}
+	budget := p.config.MergeMaxTokens
+	if budget <= 0 {
+		budget = defaultMergeMaxTokens
+	}
+
+	var results []string
+	var tokens []int
+	for _, result := range append([]string{previousResult}, newResults...) {
+		if result = strings.TrimSpace(result); result != "" {
+			results = append(results, result)
+			tokens = append(tokens, countTokens(result))
+		}
+	}
+	var groups [][]string
+	for _, batch := range groupMergeBatches(tokens, budget) {
+		groups = append(groups, results[batch[0]:batch[1]])
+	}
+	log.Info("Starting hierarchical merge of %d results in %d batches, budget %d tokens", len(newResults), len(groups), budget)
+
+	var fallback []string
+	for level := 1; ; level++ {
+		nodes, failed := p.mergeLevel(groups, budget, countTokens)
+		fallback = append(fallback, failed...)
+		log.Debug("Merge level %d: %d batches merged into %d results, %d left to the database", level, len(groups), len(nodes), len(failed))
+		switch len(nodes) {
+		case 0:
+			return "", fallback, nil
+		case 1:
+			return nodes[0], fallback, nil
+		}
+
+		groups = nil
+		for i := 0; i < len(nodes); i += 2 {
+			groups = append(groups, nodes[i:min(i+2, len(nodes))])
+		}
+	}
+}
+
+// groupMergeBatches regroupe des résultats consécutifs, d'après leur nombre de tokens, en lots sous le budget.
+// Chaque lot est retourné sous la forme [début, fin) des indices de ses résultats.
+func groupMergeBatches(tokens []int, budget int) [][2]int {
+	var batches [][2]int
+	start, batchTokens := 0, 0
+	for i, count := range tokens {
+		if i > start && batchTokens+count > budget {
+			batches = append(batches, [2]int{start, i})
+			start, batchTokens = i, 0
+		}
+		batchTokens += count
+	}
+	if start < len(tokens) {
+		batches = append(batches, [2]int{start, len(tokens)})
+	}
+	return batches
+}
+
+// projectMergeCalls compte les appels au LLM d'une fusion hiérarchique dont le premier niveau est formé
+// des lots indiqués, en supposant que chaque fusion réussit
+func projectMergeCalls(batches [][2]int) int {
+	calls := 0
+	for _, batch := range batches {
+		if batch[1]-batch[0] > 1 {
+			calls++
+		}
+	}
+	for nodes := len(batches); nodes > 1; nodes = (nodes + 1) / 2 {
+		calls += nodes / 2
+	}
+	return calls
+}
+
+// tokenCounterFunc retourne le comptage des tokens des prompts, par tiktoken sauf s'il est remplacé
+func (p *Pipeline) tokenCounterFunc() (func(string) int, error) {
+	if p.tokenCounter != nil {
+		return p.tokenCounter, nil
+	}
+	tke, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tokenizer: %w", err)
+	}
+	return func(text string) int {
+		return len(tke.Encode(text, nil, nil))
+	}, nil
+}
+
+// mergeLevel fusionne les lots d'un niveau de l'arbre en parallèle, dans la limite de maxConcurrentThreads.
+// Elle retourne les résultats fusionnés et les résultats des lots en échec.
+func (p *Pipeline) mergeLevel(groups [][]string, budget int, countTokens func(string) int) ([]string, []string) {
+	nodes := make([]string, len(groups))
+	failed := make([][]string, len(groups))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(p.maxConcurrentThreads, 1))
+
+	for i, group := range groups {
+		if len(group) == 1 {
+			nodes[i] = group[0]
+			continue
+		}
+		wg.Add(1)
+		go func(i int, group []string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tokens := 0
+			for _, result := range group {
+				tokens += countTokens(result)
+			}
+			if tokens > budget {
+				log.Warning("Merge batch %d exceeds the budget (%d > %d tokens), merged in the database", i+1, tokens, budget)
+				failed[i] = group
+				return
+			}
+			merged, err := p.mergeBatch(group)
+			if err != nil {
+				log.Warning("Merge batch %d failed, merged in the database: %v", i+1, err)
+				failed[i] = group
+				return
+			}
+			nodes[i] = merged
+		}(i, group)
+	}
+	wg.Wait()
+
+	var merged, fallback []string
+	for i := range groups {
+		if nodes[i] != "" {
+			merged = append(merged, nodes[i])
+		}
+		fallback = append(fallback, failed[i]...)
+	}
+	return merged, fallback
+}
+
+// mergeBatch fusionne un lot de résultats par un appel au LLM avec OntologyMergePrompt
+func (p *Pipeline) mergeBatch(batch []string) (string, error) {
+	mergeValues := map[string]string{
+		"previous_ontology": batch[0],
+		"new_ontology":      strings.Join(batch[1:], "\n"),
+		"additional_prompt": p.ontologyMergePrompt,
+	}
+
+	log.Debug("Calling LLM with OntologyMergePrompt for %d results", len(batch))
+	mergedResult, err := p.callLLM(mergeOffset, prompt.OntologyMergePrompt, mergeValues, func(ctx context.Context) (string, llm.Usage, error) {
+		return p.llm.ProcessWithPrompt(ctx, prompt.OntologyMergePrompt, mergeValues)
+	})
+	if err != nil {
+		return "", fmt.Errorf("ontology merge failed: %w", err)
+	}
+
+	canonicalResult := canonicalMergeResult(normalizeTSV(mergedResult))
+	if canonicalResult == "" {
+		return "", fmt.Errorf("ontology merge returned no entity or relation")
+	}
+	return canonicalResult, nil
+}
+
+// canonicalMergeResult ramène la réponse de fusion au format TSV canonique compris par insertResults :
+// les entités sur trois colonnes, sans leurs positions, et les relations avec leur poids
+func canonicalMergeResult(result string) string {
+	var rows [][]string
+	entities := make(map[string]bool)
+	for _, line := range strings.Split(result, "\n") {
+		parts := splitTSVLine(line)
+		if len(parts) < 3 {
+			continue
+		}
+		rows = append(rows, parts)
+		if !isAliasLine(parts) && !strings.Contains(parts[1], ":") {
+			entities[parts[0]] = true
+		}
+	}
+
+	var lines []string
+	for _, parts := range rows {
+		switch {
+		case isAliasLine(parts), strings.Contains(parts[1], ":"):
+			lines = append(lines, strings.Join(parts, "\t"))
+		case len(parts) >= 4 && entities[parts[2]]:
+			// Relation dont le poids a été omis
+			lines = append(lines, fmt.Sprintf("%s\t%s:1\t%s\t%s", parts[0], parts[1], parts[2], strings.Join(parts[3:], " ")))
+		default:
+			lines = append(lines, strings.Join(parts[:3], "\t"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
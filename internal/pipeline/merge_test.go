@@ -0,0 +1,99 @@
+// pipeline/merge_test.go
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingLLM échoue à chaque appel
+type failingLLM struct {
+	calls int
+}
+
+func (f *failingLLM) Translate(ctx context.Context, prompt string, systemContext string) (string, llm.Usage, error) {
+	f.calls++
+	return "", llm.Usage{}, errors.New("context window exceeded")
+}
+
+func (f *failingLLM) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, llm.Usage, error) {
+	return f.Translate(ctx, "", "")
+}
+
+// mergeLLM répond à chaque fusion selon la première entité de l'ontologie existante
+type mergeLLM struct {
+	mu        sync.Mutex
+	responses map[string]string
+	merged    []string // ontologies fusionnées, au format existante + nouvelle
+}
+
+func (m *mergeLLM) Translate(ctx context.Context, prompt string, systemContext string) (string, llm.Usage, error) {
+	return "", llm.Usage{}, errors.New("unexpected call")
+}
+
+func (m *mergeLLM) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, llm.Usage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.merged = append(m.merged, values["previous_ontology"]+"\n"+values["new_ontology"])
+	return m.responses[strings.SplitN(values["previous_ontology"], "\t", 2)[0]], llm.Usage{Requests: 1}, nil
+}
+
+func TestMergeResultsBuildsTreeUnderBudget(t *testing.T) {
+	results := []string{
+		"PSSI\tDocument\tPolitique de sécurité du système",
+		"RSSI\tRole\tResponsable de la sécurité du système",
+		"DSI\tRole\tDirection des systèmes d'information",
+		"SMSI\tConcept\tSystème de management de la sécurité",
+	}
+	p := newTestPipeline()
+	p.maxConcurrentThreads = 2
+	p.tokenCounter = func(text string) int { return len(strings.Fields(text)) }
+	p.config.MergeMaxTokens = 16
+	client := &mergeLLM{responses: map[string]string{
+		"PSSI": "PSSI\tDocument\tPolitique\nRSSI\tRole\tResponsable\nRSSI\trédige\tPSSI\tRédaction",
+		"DSI":  "DSI\tRole\tDirection\nSMSI\tConcept\tSystème",
+	}}
+	p.llm = client
+
+	merged, fallback, err := p.mergeResults("", results)
+
+	// Deux lots sous le budget sont fusionnés, puis leurs résultats entre eux
+	assert.NoError(t, err)
+	assert.Empty(t, fallback)
+	assert.Len(t, client.merged, 3)
+	assert.Contains(t, client.merged, results[0]+"\n"+results[1])
+	assert.Contains(t, client.merged, results[2]+"\n"+results[3])
+	assert.Equal(t, "PSSI\tDocument\tPolitique\nRSSI\tRole\tResponsable\nRSSI\trédige:1\tPSSI\tRédaction", merged)
+}
+
+func TestMergeSegmentResultsFallsBackToDatabase(t *testing.T) {
+	p := newTestPipeline()
+	p.config.MergeStrategy = MergeStrategyLLM
+	p.tokenCounter = func(text string) int { return len(strings.Fields(text)) }
+	client := &failingLLM{}
+	p.llm = client
+
+	merged, err := p.mergeSegmentResults("", []string{
+		"PSSI\tDocument\tPolitique de sécurité",
+		"RSSI\tRole\tResponsable\nRSSI\trédige:3\tPSSI\tRédaction",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, client.calls)
+	assert.Contains(t, merged, "PSSI\tDocument\tPolitique de sécurité")
+	assert.Contains(t, merged, "RSSI\tRole\tResponsable")
+	assert.Contains(t, merged, "RSSI\trédige:3\tPSSI\tRédaction")
+}
+
+func TestCanonicalMergeResult(t *testing.T) {
+	result := canonicalMergeResult("A\tConcept\tPremier\tdoc1:0-1\nB\tConcept\tSecond\nA\tinclut\tB\tInclusion\nAbr\talias\tA\nligne invalide")
+	assert.Equal(t, "A\tConcept\tPremier\nB\tConcept\tSecond\nA\tinclut:1\tB\tInclusion\nAbr\talias\tA", result)
+}
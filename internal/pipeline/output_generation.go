@@ -3,31 +3,49 @@
 package pipeline
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"strings"
 
+	"github.com/chrlesur/Ontology/internal/converter"
 	"github.com/chrlesur/Ontology/internal/i18n"
 	"github.com/chrlesur/Ontology/internal/metadata"
 )
 
 // saveResult sauvegarde les résultats de l'ontologie et génère les fichiers de sortie
-func (p *Pipeline) saveResult(result string, outputPath string, newContent []byte, fileMetadata map[string]metadata.FileMetadata) error {
+func (p *Pipeline) saveResult(result string, outputPath string, newContent []byte) error {
 	p.logger.Debug("Starting saveResult")
 	p.logger.Info("Number of elements in ontology: %d", len(p.ontology.Elements))
 	p.logger.Info("Number of relations in ontology: %d", len(p.ontology.Relations))
-	p.logger.Debug("Writing TSV to: %s", outputPath)
+	p.logger.Debug("Writing %s ontology to: %s", p.config.OutputFormat, outputPath)
 
-	// Générer le contenu TSV
-	tsvContent := p.generateTSVContent()
+	// Rattacher à chaque élément les fichiers, segments et passes qui l'ont produit
+	provenance, err := p.attachProvenance()
+	if err != nil {
+		p.logger.Error("Failed to attach provenance: %v", err)
+		return fmt.Errorf("failed to attach provenance: %w", err)
+	}
+
+	// Sérialiser l'ontologie dans le format de sortie demandé
+	exporter, err := converter.GetExporter(p.config.OutputFormat, p.config.BaseURI)
+	if err != nil {
+		p.logger.Error("Failed to get exporter: %v", err)
+		return fmt.Errorf("failed to get exporter: %w", err)
+	}
+	ontologyContent, err := exporter.Export(p.ontology)
+	if err != nil {
+		p.logger.Error("Failed to export ontology: %v", err)
+		return fmt.Errorf("failed to export ontology: %w", err)
+	}
 
-	// Sauvegarder le fichier TSV
-	err := p.storage.Write(outputPath, []byte(tsvContent))
+	// Sauvegarder le fichier de l'ontologie
+	err = p.storage.Write(outputPath, ontologyContent)
 	if err != nil {
-		p.logger.Error("Failed to write TSV file: %v", err)
+		p.logger.Error("Failed to write ontology file: %v", err)
 		return fmt.Errorf("%s: %w", i18n.GetMessage("ErrWriteOutput"), err)
 	}
-	p.logger.Debug("TSV file written: %s", outputPath)
+	p.logger.Debug("Ontology file written: %s", outputPath)
 
 	contextFile := ""
 	if p.contextOutput {
@@ -37,6 +55,9 @@ func (p *Pipeline) saveResult(result string, outputPath string, newContent []byt
 			p.logger.Error("Failed to get all entities: %v", err)
 			return fmt.Errorf("failed to get all entities: %w", err)
 		}
+		for _, entity := range entities {
+			entity.Provenance = provenance[entity.Name]
+		}
 		p.ontology.Elements = entities
 		p.logger.Debug("Updated ontology with %d entities from database", len(entities))
 
@@ -46,12 +67,7 @@ func (p *Pipeline) saveResult(result string, outputPath string, newContent []byt
 		mergedPositions := mergeOverlappingPositions(positionRanges)
 		p.logger.Debug("Merged to %d position ranges", len(mergedPositions))
 
-		positions := make([]int, len(mergedPositions))
-		for i, pr := range mergedPositions {
-			positions[i] = pr.Start
-		}
-
-		contextJSON, err := GenerateContextJSON(newContent, positions, p.contextWords, mergedPositions, fileMetadata, p.storage)
+		contextJSON, err := GenerateContextJSON(newContent, p.contextWords, mergedPositions, p.locate, provenance)
 		if err != nil {
 			p.logger.Error("Failed to generate context JSON: %v", err)
 			return fmt.Errorf("failed to generate context JSON: %w", err)
@@ -67,6 +83,36 @@ func (p *Pipeline) saveResult(result string, outputPath string, newContent []byt
 		p.logger.Debug("Context output is disabled. Skipping context JSON generation.")
 	}
 
+	// Sauvegarder le journal des fusions de la résolution locale, pour relecture
+	if len(p.merges) > 0 {
+		mergesFile := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_merges.json"
+		mergesJSON, err := json.MarshalIndent(p.merges, "", "  ")
+		if err != nil {
+			p.logger.Error("Failed to marshal merge log: %v", err)
+			return fmt.Errorf("failed to marshal merge log: %w", err)
+		}
+		if err := p.storage.Write(mergesFile, mergesJSON); err != nil {
+			p.logger.Error("Failed to write merge log: %v", err)
+			return fmt.Errorf("failed to write merge log: %w", err)
+		}
+		p.logger.Info("Merge log saved to: %s", mergesFile)
+	}
+
+	// Sauvegarder le rapport de conformité au schéma du fichier de définition d'ontologie
+	if p.schema != nil {
+		reportFile := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_schema_report.json"
+		reportJSON, err := json.MarshalIndent(p.schemaReport(), "", "  ")
+		if err != nil {
+			p.logger.Error("Failed to marshal schema report: %v", err)
+			return fmt.Errorf("failed to marshal schema report: %w", err)
+		}
+		if err := p.storage.Write(reportFile, reportJSON); err != nil {
+			p.logger.Error("Failed to write schema report: %v", err)
+			return fmt.Errorf("failed to write schema report: %w", err)
+		}
+		p.logger.Info("Schema report saved to: %s (%d violations)", reportFile, len(p.schemaViolations))
+	}
+
 	// Générer et sauvegarder les métadonnées
 	metadataGen := metadata.NewGenerator(p.storage)
 	if metadataGen == nil {
@@ -80,6 +126,8 @@ func (p *Pipeline) saveResult(result string, outputPath string, newContent []byt
 		p.logger.Error("Failed to generate metadata: %v", err)
 		return fmt.Errorf("failed to generate metadata: %w", err)
 	}
+	meta.Usage = p.UsageReport()
+	meta.Coverage = p.CoverageReport()
 
 	metaFilePath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + "_meta.json"
 	err = metadataGen.SaveMetadata(meta, metaFilePath)
@@ -93,39 +141,6 @@ func (p *Pipeline) saveResult(result string, outputPath string, newContent []byt
 	return nil
 }
 
-// generateTSVContent génère le contenu TSV à partir de l'ontologie
-func (p *Pipeline) generateTSVContent() string {
-	var tsvBuilder strings.Builder
-
-	p.logger.Debug("Starting generateTSVContent")
-
-	// Écrire les éléments
-	for _, element := range p.ontology.Elements {
-		positions := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(element.Positions)), ","), "[]")
-		line := fmt.Sprintf("%s\t%s\t%s\t%s\n", element.Name, element.Type, element.Description, positions)
-		tsvBuilder.WriteString(line)
-		p.logger.Debug("Added element to TSV: Name=%s, Type=%s, Description=%s, Positions=%s",
-			element.Name, element.Type, element.Description, positions)
-	}
-
-	// Écrire les relations
-	for _, relation := range p.ontology.Relations {
-		line := fmt.Sprintf("%s\t%s:%d\t%s\t%s\n",
-			relation.Source,
-			relation.Type,
-			relation.Weight,
-			relation.Target,
-			relation.Description)
-		tsvBuilder.WriteString(line)
-		p.logger.Debug("Added relation to TSV: Source=%s, Type=%s, Weight=%d, Target=%s, Description=%s",
-			relation.Source, relation.Type, relation.Weight, relation.Target, relation.Description)
-	}
-
-	result := tsvBuilder.String()
-	p.logger.Debug("TSV content generation completed. Total lines: %d", strings.Count(result, "\n"))
-	return result
-}
-
 func (p *Pipeline) getSourcePaths() []string {
 	var sourcePaths []string
 
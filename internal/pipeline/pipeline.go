@@ -3,11 +3,14 @@
 package pipeline
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/chrlesur/Ontology/internal/config"
 	"github.com/chrlesur/Ontology/internal/i18n"
@@ -15,6 +18,7 @@ import (
 	"github.com/chrlesur/Ontology/internal/logger"
 	"github.com/chrlesur/Ontology/internal/metadata"
 	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/prompt"
 	"github.com/chrlesur/Ontology/internal/storage"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -50,11 +54,35 @@ type Pipeline struct {
 	storage                  storage.Storage
 	maxConcurrentThreads     int
 	enrichmentPromptFile     string
+	enrichmentTemplate       *prompt.Template // fichier de définition d'ontologie analysé au début de l'exécution
 	positionIndex            map[string][]int
 	fullContent              []byte // stocker le contenu complet du document.
 	segmentOffsets           []int  // stocker les offsets de début de chaque segment.
 	db                       *sql.DB
 	invertedIndex map[string][]int
+	wordSpans                []wordSpan // offsets en octets des mots indexés par invertedIndex
+	ontologyMu               sync.Mutex // protège l'ontologie enrichie en parallèle par les segments
+	vocabulary               *model.Vocabulary // classes et propriétés de l'ontologie existante importée
+	runID                    int64    // identifiant de l'exécution dans la table runs
+	currentPass              int      // passe en cours, enregistrée dans la provenance
+	sourceFiles              []string // fichiers sources de l'exécution, enregistrés dans la provenance
+	incrementalFiles         map[string]bool // fichiers nouveaux ou modifiés en mode incrémental, nil sinon
+	fileSpans                []fileSpan      // position de chaque fichier source dans le contenu de la passe
+	segmentSpans             []fileSpan      // position de chaque segment dans le contenu de la passe
+	contentLanguage          string          // langue du contenu situé hors des fichiers connus
+	acronyms                 map[string]string // sigles définis dans le contenu de la passe, associés à leur forme développée
+	merges                   []MergeRecord     // fusions d'entités de la résolution locale pendant l'exécution
+	schema                   *model.Schema     // types et relations imposés par le fichier de définition d'ontologie, nil sinon
+	schemaViolations         []SchemaViolation // écarts au schéma consignés segment après segment
+	tokenCounter             func(string) int  // comptage des tokens de la fusion hiérarchique et du budget, tiktoken si nil
+	llmProvider              string            // fournisseur et modèle du LLM, pour le prix des appels
+	llmModel                 string
+	routedModels             []llm.Link        // modèle sélectionné, puis modèles des règles de routage et de la chaîne de repli
+	usage                    usageTracker      // tokens et coût des appels au LLM de l'exécution
+	checkpoint               *runCheckpoint    // résultats des segments et des passes enregistrés pour la reprise, nil en dry-run
+	coverage                 coverageTracker   // segments traités et segments en échec de l'exécution
+	retrySegments            map[int]metadata.FailedSegment // avec retry_failed, segments en échec de l'exécution précédente, par index
+	ctx                      context.Context   // contexte de l'exécution en cours, annulé à l'interruption
 }
 
 // NewPipeline crée une nouvelle instance du pipeline de traitement
@@ -82,11 +110,31 @@ func NewPipeline(includePositions bool, contextOutput bool, contextWords int, en
 
 	log.Info("Selected LLM: %s, Model: %s", selectedLLM, selectedModel)
 
-	// Initialisation du client LLM
-	client, err := llm.GetClient(selectedLLM, selectedModel)
+	// Modèles vers lesquels les requêtes peuvent être routées : le modèle sélectionné, puis les règles de routage et la chaîne de repli
+	routedModels, err := llm.RoutedModels(cfg, llm.Link{Provider: selectedLLM, Model: selectedModel})
 	if err != nil {
-		log.Error("Failed to initialize LLM client: %v", err)
-		return nil, fmt.Errorf("%s: %w", i18n.GetMessage("ErrInitLLMClient"), err)
+		return nil, err
+	}
+	if len(routedModels) > 1 {
+		log.Info("Requests may be routed to: %v", routedModels)
+	}
+
+	// Le budget ne peut être respecté que si le prix de chaque modèle est connu
+	for _, link := range routedModels {
+		if _, ok := cfg.Pricing[link.Model]; cfg.Budget > 0 && !ok {
+			return nil, fmt.Errorf("a budget of %v %s is set but no price is configured for model %s (see pricing in the configuration)", cfg.Budget, cfg.Currency, link.Model)
+		}
+	}
+
+	// Initialisation du client LLM, inutile en mode --dry-run qui n'envoie aucune requête
+	var client llm.Client
+	if !cfg.DryRun {
+		var err error
+		client, err = llm.GetRoutedClient(selectedLLM, selectedModel)
+		if err != nil {
+			log.Error("Failed to initialize LLM client: %v", err)
+			return nil, fmt.Errorf("%s: %w", i18n.GetMessage("ErrInitLLMClient"), err)
+		}
 	}
 
 	// Initialisation du stockage
@@ -96,9 +144,13 @@ func NewPipeline(includePositions bool, contextOutput bool, contextWords int, en
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
-	// Initialisation de la base sql in memory
-
-	db, err := initDB()
+	// Initialisation de la base de projet, en mémoire sauf si un fichier est configuré.
+	// En mode --dry-run, la base de projet n'est que lue : la base de travail reste en mémoire.
+	dbPath := cfg.Database
+	if cfg.DryRun {
+		dbPath = ""
+	}
+	db, err := initDB(dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -109,6 +161,9 @@ func NewPipeline(includePositions bool, contextOutput bool, contextWords int, en
 		config:                   cfg,
 		logger:                   log,
 		llm:                      client,
+		llmProvider:              selectedLLM,
+		llmModel:                 selectedModel,
+		routedModels:             routedModels,
 		ontology:                 model.NewOntology(),
 		includePositions:         includePositions,
 		contextOutput:            contextOutput,
@@ -133,6 +188,26 @@ func (p *Pipeline) SetProgressCallback(callback ProgressCallback) {
 
 // ExecutePipeline orchestre l'ensemble du flux de travail
 func (p *Pipeline) ExecutePipeline(input string, output string, passes int, existingOntology string, ontology *model.Ontology) error {
+	return p.ExecutePipelineContext(context.Background(), input, output, passes, existingOntology, ontology)
+}
+
+// ExecutePipelineContext exécute le pipeline jusqu'à son terme, à l'annulation de ctx ou à l'expiration
+// de timeout_seconds. L'annulation interrompt les appels au LLM et les requêtes au stockage en cours ;
+// les résultats des segments déjà traités sont alors fusionnés et enregistrés avant de retourner l'erreur.
+func (p *Pipeline) ExecutePipelineContext(ctx context.Context, input string, output string, passes int, existingOntology string, ontology *model.Ontology) error {
+	if p.config.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(p.config.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	runStorage := p.storage
+	p.ctx = ctx
+	p.storage = storage.WithContext(ctx, runStorage)
+	defer func() {
+		p.ctx = nil
+		p.storage = runStorage
+	}()
+
 	p.inputPath = input
 	p.logger.Info(i18n.GetMessage("StartingPipeline"))
 	p.logger.Debug("Input: %s, Output: %s, Passes: %d, Existing Ontology: %s", input, output, passes, existingOntology)
@@ -147,21 +222,56 @@ func (p *Pipeline) ExecutePipeline(input string, output string, passes int, exis
 		p.logger.Error("Failed to initialize tokenizer: %v", err)
 		return fmt.Errorf("failed to initialize tokenizer: %w", err)
 	}
+	if p.tokenCounter == nil {
+		p.tokenCounter = func(text string) int {
+			return len(tke.Encode(text, nil, nil))
+		}
+	}
+	defer p.logUsageSummary()
 
-	// Charger l'ontologie existante si spécifiée
-	if existingOntology != "" {
-		content, err := p.storage.Read(existingOntology)
+	p.runID, err = StartRun(p.db, input, passes)
+	if err != nil {
+		p.logger.Error("Failed to record run: %v", err)
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	// Ne retraiter que les segments en échec de l'exécution précédente, à partir de l'ontologie qu'elle a produite
+	if p.config.RetryFailed {
+		existingOntology, err = p.planRetryFailed(output, existingOntology)
 		if err != nil {
-			p.logger.Error("Failed to read existing ontology: %v", err)
-			return fmt.Errorf("%s: %w", i18n.GetMessage("ErrLoadExistingOntology"), err)
+			return err
 		}
-		result = string(content)
-		tokenCount := len(tke.Encode(result, nil, nil))
-		p.logger.Debug("Loaded existing ontology, token count: %d", tokenCount)
+		passes = 1
+	}
+
+	result, err = p.prepareRun(output, existingOntology, p.db)
+	if err != nil {
+		return err
+	}
+
+	if p.incrementalFiles != nil && len(p.incrementalFiles) == 0 {
+		p.logger.Info("No new or changed files since the previous run, skipping LLM passes")
+		passes = 0
+	}
+
+	if p.config.Resume != "" {
+		p.checkpoint, err = p.resumeCheckpoint(p.config.Resume, input, output)
+	} else if passes > 0 {
+		p.checkpoint, err = p.startCheckpoint(input, output)
+	}
+	if err != nil {
+		p.logger.Error("Failed to open checkpoint: %v", err)
+		return fmt.Errorf("failed to open checkpoint: %w", err)
 	}
 
 	// Effectuer les passes de traitement
+	var interruption error
 	for i := 0; i < passes; i++ {
+		if err := ctx.Err(); err != nil {
+			interruption = err
+			break
+		}
+		p.currentPass = i + 1
 		if p.progressCallback != nil {
 			p.progressCallback(ProgressInfo{
 				CurrentPass: i + 1,
@@ -172,17 +282,66 @@ func (p *Pipeline) ExecutePipeline(input string, output string, passes int, exis
 		initialTokenCount := len(tke.Encode(result, nil, nil))
 		p.logger.Info("Starting pass %d with initial result token count: %d", i+1, initialTokenCount)
 
-		result, finalContent, err = p.processSinglePass(input, result, p.includePositions)
+		passResult, passContent, err := p.processSinglePass(input, result, p.includePositions)
+		if err != nil && ctx.Err() != nil && passContent != nil {
+			// Le résultat partiel de la passe interrompue remplace celui de la passe précédente
+			result, finalContent = passResult, passContent
+			interruption = err
+			break
+		}
 		if err != nil {
 			p.logger.Error(i18n.GetMessage("ErrProcessingPass"), i+1, err)
 			return fmt.Errorf("%s: %w", i18n.GetMessage("ErrProcessingPass"), err)
 		}
+		result, finalContent = passResult, passContent
 
 		newTokenCount := len(tke.Encode(result, nil, nil))
 		p.logger.Info("Completed pass %d, new result token count: %d", i+1, newTokenCount)
 		p.logger.Info("Token count change in pass %d: %d", i+1, newTokenCount-initialTokenCount)
 	}
+	if interruption != nil {
+		return p.saveInterruptedRun(interruption, result, output, finalContent, runStorage)
+	}
+	p.checkpoint.complete(passes)
+
+	if err := p.saveRun(result, output, finalContent); err != nil {
+		return err
+	}
 
+	// Les résultats sont enregistrés même lorsque la couverture est insuffisante, pour pouvoir être repris
+	if err := p.checkCoverage(output); err != nil {
+		p.logger.Error("%v", err)
+		return err
+	}
+
+	if err := CompleteRun(p.db, p.runID); err != nil {
+		p.logger.Warning("Failed to complete run: %v", err)
+	}
+
+	p.logger.Info("Pipeline execution completed successfully")
+	return nil
+}
+
+// saveInterruptedRun enregistre les résultats obtenus avant l'interruption de l'exécution, avec un stockage
+// qui n'est plus lié au contexte annulé, et retourne l'erreur d'interruption
+func (p *Pipeline) saveInterruptedRun(interruption error, result string, output string, finalContent []byte, runStorage storage.Storage) error {
+	p.logger.Warning("Run interrupted: %v", interruption)
+	if finalContent == nil {
+		return fmt.Errorf("run interrupted before any segment was processed: %w", interruption)
+	}
+	p.storage = runStorage
+	if err := p.saveRun(result, output, finalContent); err != nil {
+		return fmt.Errorf("run interrupted (%v), and saving the partial results failed: %w", interruption, err)
+	}
+	p.logger.Warning("Partial results saved to %s", output)
+	if p.checkpoint != nil {
+		p.logger.Warning("Resume the run with: ontology enrich %s --output %s --resume %s", p.checkpoint.state.Input, p.checkpoint.state.Output, p.checkpoint.state.RunID)
+	}
+	return fmt.Errorf("run interrupted, partial results saved: %w", interruption)
+}
+
+// saveRun enregistre l'ontologie, ses fichiers annexes et les métadonnées de l'exécution
+func (p *Pipeline) saveRun(result string, output string, finalContent []byte) error {
 	// Générer les métadonnées
 	metadataGen := metadata.NewGenerator(p.storage)
 	sourcePaths := p.getSourcePaths()
@@ -191,9 +350,11 @@ func (p *Pipeline) ExecutePipeline(input string, output string, passes int, exis
 		p.logger.Error("Failed to generate metadata: %v", err)
 		return fmt.Errorf("failed to generate metadata: %w", err)
 	}
+	meta.Usage = p.UsageReport()
+	meta.Coverage = p.CoverageReport()
 
 	// Sauvegarder les résultats
-	err = p.saveResult(result, output, finalContent, meta.Files)
+	err = p.saveResult(result, output, finalContent)
 	if err != nil {
 		p.logger.Error(i18n.GetMessage("ErrSavingResult"), err)
 		return fmt.Errorf("%s: %w", i18n.GetMessage("ErrSavingResult"), err)
@@ -206,11 +367,54 @@ func (p *Pipeline) ExecutePipeline(input string, output string, passes int, exis
 		p.logger.Error("Failed to save metadata: %v", err)
 		return fmt.Errorf("failed to save metadata: %w", err)
 	}
-
-	p.logger.Info("Pipeline execution completed successfully")
 	return nil
 }
 
+// prepareRun charge le fichier de définition d'ontologie et retourne l'ontologie de départ de la première passe,
+// reprise du fichier de sortie en mode incrémental, de la base de projet projectDB et de l'ontologie existante
+func (p *Pipeline) prepareRun(output string, existingOntology string, projectDB *sql.DB) (string, error) {
+	var result string
+	var err error
+	p.sourceFiles = p.getSourcePaths()
+
+	// Lire le fichier de définition d'ontologie et le schéma qu'il déclare, appliqué après chaque segment
+	if err := p.loadEnrichmentTemplate(); err != nil {
+		p.logger.Error("Failed to load ontology definition file: %v", err)
+		return "", fmt.Errorf("failed to load ontology definition file: %w", err)
+	}
+
+	// En mode incrémental, ne retraiter que les fichiers nouveaux ou modifiés depuis la dernière exécution
+	if p.config.Incremental {
+		result, err = p.planIncrementalRun(output)
+		if err != nil {
+			p.logger.Error("Failed to plan incremental run: %v", err)
+			return "", fmt.Errorf("failed to plan incremental run: %w", err)
+		}
+	}
+
+	// Reprendre l'ontologie accumulée dans la base de projet persistante
+	if projectDB != nil && p.config.Database != "" {
+		dbResult, err := p.loadDatabaseOntology(projectDB)
+		if err != nil {
+			p.logger.Error("Failed to load project database: %v", err)
+			return "", fmt.Errorf("failed to load project database: %w", err)
+		}
+		result = strings.TrimSpace(result + "\n" + dbResult)
+	}
+
+	// Charger l'ontologie existante si spécifiée
+	if existingOntology != "" {
+		seedResult, err := p.loadExistingOntology(existingOntology)
+		if err != nil {
+			p.logger.Error("Failed to load existing ontology: %v", err)
+			return "", fmt.Errorf("%s: %w", i18n.GetMessage("ErrLoadExistingOntology"), err)
+		}
+		result = strings.TrimSpace(result + "\n" + seedResult)
+		p.logger.Debug("Loaded existing ontology, token count: %d", p.tokenCounter(result))
+	}
+	return result, nil
+}
+
 func (p *Pipeline) readPromptFile(filePath string) (string, error) {
 
 	if strings.HasPrefix(filePath, "s3://") {
@@ -3,46 +3,100 @@
 package pipeline
 
 import (
-	"bytes"
 	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
+
+	"github.com/chrlesur/Ontology/internal/language"
+	"github.com/chrlesur/Ontology/internal/metadata"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/parser"
 
-	"github.com/kljensen/snowball/french"
 	"golang.org/x/text/runes"
 	"golang.org/x/text/transform"
 	"golang.org/x/text/unicode/norm"
 )
 
-// PositionRange représente une plage de positions pour un élément
+// PositionRange représente la plage d'octets occupée par un élément dans le contenu d'une passe
 type PositionRange struct {
-	Start   int
-	End     int
+	Start   int // offset en octets du début de la mention
+	End     int // offset en octets de la fin (exclue) de la mention
 	Element string
 }
 
-var stopWords = map[string]bool{
-	"le": true, "la": true, "les": true, "un": true, "une": true, "des": true,
-	"et": true, "en": true, "de": true, "du": true, "ce": true, "qui": true,
-	// Ajoutez d'autres mots stop si nécessaire
+// wordSpan situe un mot dans le contenu d'une passe
+type wordSpan struct {
+	Start int
+	End   int
 }
 
+// createInvertedIndex indexe les racines des mots du contenu, chacune préfixée par la langue de son fichier
 func (p *Pipeline) createInvertedIndex(content []byte) {
     p.invertedIndex = make(map[string][]int)
-    words := bytes.Fields(content)
-    
-    for i := 0; i < len(words); i++ {
-        normalizedWords := strings.Fields(normalizeAndStem(string(words[i])))
-        for _, word := range normalizedWords {
-            if !stopWords[word] && len(word) >= 3 {
-                p.addToIndex(word, i)
-            }
+    p.wordSpans = fieldSpans(content)
+    p.detectLanguages(content)
+    p.detectAcronyms(content)
+
+    file := 0
+    for i, span := range p.wordSpans {
+        // Les mots et les fichiers sont ordonnés par offset : on avance dans les fichiers au fil des mots
+        for file < len(p.fileSpans) && span.Start >= p.fileSpans[file].End {
+            file++
+        }
+        code := p.contentLanguage
+        if file < len(p.fileSpans) && span.Start >= p.fileSpans[file].Start {
+            code = p.fileSpans[file].Language
+        }
+        analyzer := language.Lookup(code)
+        for _, word := range strings.Fields(normalizeAndStem(string(content[span.Start:span.End]), analyzer)) {
+            p.addToIndex(indexTerm(analyzer, word), i)
         }
     }
     
     p.logger.Debug("Inverted index created with %d unique terms", len(p.invertedIndex))
 }
 
+// detectLanguages détermine la langue de chaque fichier de la passe : celle imposée par la configuration,
+// sinon celle détectée d'après ses mots vides
+func (p *Pipeline) detectLanguages(content []byte) {
+	configured := strings.ToLower(p.config.Language)
+	if configured == language.Auto {
+		configured = ""
+	}
+	detect := func(text []byte) string {
+		if configured != "" {
+			return configured
+		}
+		return language.Detect(string(text))
+	}
+
+	for i := range p.fileSpans {
+		file := &p.fileSpans[i]
+		file.Language = detect(content[min(file.Start, len(content)):min(file.End, len(content))])
+		p.logger.Debug("Language of %s: %s", file.Path, file.Language)
+	}
+	p.contentLanguage = detect(content)
+}
+
+// corpusLanguages retourne les langues distinctes du contenu indexé
+func (p *Pipeline) corpusLanguages() []string {
+	languages := []string{p.contentLanguage}
+	seen := map[string]bool{p.contentLanguage: true}
+	for _, file := range p.fileSpans {
+		if !seen[file.Language] {
+			seen[file.Language] = true
+			languages = append(languages, file.Language)
+		}
+	}
+	return languages
+}
+
+// indexTerm construit la clé de l'index inversé d'une racine dans une langue
+func indexTerm(analyzer language.Analyzer, stem string) string {
+	return analyzer.Code() + ":" + stem
+}
+
 func (p *Pipeline) addToIndex(term string, position int) {
 	if _, exists := p.invertedIndex[term]; !exists {
 		p.invertedIndex[term] = []int{}
@@ -53,19 +107,30 @@ func (p *Pipeline) addToIndex(term string, position int) {
 	}
 }
 
+// findPositions retourne les index des mots où commence une occurrence de l'élément,
+// en racinisant son nom dans chacune des langues du contenu
 func (p *Pipeline) findPositions(entityName string, fullContent string) []int {
-    normalizedEntity := normalizeAndStem(entityName)
+    var positions []int
+    for _, code := range p.corpusLanguages() {
+        positions = append(positions, p.findPositionsInLanguage(entityName, language.Lookup(code))...)
+    }
+    sort.Ints(positions)
+    return uniqueIntSlice(positions)
+}
+
+func (p *Pipeline) findPositionsInLanguage(entityName string, analyzer language.Analyzer) []int {
+    normalizedEntity := normalizeAndStem(entityName, analyzer)
     entityParts := strings.Fields(normalizedEntity)
     
     var positions []int
     maxDistance := 5 // Distance maximale entre les mots
 
     if len(entityParts) == 1 {
-        return p.invertedIndex[entityParts[0]]
+        return p.invertedIndex[indexTerm(analyzer, entityParts[0])]
     }
 
     for i, part := range entityParts {
-        if pos, exists := p.invertedIndex[part]; exists {
+        if pos, exists := p.invertedIndex[indexTerm(analyzer, part)]; exists {
             if i == 0 {
                 positions = pos
             } else {
@@ -95,9 +160,9 @@ func intersectNearPositions(pos1, pos2 []int, maxDistance int) []int {
     return result
 }
 
-func normalizeAndStem(text string) string {
-	text = strings.ToLower(text)
-	text = removeAccents(text)
+// normalizeAndStem normalise un texte et retourne les racines de ses mots, sans les mots vides de la langue
+func normalizeAndStem(text string, analyzer language.Analyzer) string {
+	text = language.Normalize(text)
 	text = strings.ReplaceAll(text, "_", " ")
 	text = strings.Map(func(r rune) rune {
 		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsSpace(r) {
@@ -108,7 +173,10 @@ func normalizeAndStem(text string) string {
 	words := strings.Fields(text)
 	stemmedWords := make([]string, 0, len(words))
 	for _, word := range words {
-		stemmed := french.Stem(word, false)
+		if analyzer.IsStopWord(word) {
+			continue
+		}
+		stemmed := analyzer.Stem(word)
 		if stemmed != "" && len(stemmed) >= 3 {
 			stemmedWords = append(stemmedWords, stemmed)
 		}
@@ -125,19 +193,109 @@ func removeAccents(s string) string {
 func (p *Pipeline) getAllPositionsFromNewContent() []PositionRange {
 	var allPositions []PositionRange
 	for _, element := range p.ontology.Elements {
-		positions := p.findPositions(element.Name, string(p.fullContent))
-		for _, pos := range positions {
-			allPositions = append(allPositions, PositionRange{
-				Start:   pos,
-				End:     pos + len(strings.Fields(element.Name)) - 1,
-				Element: element.Name,
-			})
-		}
+		allPositions = append(allPositions, p.findPositionRanges(element.Name, element.Aliases...)...)
 	}
 	p.logger.Debug("Total position ranges collected from ontology: %d", len(allPositions))
 	return allPositions
 }
 
+// findPositionRanges convertit les occurrences d'un élément, sous son nom ou l'un de ses alias,
+// trouvées en index de mots, en plages d'octets triées
+func (p *Pipeline) findPositionRanges(entityName string, aliases ...string) []PositionRange {
+	var ranges []PositionRange
+	seen := make(map[int]bool)
+	for _, name := range append([]string{entityName}, aliases...) {
+		length := max(1, len(strings.Fields(strings.ReplaceAll(name, "_", " "))))
+		for _, position := range uniqueIntSlice(p.findPositions(name, string(p.fullContent))) {
+			if position < 0 || position >= len(p.wordSpans) || seen[position] {
+				continue
+			}
+			seen[position] = true
+			last := min(position+length-1, len(p.wordSpans)-1)
+			start, end := trimPunctuation(p.fullContent, p.wordSpans[position].Start, p.wordSpans[last].End)
+			ranges = append(ranges, PositionRange{
+				Start:   start,
+				End:     end,
+				Element: entityName,
+			})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	return ranges
+}
+
+// findMentions recherche les occurrences d'un élément, sous son nom ou l'un de ses alias, et les situe dans leurs fichiers sources
+func (p *Pipeline) findMentions(entityName string, aliases ...string) []model.Mention {
+	var mentions []model.Mention
+	for _, pr := range p.findPositionRanges(entityName, aliases...) {
+		mentions = append(mentions, p.locate(pr.Start, pr.End))
+	}
+	return mentions
+}
+
+// locate situe une plage d'octets du contenu de la passe dans son fichier source, avec sa page ou son paragraphe.
+// Les offsets de la mention sont relatifs au texte extrait du fichier.
+func (p *Pipeline) locate(start, end int) model.Mention {
+	for _, file := range p.fileSpans {
+		if start < file.Start || start >= file.End {
+			continue
+		}
+		mention := model.Mention{
+			FileID: metadata.FileID(file.Path),
+			Start:  start - file.Start,
+			End:    min(end, file.End) - file.Start,
+		}
+		if location, ok := parser.FindLocation(file.Locations, mention.Start); ok {
+			mention.Page = location.Page
+			mention.Paragraph = location.Paragraph
+		}
+		return mention
+	}
+	return model.Mention{Start: start, End: end}
+}
+
+// trimPunctuation retire la ponctuation qui entoure une plage d'octets, afin que la mention ne couvre que le nom
+func trimPunctuation(content []byte, start, end int) (int, int) {
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	for start < end {
+		r, size := utf8.DecodeRune(content[start:end])
+		if isWordRune(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRune(content[start:end])
+		if isWordRune(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
+
+// fieldSpans découpe le contenu en mots comme bytes.Fields, en conservant leurs offsets en octets
+func fieldSpans(content []byte) []wordSpan {
+	var spans []wordSpan
+	start := -1
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				spans = append(spans, wordSpan{Start: start, End: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+		i += size
+	}
+	if start >= 0 {
+		spans = append(spans, wordSpan{Start: start, End: len(content)})
+	}
+	return spans
+}
+
 func mergeOverlappingPositions(positions []PositionRange) []PositionRange {
 	if len(positions) == 0 {
 		return positions
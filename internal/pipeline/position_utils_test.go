@@ -0,0 +1,89 @@
+// pipeline/position_utils_test.go
+
+package pipeline
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/metadata"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCorpus concatène deux fichiers comme readDirectory et indexe le contenu
+func newTestCorpus(p *Pipeline) []byte {
+	charte := "La PSSI définit la politique."
+	note := "Le RSSI pilote la PSSI."
+	content := []byte(charte + "\n" + note + "\n")
+
+	p.fileSpans = []fileSpan{
+		{Path: "corpus/charte.md", Start: 0, End: len(charte), Locations: []parser.Location{{Start: 0, End: len(charte), Paragraph: 1}}},
+		{Path: "corpus/note.pdf", Start: len(charte) + 1, End: len(charte) + 1 + len(note), Locations: []parser.Location{{Start: 0, End: len(note), Page: 3}}},
+	}
+	p.fullContent = content
+	p.createInvertedIndex(content)
+	return content
+}
+
+func TestFindMentionsUsesFileByteOffsets(t *testing.T) {
+	p := newTestPipeline()
+	newTestCorpus(p)
+
+	mentions := p.findMentions("PSSI")
+	assert.Equal(t, []model.Mention{
+		{FileID: metadata.FileID("corpus/charte.md"), Start: 3, End: 7, Paragraph: 1},
+		{FileID: metadata.FileID("corpus/note.pdf"), Start: 18, End: 22, Page: 3},
+	}, mentions)
+}
+
+func TestGenerateContextJSONLocatesMentions(t *testing.T) {
+	p := newTestPipeline()
+	content := newTestCorpus(p)
+	p.ontology.AddElement(&model.OntologyElement{Name: "RSSI", Type: "Role"})
+
+	output, err := GenerateContextJSON(content, 2, p.getAllPositionsFromNewContent(), p.locate, nil)
+	assert.NoError(t, err)
+
+	var entries []ContextEntry
+	assert.NoError(t, json.Unmarshal([]byte(output), &entries))
+	if assert.Len(t, entries, 1) {
+		entry := entries[0]
+		assert.Equal(t, "RSSI", entry.Element)
+		assert.Equal(t, metadata.FileID("corpus/note.pdf"), entry.FileID)
+		assert.Equal(t, 3, entry.FilePosition)
+		assert.Equal(t, 7, entry.FileEnd)
+		assert.Equal(t, 3, entry.Page)
+		assert.Equal(t, "RSSI", string(content[entry.Position:entry.Position+entry.Length]))
+		assert.Equal(t, []string{"politique.", "Le"}, entry.Before)
+		assert.Equal(t, []string{"pilote", "la"}, entry.After)
+	}
+}
+
+func TestFindPositionsPerFileLanguage(t *testing.T) {
+	p := newTestPipeline()
+	english := "The security policies are reviewed every year."
+	french := "Les politiques de sécurité sont revues chaque année."
+	content := []byte(english + "\n" + french + "\n")
+	p.fileSpans = []fileSpan{
+		{Path: "corpus/policy.md", Start: 0, End: len(english)},
+		{Path: "corpus/politique.md", Start: len(english) + 1, End: len(english) + 1 + len(french)},
+	}
+	p.fullContent = content
+	p.createInvertedIndex(content)
+
+	assert.Equal(t, "en", p.fileSpans[0].Language)
+	assert.Equal(t, "fr", p.fileSpans[1].Language)
+
+	mentions := p.findMentions("Security_Policy")
+	if assert.Len(t, mentions, 1) {
+		assert.Equal(t, metadata.FileID("corpus/policy.md"), mentions[0].FileID)
+		assert.Equal(t, "security policies", english[mentions[0].Start:mentions[0].End])
+	}
+	assert.Len(t, p.findMentions("Politique de sécurité"), 1)
+
+	p.config.Language = "fr"
+	p.createInvertedIndex(content)
+	assert.Equal(t, "fr", p.fileSpans[0].Language)
+}
@@ -0,0 +1,69 @@
+// provenance.go
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+// attachProvenance renseigne la provenance des éléments et relations de l'ontologie à partir de la base.
+// Le champ Source des entités reçoit la liste des fichiers sources, également enregistrée en base.
+// Elle retourne la provenance des éléments indexée par nom, utilisée pour le fichier de contexte.
+func (p *Pipeline) attachProvenance() (map[string][]model.Provenance, error) {
+	entityRecords, err := GetAllProvenance(p.db, provenanceEntity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entity provenance: %w", err)
+	}
+	relationRecords, err := GetAllProvenance(p.db, provenanceRelation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relation provenance: %w", err)
+	}
+
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
+	byElement := make(map[string][]model.Provenance)
+	for _, element := range p.ontology.Elements {
+		provenance := mergeProvenance(element.Provenance, entityRecords[element.Name])
+		if len(provenance) == 0 {
+			continue
+		}
+		element.Provenance = provenance
+		element.Source = strings.Join(model.ProvenanceFileIDs(provenance), ",")
+		byElement[element.Name] = provenance
+		if _, err := p.db.Exec(`UPDATE entities SET source = ? WHERE name = ?`, element.Source, element.Name); err != nil {
+			p.logger.Warning("Failed to update source of entity %s: %v", element.Name, err)
+		}
+	}
+
+	for _, relation := range p.ontology.Relations {
+		relation.Provenance = mergeProvenance(relation.Provenance, relationRecords[relationProvenanceKey(relation)])
+	}
+
+	p.logger.Debug("Attached provenance to %d elements", len(byElement))
+	return byElement, nil
+}
+
+// mergeProvenance ajoute à une provenance existante celle des enregistrements de la base, sans doublon
+func mergeProvenance(existing []model.Provenance, records []ProvenanceRecord) []model.Provenance {
+	seen := make(map[model.Provenance]bool)
+	var merged []model.Provenance
+	add := func(entry model.Provenance) {
+		if !seen[entry] {
+			seen[entry] = true
+			merged = append(merged, entry)
+		}
+	}
+	for _, entry := range existing {
+		add(entry)
+	}
+	for _, record := range records {
+		for _, entry := range record.Entries() {
+			add(entry)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,286 @@
+// resolution.go
+
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/chrlesur/Ontology/internal/language"
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+// Pondération du score de résolution : la similarité des noms domine, le type départage
+const (
+	nameWeight = 0.85
+	typeWeight = 0.15
+)
+
+// Raisons inscrites au journal des fusions
+const (
+	reasonSameName    = "same normalized name"
+	reasonSameStem    = "same stemmed name"
+	reasonSimilarName = "similar name"
+)
+
+// resolutionCandidate est une entité préparée pour la comparaison
+type resolutionCandidate struct {
+	entity *model.OntologyElement
+	key    string   // mots significatifs du nom, sans accents ni casse
+	stem   string   // racines de ces mots
+	tokens []string // mots de la clé, utilisés pour ne comparer que les entités qui en partagent un
+	kind   string   // type normalisé
+}
+
+// resolveEntities fusionne, sans appel au LLM, les entités de la base dont les noms et les types sont assez proches.
+// Les entités les mieux attestées sont conservées ; les autres y sont fusionnées et la fusion est journalisée.
+func (p *Pipeline) resolveEntities() ([]MergeRecord, error) {
+	entities, err := GetAllEntities(p.db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load entities for resolution: %w", err)
+	}
+	analyzer := language.Lookup(p.contentLanguage)
+
+	candidates := make([]*resolutionCandidate, len(entities))
+	for i, entity := range entities {
+		candidates[i] = newResolutionCandidate(entity, analyzer)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return preferredEntity(candidates[i].entity, candidates[j].entity)
+	})
+
+	byToken := make(map[string][]int)
+	for i, candidate := range candidates {
+		for _, token := range candidate.tokens {
+			byToken[token] = append(byToken[token], i)
+		}
+	}
+
+	threshold := p.config.ResolutionThreshold
+	merged := make([]bool, len(candidates))
+	var records []MergeRecord
+	for i, kept := range candidates {
+		if merged[i] {
+			continue
+		}
+		for _, j := range candidateNeighbours(kept, byToken, i) {
+			if merged[j] {
+				continue
+			}
+			other := candidates[j]
+			score, reason := resolutionScore(kept, other)
+			if score < threshold {
+				continue
+			}
+			record := MergeRecord{
+				RunID:     p.runID,
+				Pass:      p.currentPass,
+				Kept:      kept.entity.Name,
+				Merged:    other.entity.Name,
+				Score:     score,
+				Reason:    reason,
+				CreatedAt: time.Now(),
+			}
+			mergeElementInto(kept.entity, other.entity)
+			if err := MergeEntity(p.db, kept.entity, record); err != nil {
+				return records, err
+			}
+			merged[j] = true
+			records = append(records, record)
+			log.Info("Merged entity %s into %s (score %.2f, %s)", record.Merged, record.Kept, score, reason)
+		}
+	}
+	p.merges = append(p.merges, records...)
+	return records, nil
+}
+
+// preferredEntity définit l'ordre déterministe dans lequel les entités sont conservées lors d'une fusion :
+// le plus de mentions, puis la description la plus longue, puis le nom
+func preferredEntity(a, b *model.OntologyElement) bool {
+	if len(a.Mentions) != len(b.Mentions) {
+		return len(a.Mentions) > len(b.Mentions)
+	}
+	if len(a.Description) != len(b.Description) {
+		return len(a.Description) > len(b.Description)
+	}
+	return a.Name < b.Name
+}
+
+// candidateNeighbours retourne, dans l'ordre de préférence, les entités suivantes qui partagent un mot avec l'entité donnée
+func candidateNeighbours(candidate *resolutionCandidate, byToken map[string][]int, index int) []int {
+	seen := make(map[int]bool)
+	var neighbours []int
+	for _, token := range candidate.tokens {
+		for _, j := range byToken[token] {
+			if j > index && !seen[j] {
+				seen[j] = true
+				neighbours = append(neighbours, j)
+			}
+		}
+	}
+	sort.Ints(neighbours)
+	return neighbours
+}
+
+func newResolutionCandidate(entity *model.OntologyElement, analyzer language.Analyzer) *resolutionCandidate {
+	tokens := significantTokens(entity.Name, analyzer)
+	stems := make([]string, len(tokens))
+	for i, token := range tokens {
+		stems[i] = analyzer.Stem(token)
+	}
+	return &resolutionCandidate{
+		entity: entity,
+		key:    strings.Join(tokens, " "),
+		stem:   strings.Join(stems, " "),
+		tokens: tokens,
+		kind:   normalizeElementKey(entity.Type),
+	}
+}
+
+// significantTokens découpe un nom en mots sans accents ni casse, en écartant les élisions et les mots vides
+func significantTokens(name string, analyzer language.Analyzer) []string {
+	words := strings.FieldsFunc(strings.ToLower(removeAccents(name)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		if len([]rune(word)) < 2 && !unicode.IsNumber([]rune(word)[0]) {
+			continue
+		}
+		if analyzer.IsStopWord(word) {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	if len(tokens) == 0 {
+		return words
+	}
+	return tokens
+}
+
+// resolutionScore compare deux entités : similarité des noms pondérée par l'accord des types
+func resolutionScore(a, b *resolutionCandidate) (float64, string) {
+	if numbers(a.tokens) != numbers(b.tokens) {
+		return 0, ""
+	}
+
+	var nameScore float64
+	var reason string
+	switch {
+	case a.key == b.key:
+		nameScore, reason = 1, reasonSameName
+	case a.stem == b.stem:
+		nameScore, reason = 0.97, reasonSameStem
+	default:
+		nameScore, reason = similarity(a.key, b.key), reasonSimilarName
+	}
+
+	typeScore := 0.0
+	switch {
+	case a.kind == b.kind:
+		typeScore = 1
+	case a.kind == "" || b.kind == "" || a.kind == "thing" || b.kind == "thing":
+		typeScore = 0.5
+	}
+	return nameWeight*nameScore + typeWeight*typeScore, reason
+}
+
+// numbers retourne les mots numériques d'un nom : deux entités numérotées différemment ne sont jamais fusionnées
+func numbers(tokens []string) string {
+	var digits []string
+	for _, token := range tokens {
+		if strings.IndexFunc(token, unicode.IsNumber) >= 0 {
+			digits = append(digits, token)
+		}
+	}
+	return strings.Join(digits, " ")
+}
+
+// similarity retourne 1 moins la distance d'édition rapportée à la longueur de la plus longue chaîne
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// mergeElementInto reporte sur l'entité conservée la description, les positions, les mentions, les sources et les alias d'une autre
+func mergeElementInto(kept, other *model.OntologyElement) {
+	kept.Description = mergeDescriptions(kept.Description, other.Description)
+	if kept.Type == "" {
+		kept.Type = other.Type
+	}
+
+	positions := append(append([]int{}, kept.Positions...), other.Positions...)
+	sort.Ints(positions)
+	kept.Positions = uniquePositions(positions)
+
+	seen := make(map[model.Mention]bool)
+	var mentions []model.Mention
+	for _, mention := range append(append([]model.Mention{}, kept.Mentions...), other.Mentions...) {
+		if !seen[mention] {
+			seen[mention] = true
+			mentions = append(mentions, mention)
+		}
+	}
+	sort.SliceStable(mentions, func(i, j int) bool {
+		if mentions[i].FileID != mentions[j].FileID {
+			return mentions[i].FileID < mentions[j].FileID
+		}
+		return mentions[i].Start < mentions[j].Start
+	})
+	kept.Mentions = mentions
+
+	var sources []string
+	for _, source := range strings.Split(kept.Source+","+other.Source, ",") {
+		if source = strings.TrimSpace(source); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	kept.Source = strings.Join(UniqueStringSlice(sources), ",")
+
+	for _, alias := range append([]string{other.Name}, other.Aliases...) {
+		kept.AddAlias(alias)
+	}
+	kept.UpdatedAt = time.Now()
+}
+
+// mergeDescriptions conserve la plus complète de deux descriptions, ou les juxtapose si aucune ne contient l'autre
+func mergeDescriptions(a, b string) string {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	switch {
+	case b == "" || strings.Contains(strings.ToLower(a), strings.ToLower(b)):
+		return a
+	case a == "" || strings.Contains(strings.ToLower(b), strings.ToLower(a)):
+		return b
+	}
+	if !strings.ContainsAny(a[len(a)-1:], ".!?") {
+		a += "."
+	}
+	return a + " " + b
+}
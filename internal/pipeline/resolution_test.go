@@ -0,0 +1,83 @@
+// pipeline/resolution_test.go
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveEntitiesMergesNearDuplicates(t *testing.T) {
+	p := newTestPipeline()
+	p.config.ResolutionThreshold = 0.9
+	now := time.Now()
+	entities := []*model.OntologyElement{
+		{Name: "Conseil_d_État", Type: "Institution", Description: "Juridiction administrative suprême",
+			Mentions: []model.Mention{{FileID: "a1b2c3d4e5f6", Start: 10, End: 24}}, Source: "a1b2c3d4e5f6"},
+		{Name: "Conseil_Etat", Type: "Institution", Description: "Conseiller du gouvernement",
+			Mentions: []model.Mention{{FileID: "0f9e8d7c6b5a", Start: 3, End: 15}}, Source: "0f9e8d7c6b5a"},
+		{Name: "Conseil_Régional", Type: "Institution", Description: "Assemblée de la région"},
+		{Name: "Directive_2016", Type: "Norme"},
+		{Name: "Directive_2017", Type: "Norme"},
+		{Name: "Politiques_de_sécurité", Type: "Document"},
+		{Name: "Politique_de_sécurité", Type: "Document", Description: "Politique de sécurité du SI"},
+		{Name: "Politique_Securite", Type: "Personne"},
+	}
+	for _, entity := range entities {
+		entity.CreatedAt, entity.UpdatedAt = now, now
+		assert.NoError(t, UpsertEntity(p.db, entity))
+	}
+	for _, relation := range []*model.Relation{
+		{Source: "Conseil_Etat", Type: "applique", Target: "Directive_2016", Weight: 2},
+		{Source: "Conseil_d_État", Type: "applique", Target: "Directive_2016", Weight: 3},
+		{Source: "Conseil_Etat", Type: "conseille", Target: "Politiques_de_sécurité", Weight: 1},
+	} {
+		assert.NoError(t, UpsertRelation(p.db, relation))
+	}
+
+	records, err := p.resolveEntities()
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "Conseil_d_État", records[0].Kept)
+		assert.Equal(t, "Conseil_Etat", records[0].Merged)
+		assert.Equal(t, reasonSameName, records[0].Reason)
+		assert.Equal(t, "Politique_de_sécurité", records[1].Kept)
+		assert.Equal(t, "Politiques_de_sécurité", records[1].Merged)
+		assert.Equal(t, reasonSameStem, records[1].Reason)
+	}
+
+	stored, err := GetAllEntities(p.db)
+	assert.NoError(t, err)
+	assert.Len(t, stored, 6)
+	for _, entity := range stored {
+		if entity.Name == "Conseil_d_État" {
+			assert.Equal(t, "Juridiction administrative suprême. Conseiller du gouvernement", entity.Description)
+			assert.Len(t, entity.Mentions, 2)
+			assert.Equal(t, "a1b2c3d4e5f6,0f9e8d7c6b5a", entity.Source)
+			assert.Equal(t, []string{"Conseil_Etat"}, entity.Aliases)
+		}
+	}
+
+	relations, err := GetAllRelations(p.db)
+	assert.NoError(t, err)
+	var keys []string
+	for _, relation := range relations {
+		keys = append(keys, relationProvenanceKey(relation))
+	}
+	assert.ElementsMatch(t, []string{
+		"Conseil_d_État\tapplique\tDirective_2016",
+		"Conseil_d_État\tconseille\tPolitique_de_sécurité",
+	}, keys)
+
+	merges, err := GetMerges(p.db)
+	assert.NoError(t, err)
+	assert.Len(t, merges, 2)
+
+	// La résolution est reproductible : une seconde exécution ne fusionne plus rien
+	records, err = p.resolveEntities()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
@@ -0,0 +1,50 @@
+// routing.go
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+)
+
+// requestRoute décrit une requête pour les règles de routage : la tâche, segment ou fusion, et la taille
+// approximative du segment ou des résultats fusionnés, hors instructions et contexte du prompt
+func requestRoute(offset int, values map[string]string) llm.Route {
+	if offset == mergeOffset {
+		return llm.Route{Task: llm.TaskMerge, Tokens: llm.ApproximateTokens(values["previous_ontology"], values["new_ontology"])}
+	}
+	return llm.Route{Task: llm.TaskSegment, Tokens: llm.ApproximateTokens(values["text"])}
+}
+
+// pricedModels retourne les modèles dont le prix borne le coût d'un appel : le modèle sélectionné
+// et ceux vers lesquels les requêtes peuvent être routées
+func (p *Pipeline) pricedModels() []string {
+	if len(p.routedModels) == 0 {
+		return []string{p.llmModel}
+	}
+	models := make([]string, 0, len(p.routedModels))
+	for _, link := range p.routedModels {
+		models = append(models, link.Model)
+	}
+	return models
+}
+
+// answeringModel retourne le modèle qui a répondu à une requête, le modèle sélectionné si elle n'a pas été routée
+func (p *Pipeline) answeringModel(usage llm.Usage) string {
+	if usage.Model != "" {
+		return usage.Model
+	}
+	return p.llmModel
+}
+
+// routingSettings résume les règles de routage pour les points de reprise, vide sans règle.
+// La chaîne de repli n'en fait pas partie : elle peut être changée pour reprendre une exécution interrompue par une panne.
+func (p *Pipeline) routingSettings() string {
+	var rules []string
+	for _, rule := range p.config.Routing {
+		rules = append(rules, fmt.Sprintf("%s<=%d:%s:%s", rule.Task, rule.MaxTokens, rule.LLM, rule.Model))
+	}
+	return strings.Join(rules, ",")
+}
@@ -0,0 +1,75 @@
+// pipeline/routing_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRoutingPipeline route les segments courts vers un modèle économique et les fusions vers un modèle plus fort
+func newTestRoutingPipeline(t *testing.T) (*Pipeline, map[string]*fakeLLM) {
+	p, _ := newTestUsagePipeline()
+	p.llmProvider = "claude"
+	p.config.Pricing["claude-3-haiku-20240307"] = config.ModelPricing{Input: 0.25, Output: 1.25}
+	p.config.Pricing["claude-3-opus-20240229"] = config.ModelPricing{Input: 15, Output: 75}
+	p.config.Routing = []config.RoutingRule{
+		{Task: llm.TaskSegment, MaxTokens: 50, LLM: "claude", Model: "claude-3-haiku-20240307"},
+		{Task: llm.TaskMerge, LLM: "claude", Model: "claude-3-opus-20240229"},
+	}
+
+	clients := make(map[string]*fakeLLM)
+	selected := llm.Link{Provider: "claude", Model: "test-model"}
+	router, err := llm.NewRouterClient(p.config, selected, func(link llm.Link) (llm.Client, error) {
+		clients[link.Model] = &fakeLLM{
+			responses: []string{"PSSI\tDocument\tPolitique de sécurité"},
+			usage:     llm.Usage{InputTokens: 1000, OutputTokens: 100, Requests: 1},
+		}
+		return clients[link.Model], nil
+	})
+	assert.NoError(t, err)
+	p.llm = router
+	p.routedModels, err = llm.RoutedModels(p.config, selected)
+	assert.NoError(t, err)
+	return p, clients
+}
+
+func TestRoutedRequestsArePricedByAnsweringModel(t *testing.T) {
+	p, clients := newTestRoutingPipeline(t)
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.NoError(t, err)
+	_, err = p.mergeBatch([]string{"PSSI\tDocument\tPolitique", "RSSI\tRole\tResponsable"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, clients["claude-3-haiku-20240307"].calls)
+	assert.Equal(t, 1, clients["claude-3-opus-20240229"].calls)
+	assert.Equal(t, 0, clients["test-model"].calls)
+
+	report := p.UsageReport()
+	assert.InDelta(t, 0.000375, report.Models["claude:claude-3-haiku-20240307"].Cost, 1e-9)
+	assert.InDelta(t, 0.0225, report.Models["claude:claude-3-opus-20240229"].Cost, 1e-9)
+	assert.InDelta(t, 0.022875, report.Total.Cost, 1e-9)
+}
+
+func TestBudgetEstimatesRoutedCallsAtHighestPrice(t *testing.T) {
+	p, clients := newTestRoutingPipeline(t)
+	// Un segment court part vers le modèle économique, mais pourrait se replier sur le plus cher
+	p.config.Budget = 0.05
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 0, clients["claude-3-haiku-20240307"].calls)
+}
+
+func TestRequestRoute(t *testing.T) {
+	route := requestRoute(0, map[string]string{"text": "La PSSI est rédigée par le RSSI.", "context": "Contexte très long"})
+	assert.Equal(t, llm.Route{Task: llm.TaskSegment, Tokens: 9}, route)
+
+	route = requestRoute(mergeOffset, map[string]string{"previous_ontology": "PSSI\tDocument", "new_ontology": "RSSI\tRole"})
+	assert.Equal(t, llm.TaskMerge, route.Task)
+	assert.Equal(t, 6, route.Tokens)
+}
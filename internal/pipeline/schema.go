@@ -0,0 +1,241 @@
+// schema.go
+
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/chrlesur/Ontology/internal/language"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// Natures des écarts au schéma consignés dans le rapport
+const (
+	schemaTypeMapped      = "type_mapped"
+	schemaTypeRejected    = "type_rejected"
+	schemaRelationUnknown = "relation_unknown"
+	schemaRelationDropped = "relation_dropped"
+	schemaDomainViolation = "domain_violation"
+	schemaRangeViolation  = "range_violation"
+)
+
+// SchemaViolation décrit un écart entre le résultat d'un segment et le schéma du template
+type SchemaViolation struct {
+	Pass     int    `json:"pass"`
+	Kind     string `json:"kind"`
+	Element  string `json:"element"`        // nom de l'entité, ou relation Source -type-> Cible
+	Type     string `json:"type,omitempty"` // type d'entité ou de relation proposé par le LLM
+	MappedTo string `json:"mapped_to,omitempty"`
+	Message  string `json:"message"`
+}
+
+// SchemaReport est le rapport de conformité au schéma écrit à côté de l'ontologie
+type SchemaReport struct {
+	Template      string            `json:"template"`
+	EntityTypes   int               `json:"entity_types"`
+	RelationTypes int               `json:"relation_types"`
+	Summary       map[string]int    `json:"summary"`
+	Violations    []SchemaViolation `json:"violations"`
+}
+
+// loadEnrichmentTemplate lit le fichier de définition d'ontologie, dont est assemblé le prompt d'enrichissement,
+// et les types d'entités et relations qu'il déclare. Le schéma n'est appliqué que s'il déclare au moins un type d'entité.
+func (p *Pipeline) loadEnrichmentTemplate() error {
+	if p.enrichmentPromptFile == "" {
+		return nil
+	}
+	template, err := p.readEnrichmentTemplate()
+	if err != nil {
+		return err
+	}
+	// Un emplacement mal nommé ferait échouer chaque segment : l'erreur est signalée dès le chargement
+	if err := template.CheckVariables(); err != nil {
+		return fmt.Errorf("invalid ontology definition file %s: %w", p.enrichmentPromptFile, err)
+	}
+	if err := template.Validate(); err != nil {
+		if template.Structured {
+			return err
+		}
+		log.Warning("Ontology definition file %s: %v", p.enrichmentPromptFile, err)
+	}
+	p.enrichmentTemplate = template
+	if template.Structured {
+		log.Info("Using template %s version %s from %s", template.Name, template.Version, p.enrichmentPromptFile)
+	}
+	if p.config.Mode == ModeTwoStage {
+		log.Info("Two-stage mode: the prompt of %s is replaced by the extraction prompts, only its types are used", p.enrichmentPromptFile)
+	}
+
+	if !p.config.SchemaEnforcement {
+		return nil
+	}
+	schema := template.Schema()
+	if schema.IsEmpty() {
+		log.Info("No entity types declared in %s, schema not enforced", p.enrichmentPromptFile)
+		return nil
+	}
+	p.schema = schema
+	log.Info("Schema loaded from %s: %d entity types, %d relation types", p.enrichmentPromptFile, len(schema.EntityTypes), len(schema.RelationTypes))
+	return nil
+}
+
+// readEnrichmentTemplate lit et analyse le fichier de définition d'ontologie, local ou sur S3
+func (p *Pipeline) readEnrichmentTemplate() (*prompt.Template, error) {
+	content, err := p.readPromptFile(p.enrichmentPromptFile)
+	if err != nil {
+		return nil, err
+	}
+	template, err := prompt.ParseTemplate(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ontology definition file %s: %w", p.enrichmentPromptFile, err)
+	}
+	return template, nil
+}
+
+// schemaEntityType retrouve le type du schéma correspondant à un type proposé : par son nom exact,
+// son nom normalisé, l'un de ses sous-types ou la racine de son nom
+func (p *Pipeline) schemaEntityType(elementType string) (string, bool) {
+	if def := p.schema.EntityType(elementType); def != nil {
+		return def.Name, true
+	}
+	key := normalizeElementKey(elementType)
+	for _, def := range p.schema.EntityTypes {
+		if normalizeElementKey(def.Name) == key {
+			return def.Name, true
+		}
+	}
+	for _, def := range p.schema.EntityTypes {
+		for _, subType := range def.SubTypes {
+			if normalizeElementKey(subType) == key {
+				return def.Name, true
+			}
+		}
+	}
+	analyzer := language.Lookup(p.contentLanguage)
+	if stem := normalizeAndStem(elementType, analyzer); stem != "" {
+		for _, def := range p.schema.EntityTypes {
+			if normalizeAndStem(def.Name, analyzer) == stem {
+				return def.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// enforceEntityType ramène le type d'une entité à un type du schéma.
+// Elle retourne false si le type est inconnu : l'entité est alors rejetée.
+func (p *Pipeline) enforceEntityType(name, elementType string) (string, bool) {
+	if p.schema == nil {
+		return elementType, true
+	}
+	mapped, ok := p.schemaEntityType(elementType)
+	if !ok {
+		p.recordSchemaViolation(SchemaViolation{
+			Kind:    schemaTypeRejected,
+			Element: name,
+			Type:    elementType,
+			Message: fmt.Sprintf("type %s is not declared by the schema", elementType),
+		})
+		return "", false
+	}
+	if mapped != elementType {
+		p.recordSchemaViolation(SchemaViolation{
+			Kind:     schemaTypeMapped,
+			Element:  name,
+			Type:     elementType,
+			MappedTo: mapped,
+			Message:  fmt.Sprintf("type %s mapped to %s", elementType, mapped),
+		})
+	}
+	return mapped, true
+}
+
+// dropRejectedRelation écarte une relation dont l'une des extrémités a été rejetée par le schéma
+func (p *Pipeline) dropRejectedRelation(source, relationType, target string, rejected map[string]bool) bool {
+	if p.schema == nil || (!rejected[normalizeElementKey(source)] && !rejected[normalizeElementKey(target)]) {
+		return false
+	}
+	p.recordSchemaViolation(SchemaViolation{
+		Kind:    schemaRelationDropped,
+		Element: fmt.Sprintf("%s -%s-> %s", source, relationType, target),
+		Type:    relationType,
+		Message: "relation references an entity rejected by the schema",
+	})
+	return true
+}
+
+// checkRelationSchema ramène le type d'une relation au nom déclaré par le schéma et signale
+// les relations inconnues ainsi que celles dont la source ou la cible sort du domaine ou de la portée
+func (p *Pipeline) checkRelationSchema(relation *model.Relation) {
+	if p.schema == nil {
+		return
+	}
+	def := p.schema.RelationType(relation.Type)
+	if def == nil {
+		key := normalizeElementKey(relation.Type)
+		for i := range p.schema.RelationTypes {
+			if normalizeElementKey(p.schema.RelationTypes[i].Name) == key {
+				def = &p.schema.RelationTypes[i]
+				break
+			}
+		}
+	}
+	element := fmt.Sprintf("%s -%s-> %s", relation.Source, relation.Type, relation.Target)
+	if def == nil {
+		p.recordSchemaViolation(SchemaViolation{
+			Kind:    schemaRelationUnknown,
+			Element: element,
+			Type:    relation.Type,
+			Message: fmt.Sprintf("relation %s is not declared by the schema", relation.Type),
+		})
+		return
+	}
+	relation.Type = def.Name
+
+	if source := p.ontology.GetElementByName(relation.Source); source != nil && !model.Allows(def.Domain, source.Type) {
+		p.recordSchemaViolation(SchemaViolation{
+			Kind:    schemaDomainViolation,
+			Element: element,
+			Type:    def.Name,
+			Message: fmt.Sprintf("source type %s is not in the domain of %s %v", source.Type, def.Name, def.Domain),
+		})
+	}
+	if target := p.ontology.GetElementByName(relation.Target); target != nil && !model.Allows(def.Range, target.Type) {
+		p.recordSchemaViolation(SchemaViolation{
+			Kind:    schemaRangeViolation,
+			Element: element,
+			Type:    def.Name,
+			Message: fmt.Sprintf("target type %s is not in the range of %s %v", target.Type, def.Name, def.Range),
+		})
+	}
+}
+
+// recordSchemaViolation consigne un écart au schéma pour la passe en cours
+func (p *Pipeline) recordSchemaViolation(violation SchemaViolation) {
+	violation.Pass = p.currentPass
+	if violation.Kind == schemaTypeMapped {
+		log.Debug("Schema: %s (%s)", violation.Message, violation.Element)
+	} else {
+		log.Warning("Schema: %s (%s)", violation.Message, violation.Element)
+	}
+	p.schemaViolations = append(p.schemaViolations, violation)
+}
+
+// schemaReport rassemble les écarts consignés pendant l'exécution
+func (p *Pipeline) schemaReport() SchemaReport {
+	report := SchemaReport{
+		Template:      p.enrichmentPromptFile,
+		EntityTypes:   len(p.schema.EntityTypes),
+		RelationTypes: len(p.schema.RelationTypes),
+		Summary:       make(map[string]int),
+		Violations:    p.schemaViolations,
+	}
+	for _, violation := range p.schemaViolations {
+		report.Summary[violation.Kind]++
+	}
+	if report.Violations == nil {
+		report.Violations = []SchemaViolation{}
+	}
+	return report
+}
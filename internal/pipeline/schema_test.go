@@ -0,0 +1,101 @@
+// pipeline/schema_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSchema() *model.Schema {
+	schema := model.NewSchema()
+	schema.AddEntityType(model.EntityTypeDef{Name: "Politique_Sécurité", SubTypes: []string{"directive"}})
+	schema.AddEntityType(model.EntityTypeDef{Name: "Controle_Sécurité"})
+	schema.AddEntityType(model.EntityTypeDef{Name: "Actif_Sécurité"})
+	schema.AddEntityType(model.EntityTypeDef{Name: "Role_Sécurité", SubTypes: []string{"RSSI"}})
+	schema.AddRelationType(model.RelationTypeDef{Name: "protège", Domain: []string{"Controle_Sécurité"}, Range: []string{"Actif_Sécurité"}})
+	schema.AddRelationType(model.RelationTypeDef{Name: "supervise", Domain: []string{"Role_Sécurité"}})
+	return schema
+}
+
+func TestEnrichOntologyWithPositionsEnforcesSchema(t *testing.T) {
+	p := newTestPipeline()
+	p.schema = newTestSchema()
+	p.currentPass = 1
+	input := "PSSI\tPolitique_Securite\tPolitique de sécurité du SI\n" +
+		"Charte_Informatique\tDirective\tRègles d'usage du SI\n" +
+		"Pare-feu\tControle_Sécurité\tFiltrage réseau\n" +
+		"Serveur_Web\tActif_Sécurité\tServeur exposé\n" +
+		"Responsable_Sécurité\tRSSI\tPilote la sécurité\n" +
+		"Cantine\tLieu\tRestaurant d'entreprise\n" +
+		"Pare-feu\tProtège:3\tServeur_Web\tLe pare-feu protège le serveur\n" +
+		"Serveur_Web\tprotège:1\tPSSI\tRelation inversée\n" +
+		"Responsable_Sécurité\tsupervise:2\tCantine\tHors périmètre\n" +
+		"PSSI\tmentionne:1\tServeur_Web\tRelation non déclarée"
+
+	result := p.enrichOntologyWithPositions(input, false, "", 0)
+
+	assert.Len(t, p.ontology.Elements, 5)
+	assert.Nil(t, p.ontology.GetElementByName("Cantine"))
+	assert.Equal(t, "Politique_Sécurité", p.ontology.GetElementByName("PSSI").Type)
+	assert.Equal(t, "Politique_Sécurité", p.ontology.GetElementByName("Charte_Informatique").Type)
+	assert.Equal(t, "Role_Sécurité", p.ontology.GetElementByName("Responsable_Sécurité").Type)
+	assert.NotNil(t, p.ontology.GetRelation("Pare-feu", "protège", "Serveur_Web"))
+	assert.Nil(t, p.ontology.GetRelation("Responsable_Sécurité", "supervise", "Cantine"))
+	assert.NotContains(t, result, "Cantine")
+	assert.Contains(t, result, "PSSI\tPolitique_Sécurité\tPolitique de sécurité du SI")
+
+	report := p.schemaReport()
+	assert.Equal(t, map[string]int{
+		schemaTypeMapped:      3,
+		schemaTypeRejected:    1,
+		schemaRelationDropped: 1,
+		schemaDomainViolation: 1,
+		schemaRangeViolation:  1,
+		schemaRelationUnknown: 1,
+	}, report.Summary)
+	for _, violation := range report.Violations {
+		assert.Equal(t, 1, violation.Pass)
+		if violation.Kind == schemaDomainViolation {
+			assert.Equal(t, "Serveur_Web -protège-> PSSI", violation.Element)
+		}
+	}
+}
+
+func TestEnrichOntologyWithExtractionEnforcesSchema(t *testing.T) {
+	p := newTestPipeline()
+	p.schema = newTestSchema()
+	result := &model.ExtractionResult{
+		Entities: []model.ExtractedEntity{
+			{Name: "Pare-feu", Type: "controle_securite", Description: "Filtrage réseau"},
+			{Name: "Cantine", Type: "Lieu", Description: "Restaurant d'entreprise"},
+		},
+		Relations: []model.ExtractedRelation{
+			{Source: "Pare-feu", Type: "protège", Target: "Cantine", Weight: 2},
+		},
+	}
+
+	p.enrichOntologyWithExtraction(result, false)
+
+	if assert.Len(t, result.Entities, 1) {
+		assert.Equal(t, "Controle_Sécurité", result.Entities[0].Type)
+	}
+	assert.Empty(t, result.Relations)
+	assert.Len(t, p.ontology.Elements, 1)
+	assert.Equal(t, 1, p.schemaReport().Summary[schemaTypeRejected])
+}
+
+func TestLoadEnrichmentTemplateFromFrontMatter(t *testing.T) {
+	p := newTestPipeline()
+	p.config.SchemaEnforcement = true
+	p.enrichmentPromptFile = "../../templates/secnumcloud.ont"
+
+	assert.NoError(t, p.loadEnrichmentTemplate())
+	if assert.NotNil(t, p.schema) {
+		assert.Len(t, p.schema.EntityTypes, 9)
+		assert.Equal(t, []string{"Sécurité_Service", "Zone_Hébergement", "Données_Service"}, p.schema.RelationType("protège").Domain)
+	}
+	assert.Contains(t, p.enrichmentTemplate.PromptTemplate().Template, "1. Sécurité_Service :")
+}
@@ -0,0 +1,160 @@
+// seed_ontology.go
+
+package pipeline
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// seedOntology charge une ontologie importée dans l'ontologie du pipeline et dans la base de données.
+// Un vocabulaire nil ne contraint pas l'enrichissement.
+// Elle retourne l'ontologie au format TSV canonique, utilisée comme résultat précédent de la première passe.
+func (p *Pipeline) seedOntology(seed *model.Ontology, vocabulary *model.Vocabulary) (string, error) {
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
+	now := time.Now()
+	var lines []string
+
+	for _, element := range seed.Elements {
+		element.CreatedAt, element.UpdatedAt = now, now
+		if p.ontology.GetElementByName(element.Name) == nil {
+			p.ontology.AddElement(element)
+		}
+		if p.db != nil {
+			if err := UpsertEntity(p.db, element); err != nil {
+				return "", fmt.Errorf("failed to load seed entity %s: %w", element.Name, err)
+			}
+		}
+		lines = append(lines, formatEntityLine(element))
+		lines = append(lines, formatAliasLines(element)...)
+	}
+
+	for _, relation := range seed.Relations {
+		relation.CreatedAt, relation.UpdatedAt = now, now
+		if !relation.Direction.Valid {
+			relation.Direction = sql.NullString{String: model.DirectionForward, Valid: true}
+		}
+		p.upsertOntologyRelation(relation)
+		lines = append(lines, formatRelationLine(relation))
+	}
+
+	if vocabulary != nil {
+		p.vocabulary = vocabulary
+		log.Info("Seed vocabulary loaded: %d classes, %d properties", len(vocabulary.Classes), len(vocabulary.Properties))
+	}
+	log.Info("Seed ontology loaded: %d elements, %d relations", len(seed.Elements), len(seed.Relations))
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// loadDatabaseOntology reprend les entités et relations d'une base de projet persistante.
+// Elle retourne l'ontologie au format TSV canonique, utilisée comme résultat précédent de la première passe.
+func (p *Pipeline) loadDatabaseOntology(db *sql.DB) (string, error) {
+	entities, err := GetAllEntities(db)
+	if err != nil {
+		return "", err
+	}
+	relations, err := GetAllRelations(db)
+	if err != nil {
+		return "", err
+	}
+
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
+	var lines []string
+	for _, entity := range entities {
+		p.ontology.AddElement(entity)
+		lines = append(lines, formatEntityLine(entity))
+		lines = append(lines, formatAliasLines(entity)...)
+	}
+	for _, relation := range relations {
+		p.ontology.AddRelation(relation)
+		lines = append(lines, formatRelationLine(relation))
+	}
+
+	if len(entities) > 0 || len(relations) > 0 {
+		log.Info("Resuming from project database: %d entities, %d relations", len(entities), len(relations))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// recordSeedProvenance attribue les éléments d'une ontologie importée à son fichier, en passe 0 et hors segment
+func (p *Pipeline) recordSeedProvenance(seed *model.Ontology, path string) {
+	if p.runID == 0 {
+		return
+	}
+	for _, element := range seed.Elements {
+		if err := RecordProvenance(p.db, p.runID, 0, -1, provenanceEntity, element.Name, []string{path}); err != nil {
+			log.Warning("Failed to record provenance of %s: %v", element.Name, err)
+		}
+	}
+	for _, relation := range seed.Relations {
+		if err := RecordProvenance(p.db, p.runID, 0, -1, provenanceRelation, relationProvenanceKey(relation), []string{path}); err != nil {
+			log.Warning("Failed to record provenance of %s: %v", relationProvenanceKey(relation), err)
+		}
+	}
+}
+
+// applySeedVocabulary ajoute au prompt d'enrichissement les classes et propriétés à réutiliser
+func (p *Pipeline) applySeedVocabulary(enrichmentPrompt *prompt.PromptTemplate, values map[string]string) *prompt.PromptTemplate {
+	if p.vocabulary.IsEmpty() {
+		return enrichmentPrompt
+	}
+	values["seed_classes"] = formatVocabularyTerms(p.vocabulary.Classes)
+	values["seed_properties"] = formatVocabularyTerms(p.vocabulary.Properties)
+	return prompt.NewPromptTemplate(enrichmentPrompt.Template + prompt.SeedVocabularyInstructions)
+}
+
+// formatVocabularyTerms écrit un terme par ligne, avec son parent et sa description lorsqu'ils sont connus
+func formatVocabularyTerms(terms []model.VocabularyTerm) string {
+	if len(terms) == 0 {
+		return "(aucune)"
+	}
+	var builder strings.Builder
+	for _, term := range terms {
+		builder.WriteString("- " + term.Name)
+		if term.Parent != "" {
+			builder.WriteString(" (sous-type de " + term.Parent + ")")
+		}
+		if term.Description != "" {
+			builder.WriteString(" : " + term.Description)
+		}
+		builder.WriteString("\n")
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// canonicalClassName remplace un type d'entité par la classe du vocabulaire importé qui lui correspond
+func (p *Pipeline) canonicalClassName(name string) string {
+	if p.vocabulary == nil {
+		return name
+	}
+	return canonicalTermName(p.vocabulary.Classes, name)
+}
+
+// canonicalPropertyName remplace un type de relation par la propriété du vocabulaire importé qui lui correspond
+func (p *Pipeline) canonicalPropertyName(name string) string {
+	if p.vocabulary == nil {
+		return name
+	}
+	return canonicalTermName(p.vocabulary.Properties, name)
+}
+
+// canonicalTermName compare les noms sans tenir compte de la casse, des accents ni des séparateurs
+func canonicalTermName(terms []model.VocabularyTerm, name string) string {
+	key := normalizeElementKey(name)
+	for _, term := range terms {
+		if normalizeElementKey(term.Name) == key {
+			return term.Name
+		}
+	}
+	return name
+}
@@ -0,0 +1,49 @@
+// pipeline/seed_ontology_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/converter"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+const seedTurtle = `@prefix owl: <http://www.w3.org/2002/07/owl#> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix smsi: <http://example.org/smsi#> .
+
+smsi:Rôle a owl:Class .
+smsi:Document a owl:Class ; rdfs:comment "Document du SMSI" .
+smsi:pilote a owl:ObjectProperty .
+smsi:RSSI a owl:NamedIndividual, smsi:Rôle ; rdfs:comment "Responsable de la sécurité" .
+smsi:PSSI a smsi:Document ; rdfs:label "PSSI" .
+smsi:RSSI smsi:pilote smsi:PSSI .
+`
+
+func TestSeedOntologyConstrainsEnrichment(t *testing.T) {
+	p := newTestPipeline()
+	seed, vocabulary, err := converter.ImportOntology([]byte(seedTurtle), converter.ImportFormatTurtle)
+	assert.NoError(t, err)
+
+	result, err := p.seedOntology(seed, vocabulary)
+	assert.NoError(t, err)
+	assert.Contains(t, result, "RSSI\tRôle\tResponsable de la sécurité")
+	assert.Contains(t, result, "RSSI\tpilote:1\tPSSI\t")
+
+	entities, err := GetAllEntities(p.db)
+	assert.NoError(t, err)
+	assert.Len(t, entities, 2)
+
+	values := map[string]string{}
+	enrichmentPrompt := p.applySeedVocabulary(prompt.OntologyEnrichmentPrompt, values)
+	assert.Contains(t, enrichmentPrompt.Template, "{seed_classes}")
+	assert.Equal(t, "- Rôle\n- Document : Document du SMSI", values["seed_classes"])
+	assert.Equal(t, "- pilote", values["seed_properties"])
+
+	p.enrichOntologyWithPositions("Charte\tdocument\tCharte informatique\nRSSI\tPILOTE:2\tCharte\tLe RSSI pilote la charte", false, "", 0)
+
+	assert.Equal(t, "Document", p.ontology.GetElementByName("Charte").Type)
+	assert.NotNil(t, p.ontology.GetRelation("RSSI", "pilote", "Charte"))
+}
@@ -0,0 +1,213 @@
+// segment_errors.go
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chrlesur/Ontology/internal/metadata"
+	"github.com/chrlesur/Ontology/internal/segmenter"
+)
+
+// Politiques appliquées à un segment dont le traitement échoue
+const (
+	SegmentErrorFail  = "fail"   // la passe échoue dès qu'un segment échoue
+	SegmentErrorSkip  = "skip"   // le segment est ignoré et consigné dans le rapport de couverture
+	SegmentErrorRetry = "retry-" // retry-N : le segment est retraité jusqu'à N fois, puis ignoré
+)
+
+// ErrSegmentFailed est retournée lorsqu'un segment échoue avec la politique fail
+var ErrSegmentFailed = errors.New("segment processing failed")
+
+// ErrCoverageBelowThreshold est retournée lorsque la part des segments traités est inférieure à min_coverage
+var ErrCoverageBelowThreshold = errors.New("segment coverage below threshold")
+
+// segmentErrorPolicy est la politique on_segment_error analysée
+type segmentErrorPolicy struct {
+	name     string
+	retries  int
+	failFast bool
+}
+
+// ValidateSegmentErrorPolicy vérifie une politique fail, skip ou retry-N
+func ValidateSegmentErrorPolicy(policy string) error {
+	_, err := parseSegmentErrorPolicy(policy)
+	return err
+}
+
+// parseSegmentErrorPolicy analyse une politique ; une politique vide est skip
+func parseSegmentErrorPolicy(policy string) (segmentErrorPolicy, error) {
+	switch {
+	case policy == "" || policy == SegmentErrorSkip:
+		return segmentErrorPolicy{name: SegmentErrorSkip}, nil
+	case policy == SegmentErrorFail:
+		return segmentErrorPolicy{name: SegmentErrorFail, failFast: true}, nil
+	case strings.HasPrefix(policy, SegmentErrorRetry):
+		retries, err := strconv.Atoi(strings.TrimPrefix(policy, SegmentErrorRetry))
+		if err == nil && retries > 0 {
+			return segmentErrorPolicy{name: policy, retries: retries}, nil
+		}
+	}
+	return segmentErrorPolicy{}, fmt.Errorf("unknown segment error policy %q (supported: %s, %s, %sN with N > 0)", policy, SegmentErrorFail, SegmentErrorSkip, SegmentErrorRetry)
+}
+
+// coverageTracker cumule les segments traités et ceux en échec sur l'ensemble des passes
+type coverageTracker struct {
+	mu        sync.Mutex
+	segments  int
+	processed int
+	failed    []metadata.FailedSegment
+}
+
+// segmentOutcome est le résultat du traitement d'un segment selon la politique
+type segmentOutcome struct {
+	attempts int
+	err      error
+}
+
+// processSegmentWithPolicy traite un segment en le retraitant selon la politique en cas d'échec.
+// Un appel refusé pour dépassement du budget, ou interrompu par l'annulation de l'exécution, n'est pas retraité.
+func (p *Pipeline) processSegmentWithPolicy(policy segmentErrorPolicy, segment []byte, context string, previousResult string, includePositions bool, offset int) (string, segmentOutcome) {
+	var outcome segmentOutcome
+	for outcome.attempts <= policy.retries {
+		outcome.attempts++
+		var result string
+		result, outcome.err = p.processSegment(segment, context, previousResult, includePositions, offset)
+		if outcome.err == nil {
+			return result, outcome
+		}
+		if errors.Is(outcome.err, ErrBudgetExceeded) || p.interrupted() {
+			break
+		}
+		if outcome.attempts <= policy.retries {
+			log.Warning("Segment at offset %d failed (attempt %d/%d), retrying: %v", offset, outcome.attempts, policy.retries+1, outcome.err)
+		}
+	}
+	return "", outcome
+}
+
+// recordCoverage consigne les segments d'une passe et ceux dont le traitement a échoué
+func (p *Pipeline) recordCoverage(segments []segmenter.SegmentInfo, outcomes []segmentOutcome) {
+	p.coverage.mu.Lock()
+	defer p.coverage.mu.Unlock()
+	p.coverage.segments += len(segments)
+	for i, outcome := range outcomes {
+		if outcome.err == nil {
+			p.coverage.processed++
+			continue
+		}
+		p.coverage.failed = append(p.coverage.failed, metadata.FailedSegment{
+			Pass:     p.currentPass,
+			Segment:  i + 1,
+			Start:    segments[i].Start,
+			End:      segments[i].End,
+			Attempts: outcome.attempts,
+			Error:    outcome.err.Error(),
+		})
+	}
+}
+
+// firstSegmentError retourne l'erreur du premier segment en échec de la passe, nil si aucun n'a échoué
+func firstSegmentError(outcomes []segmentOutcome) error {
+	for i, outcome := range outcomes {
+		if outcome.err != nil {
+			return fmt.Errorf("%w: segment %d: %v", ErrSegmentFailed, i+1, outcome.err)
+		}
+	}
+	return nil
+}
+
+// CoverageReport retourne la part des segments traités et la liste des segments en échec de l'exécution
+func (p *Pipeline) CoverageReport() *metadata.CoverageReport {
+	p.coverage.mu.Lock()
+	defer p.coverage.mu.Unlock()
+
+	policy, _ := parseSegmentErrorPolicy(p.config.OnSegmentError)
+	report := &metadata.CoverageReport{
+		Policy:         policy.name,
+		Segments:       p.coverage.segments,
+		Processed:      p.coverage.processed,
+		Coverage:       1,
+		MinCoverage:    p.config.MinCoverage,
+		FailedSegments: append([]metadata.FailedSegment{}, p.coverage.failed...),
+	}
+	if p.coverage.segments > 0 {
+		report.Coverage = float64(p.coverage.processed) / float64(p.coverage.segments)
+	}
+	if p.checkpoint != nil {
+		report.RunID = p.checkpoint.state.RunID
+	}
+	sort.Slice(report.FailedSegments, func(i, j int) bool {
+		a, b := report.FailedSegments[i], report.FailedSegments[j]
+		return a.Pass < b.Pass || (a.Pass == b.Pass && a.Segment < b.Segment)
+	})
+	return report
+}
+
+// checkCoverage consigne les segments en échec et la façon de les relancer, et retourne
+// ErrCoverageBelowThreshold si la couverture de l'exécution est inférieure à min_coverage
+func (p *Pipeline) checkCoverage(output string) error {
+	report := p.CoverageReport()
+	if len(report.FailedSegments) == 0 {
+		return nil
+	}
+	var failed []string
+	for _, segment := range report.FailedSegments {
+		failed = append(failed, fmt.Sprintf("%d/%d", segment.Pass, segment.Segment))
+	}
+	p.logger.Warning("%d of %d segments failed (pass/segment: %s), coverage %.1f%%", len(report.FailedSegments), report.Segments, strings.Join(failed, ", "), report.Coverage*100)
+	p.logger.Warning("Re-run only the failed segments with: ontology enrich %s --output %s --retry-failed", p.inputPath, output)
+	if report.RunID != "" {
+		p.logger.Warning("Or resume the run from its checkpoint, with the passes that followed them, with: ontology enrich %s --output %s --resume %s", p.inputPath, output, report.RunID)
+	}
+	if report.Coverage < p.config.MinCoverage {
+		return fmt.Errorf("%w: %.1f%% of segments processed, minimum %.1f%%", ErrCoverageBelowThreshold, report.Coverage*100, p.config.MinCoverage*100)
+	}
+	return nil
+}
+
+// planRetryFailed lit dans le _meta.json de la sortie les segments en échec de l'exécution précédente,
+// qui sont les seuls retraités, et retourne l'ontologie de départ : la sortie de l'exécution précédente
+func (p *Pipeline) planRetryFailed(output string, existingOntology string) (string, error) {
+	if existingOntology != "" || p.config.Incremental || p.config.Resume != "" {
+		return "", fmt.Errorf("retry_failed cannot be combined with an existing ontology, an incremental run or a resume")
+	}
+	metaFilePath := strings.TrimSuffix(output, filepath.Ext(output)) + "_meta.json"
+	previous, err := metadata.NewGenerator(p.storage).LoadMetadata(metaFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the failed segments of the previous run: %w", err)
+	}
+	if previous.Coverage == nil || len(previous.Coverage.FailedSegments) == 0 {
+		return "", fmt.Errorf("no failed segment to retry in %s", metaFilePath)
+	}
+
+	// Un segment en échec dans plusieurs passes n'est retraité qu'une fois
+	p.retrySegments = make(map[int]metadata.FailedSegment)
+	for _, segment := range previous.Coverage.FailedSegments {
+		p.retrySegments[segment.Segment-1] = segment
+	}
+	p.logger.Info("Retrying %d failed segments of the previous run, starting from %s", len(p.retrySegments), output)
+	return output, nil
+}
+
+// retriedSegments retourne les segments de la passe à traiter avec retry_failed, nil pour les traiter tous.
+// Les segments en échec doivent se retrouver aux mêmes positions, sinon l'entrée ou les réglages ont changé.
+func (p *Pipeline) retriedSegments(segments []segmenter.SegmentInfo) (map[int]bool, error) {
+	if p.retrySegments == nil {
+		return nil, nil
+	}
+	retried := make(map[int]bool, len(p.retrySegments))
+	for i, failed := range p.retrySegments {
+		if i < 0 || i >= len(segments) || segments[i].Start != failed.Start || segments[i].End != failed.End {
+			return nil, fmt.Errorf("failed segment %d of the previous run no longer matches the input (bytes %d-%d): the input or the segmentation settings changed", failed.Segment, failed.Start, failed.End)
+		}
+		retried[i] = true
+	}
+	return retried, nil
+}
@@ -0,0 +1,143 @@
+// pipeline/segment_errors_test.go
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/chrlesur/Ontology/internal/segmenter"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyLLM échoue sur les premiers appels puis répond
+type flakyLLM struct {
+	failures int
+	calls    int
+}
+
+func (f *flakyLLM) Translate(ctx context.Context, prompt string, systemContext string) (string, llm.Usage, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return "", llm.Usage{}, errors.New("status 529: overloaded")
+	}
+	return "PSSI\tDocument\tPolitique de sécurité", llm.Usage{}, nil
+}
+
+func (f *flakyLLM) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, llm.Usage, error) {
+	return f.Translate(ctx, "", "")
+}
+
+func TestParseSegmentErrorPolicy(t *testing.T) {
+	policy, err := parseSegmentErrorPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, segmentErrorPolicy{name: SegmentErrorSkip}, policy)
+
+	policy, err = parseSegmentErrorPolicy("fail")
+	assert.NoError(t, err)
+	assert.True(t, policy.failFast)
+
+	policy, err = parseSegmentErrorPolicy("retry-3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, policy.retries)
+
+	for _, invalid := range []string{"retry", "retry-0", "retry-x", "ignore"} {
+		assert.Error(t, ValidateSegmentErrorPolicy(invalid), invalid)
+	}
+}
+
+func TestProcessSegmentWithPolicyRetries(t *testing.T) {
+	p := newTestPipeline()
+	client := &flakyLLM{failures: 2}
+	p.llm = client
+
+	result, outcome := p.processSegmentWithPolicy(segmentErrorPolicy{retries: 2}, []byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.NoError(t, outcome.err)
+	assert.Equal(t, 3, outcome.attempts)
+	assert.Contains(t, result, "PSSI")
+
+	client = &flakyLLM{failures: 5}
+	p.llm = client
+	_, outcome = p.processSegmentWithPolicy(segmentErrorPolicy{retries: 1}, []byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.Error(t, outcome.err)
+	assert.Equal(t, 2, outcome.attempts)
+	assert.Equal(t, 2, client.calls)
+}
+
+func TestCoverageReportListsFailedSegments(t *testing.T) {
+	p := newTestPipeline()
+	p.config.MinCoverage = 0.8
+	segments := []segmenter.SegmentInfo{{Start: 0, End: 100}, {Start: 100, End: 200}, {Start: 200, End: 300}}
+
+	p.currentPass = 1
+	p.recordCoverage(segments, []segmentOutcome{{attempts: 1}, {attempts: 3, err: errors.New("timeout")}, {attempts: 1}})
+	p.currentPass = 2
+	p.recordCoverage(segments, []segmentOutcome{{attempts: 1}, {attempts: 1}, {attempts: 1}})
+
+	report := p.CoverageReport()
+	assert.Equal(t, SegmentErrorSkip, report.Policy)
+	assert.Equal(t, 6, report.Segments)
+	assert.Equal(t, 5, report.Processed)
+	assert.InDelta(t, 5.0/6, report.Coverage, 1e-9)
+	if assert.Len(t, report.FailedSegments, 1) {
+		failed := report.FailedSegments[0]
+		assert.Equal(t, 1, failed.Pass)
+		assert.Equal(t, 2, failed.Segment)
+		assert.Equal(t, 100, failed.Start)
+		assert.Equal(t, 3, failed.Attempts)
+		assert.Equal(t, "timeout", failed.Error)
+	}
+	assert.NoError(t, p.checkCoverage("out.tsv"))
+
+	p.config.MinCoverage = 0.9
+	assert.ErrorIs(t, p.checkCoverage("out.tsv"), ErrCoverageBelowThreshold)
+}
+
+func TestFirstSegmentError(t *testing.T) {
+	assert.NoError(t, firstSegmentError([]segmentOutcome{{attempts: 1}}))
+	err := firstSegmentError([]segmentOutcome{{attempts: 1}, {attempts: 1, err: errors.New("timeout")}})
+	assert.ErrorIs(t, err, ErrSegmentFailed)
+	assert.ErrorContains(t, err, "segment 2")
+}
+
+func TestRetryFailedSegmentsOfThePreviousRun(t *testing.T) {
+	p, output := newTestCheckpointPipeline(t)
+	p.config.RetryFailed = true
+	metaFile := strings.TrimSuffix(output, ".tsv") + "_meta.json"
+
+	_, err := p.planRetryFailed(output, "")
+	assert.ErrorContains(t, err, "failed to read the failed segments")
+
+	assert.NoError(t, p.storage.Write(metaFile, []byte(`{"coverage": {"segments": 6, "processed": 6, "failed_segments": []}}`)))
+	_, err = p.planRetryFailed(output, "")
+	assert.ErrorContains(t, err, "no failed segment to retry")
+
+	// Le segment 2 a échoué dans les deux passes, le segment 3 dans la première
+	assert.NoError(t, p.storage.Write(metaFile, []byte(`{"coverage": {"segments": 6, "processed": 3, "failed_segments": [
+		{"pass": 1, "segment": 2, "start": 100, "end": 200},
+		{"pass": 1, "segment": 3, "start": 200, "end": 300},
+		{"pass": 2, "segment": 2, "start": 100, "end": 200}]}}`)))
+	_, err = p.planRetryFailed(output, "seed.ttl")
+	assert.Error(t, err)
+	existingOntology, err := p.planRetryFailed(output, "")
+	assert.NoError(t, err)
+	assert.Equal(t, output, existingOntology)
+
+	segments := []segmenter.SegmentInfo{{Start: 0, End: 100}, {Start: 100, End: 200}, {Start: 200, End: 300}}
+	retried, err := p.retriedSegments(segments)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]bool{1: true, 2: true}, retried)
+
+	// Les segments retraités doivent être ceux de l'exécution précédente
+	_, err = p.retriedSegments([]segmenter.SegmentInfo{{Start: 0, End: 150}, {Start: 150, End: 300}})
+	assert.ErrorContains(t, err, "no longer matches the input")
+
+	p.retrySegments = nil
+	retried, err = p.retriedSegments(segments)
+	assert.NoError(t, err)
+	assert.Nil(t, retried)
+}
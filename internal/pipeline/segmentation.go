@@ -9,12 +9,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/chrlesur/Ontology/internal/i18n"
 	"github.com/chrlesur/Ontology/internal/model"
 	"github.com/chrlesur/Ontology/internal/parser"
-	"github.com/chrlesur/Ontology/internal/prompt"
 	"github.com/chrlesur/Ontology/internal/segmenter"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -25,27 +25,9 @@ import (
 func (p *Pipeline) processSinglePass(input string, previousResult string, includePositions bool) (string, []byte, error) {
 	p.logger.Debug("Démarrage du traitement d'une passe unique pour l'entrée : %s", input)
 
-	isDir, err := p.storage.IsDirectory(input)
-	if err != nil {
-		p.logger.Error("Échec de la vérification si l'entrée est un répertoire : %v", err)
-		return "", nil, fmt.Errorf("échec de la vérification si l'entrée est un répertoire : %w", err)
-	}
-
-	var content []byte
-	if isDir {
-		content, err = p.readDirectory(input)
-	} else {
-		content, err = p.readFile(input)
-	}
-
+	content, err := p.readInput(input)
 	if err != nil {
-		p.logger.Error("Échec de la lecture de l'entrée : %v", err)
-		return "", nil, fmt.Errorf("échec de la lecture de l'entrée : %w", err)
-	}
-
-	if len(content) == 0 {
-		p.logger.Error("Aucun contenu trouvé dans l'entrée : %s", input)
-		return "", nil, fmt.Errorf("aucun contenu trouvé dans l'entrée")
+		return "", nil, err
 	}
 
     p.fullContent = content
@@ -79,9 +61,18 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 	}
 
 	p.segmentOffsets = offsets
+	p.segmentSpans = make([]fileSpan, len(segments))
+	for i, segment := range segments {
+		p.segmentSpans[i] = fileSpan{Start: segment.Start, End: segment.End}
+	}
 
 	p.logger.Info("Nombre de segments : %d", len(segments))
 
+	// À la reprise, le contenu doit être celui qui a été segmenté lors de l'exécution interrompue
+	if err := p.checkpoint.checkContent(content, len(segments)); err != nil {
+		return "", nil, err
+	}
+
 	if p.progressCallback != nil {
 		p.progressCallback(ProgressInfo{
 			CurrentStep:   "Segmentation",
@@ -89,7 +80,18 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 		})
 	}
 
+	policy, err := parseSegmentErrorPolicy(p.config.OnSegmentError)
+	if err != nil {
+		return "", nil, err
+	}
+	retried, err := p.retriedSegments(segments)
+	if err != nil {
+		return "", nil, err
+	}
+
 	results := make([]string, len(segments))
+	outcomes := make([]segmentOutcome, len(segments))
+	var aborted atomic.Bool // avec la politique fail, les segments restants ne sont plus envoyés au LLM
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, p.maxConcurrentThreads)
 
@@ -101,6 +103,11 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 			sem <- struct{}{}
 			defer func() { <-sem }()
 
+			// Avec retry_failed, les segments réussis lors de l'exécution précédente sont déjà dans l'ontologie de départ
+			if retried != nil && !retried[i] {
+				return
+			}
+
 			segmentTokens := len(tke.Encode(string(seg.Content), nil, nil))
 			p.logger.Debug("Traitement du segment %d/%d, Début : %d, Fin : %d, Longueur : %d octets, Tokens : %d",
 				i+1, len(segments), seg.Start, seg.End, len(seg.Content), segmentTokens)
@@ -112,10 +119,27 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 			})
 			p.logger.Debug("Contexte pour le segment %d/%d, Longueur : %d octets", i+1, len(segments), len(context))
 
-            result, err := p.processSegment(seg.Content, context, previousResult, includePositions, seg.Start)
-			if err != nil {
-				p.logger.Error(i18n.GetMessage("SegmentProcessingError"), i+1, err)
-				return
+			// Un segment traité avant l'interruption est rejoué depuis son checkpoint, sans appel au LLM
+			result, resumed := p.checkpoint.segmentResult(p.currentPass, i)
+			if resumed {
+				p.logger.Info("Segment %d repris depuis le checkpoint", i+1)
+				result = p.enrichOntologyWithPositions(result, includePositions, string(seg.Content), seg.Start)
+			} else {
+				if policy.failFast && aborted.Load() {
+					outcomes[i] = segmentOutcome{err: fmt.Errorf("not processed after the failure of another segment")}
+					return
+				}
+				if p.interrupted() {
+					outcomes[i] = segmentOutcome{err: p.runContext().Err()}
+					return
+				}
+				result, outcomes[i] = p.processSegmentWithPolicy(policy, seg.Content, context, previousResult, includePositions, seg.Start)
+				if outcomes[i].err != nil {
+					p.logger.Error(i18n.GetMessage("SegmentProcessingError"), i+1, outcomes[i].err)
+					aborted.Store(true)
+					return
+				}
+				p.checkpoint.saveSegment(p.currentPass, i, result)
 			}
 			resultTokens := len(tke.Encode(result, nil, nil))
 			results[i] = result
@@ -130,12 +154,31 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 		}(i, segment)
 	}
 	wg.Wait()
+	p.recordCoverage(segments, outcomes)
+
+	// Interrompue, la passe fusionne dans la base les résultats déjà obtenus, sans appel au LLM
+	if err := p.runContext().Err(); err != nil {
+		p.logger.Warning("Pass %d interrupted, merging the results of the processed segments", p.currentPass)
+		mergedResult, mergeErr := p.mergeResultsWithDB(previousResult, results)
+		if mergeErr != nil {
+			return "", nil, fmt.Errorf("failed to merge the partial results: %w", mergeErr)
+		}
+		return mergedResult, content, err
+	}
 
-	// Utilisation de la nouvelle fonction mergeResultsWithDB
-	mergedResult, err := p.mergeResultsWithDB(previousResult, results)
+	// Un appel refusé pour dépassement du budget interrompt l'exécution au lieu d'ignorer le segment
+	if err := p.budgetExceeded(); err != nil {
+		return "", nil, err
+	}
+	if policy.failFast {
+		if err := firstSegmentError(outcomes); err != nil {
+			return "", nil, err
+		}
+	}
+
+	mergedResult, err := p.mergePass(previousResult, results, outcomes)
 	if err != nil {
-		p.logger.Error("Échec de la fusion des résultats : %v", err)
-		return "", nil, fmt.Errorf("échec de la fusion des résultats : %w", err)
+		return "", nil, err
 	}
 
 	mergedResultTokens := len(tke.Encode(mergedResult, nil, nil))
@@ -144,41 +187,68 @@ func (p *Pipeline) processSinglePass(input string, previousResult string, includ
 	return mergedResult, content, nil
 }
 
-// mergeResults fusionne les résultats de tous les segments
-func (p *Pipeline) mergeResults(previousResult string, newResults []string) (string, error) {
-	log.Info("Starting mergeResults. Previous result length: %d, Number of new results: %d", len(previousResult), len(newResults))
+// mergePass fusionne les résultats des segments de la passe en cours et enregistre le résultat fusionné
+// dans le checkpoint. Une passe terminée avant l'interruption reprend son résultat fusionné au lieu de
+// refaire la fusion, puis passe par les mêmes étapes qu'après une fusion : provenance des segments,
+// résolution des entités et mise à jour de l'ontologie exportée.
+func (p *Pipeline) mergePass(previousResult string, results []string, outcomes []segmentOutcome) (string, error) {
+	if mergedResult, resumed := p.checkpoint.passResult(p.currentPass); resumed {
+		p.logger.Info("Résultat fusionné de la passe %d repris depuis le checkpoint", p.currentPass)
+		if err := p.insertResults(p.db, mergedResult); err != nil {
+			p.logger.Error("Échec de l'insertion du résultat repris : %v", err)
+			return "", err
+		}
+		for i, result := range results {
+			if err := p.recordResultProvenance(result, i); err != nil {
+				p.logger.Warning("Failed to record provenance of result %d: %v", i, err)
+			}
+		}
+		return p.resolveMergedResults()
+	}
 
-	// Combiner tous les nouveaux résultats
-	combinedNewResults := strings.Join(newResults, "\n")
-	log.Debug("Combined new results length: %d", len(combinedNewResults))
-	log.Debug("--- Combined Results ---")
-	log.Debug("%s", combinedNewResults)
-	log.Debug("--- ---")
+	// Fusion des résultats des segments, dans la base ou par le LLM selon la stratégie configurée
+	mergedResult, err := p.mergeSegmentResults(previousResult, results)
+	if err != nil {
+		p.logger.Error("Échec de la fusion des résultats : %v", err)
+		return "", fmt.Errorf("échec de la fusion des résultats : %w", err)
+	}
+	failedSegments := 0
+	for _, outcome := range outcomes {
+		if outcome.err != nil {
+			failedSegments++
+		}
+	}
+	p.checkpoint.completePass(p.currentPass, mergedResult, failedSegments)
+	return mergedResult, nil
+}
 
-	// Préparer les valeurs pour le prompt de fusion
-	mergeValues := map[string]string{
-		"previous_ontology": previousResult,
-		"new_ontology":      combinedNewResults,
-		"additional_prompt": p.ontologyMergePrompt,
+// readInput lit le contenu d'un fichier ou d'un répertoire et la position de chaque fichier source dans ce contenu
+func (p *Pipeline) readInput(input string) ([]byte, error) {
+	isDir, err := p.storage.IsDirectory(input)
+	if err != nil {
+		p.logger.Error("Échec de la vérification si l'entrée est un répertoire : %v", err)
+		return nil, fmt.Errorf("échec de la vérification si l'entrée est un répertoire : %w", err)
 	}
 
-	// Utiliser le LLM pour fusionner les résultats
-	log.Debug("Calling LLM with OntologyMergePrompt")
+	var content []byte
+	if isDir {
+		content, err = p.readDirectory(input)
+	} else {
+		var locations []parser.Location
+		content, locations, err = p.readFile(input)
+		p.fileSpans = []fileSpan{{Path: input, Start: 0, End: len(content), Locations: locations}}
+	}
 
-	mergedResult, err := p.llm.ProcessWithPrompt(prompt.OntologyMergePrompt, mergeValues)
 	if err != nil {
-		log.Error("Ontology merge failed: %v", err)
-		return "", fmt.Errorf("ontology merge failed: %w", err)
+		p.logger.Error("Échec de la lecture de l'entrée : %v", err)
+		return nil, fmt.Errorf("échec de la lecture de l'entrée : %w", err)
 	}
 
-	// Normaliser le résultat fusionné
-	normalizedMergedResult := normalizeTSV(mergedResult)
-	log.Debug("--- Normalized Results ---")
-	log.Debug("%s", normalizedMergedResult)
-	log.Debug("--- ---")
-
-	log.Debug("Merged result length: %d", len(normalizedMergedResult))
-	return normalizedMergedResult, nil
+	if len(content) == 0 {
+		p.logger.Error("Aucun contenu trouvé dans l'entrée : %s", input)
+		return nil, fmt.Errorf("aucun contenu trouvé dans l'entrée")
+	}
+	return content, nil
 }
 
 func (p *Pipeline) processMetadata(metadata map[string]string) {
@@ -198,14 +268,20 @@ func (p *Pipeline) readDirectory(dirPath string) ([]byte, error) {
 	}
 
 	var allContent []byte
+	p.fileSpans = nil
 	for _, filePath := range files {
+		if p.isIncrementalSkip(filePath) {
+			p.logger.Debug("Skipping unchanged file: %s", filePath)
+			continue
+		}
 		// Utiliser le chemin tel quel, sans le joindre à dirPath
-		content, err := p.readFile(filePath)
+		content, locations, err := p.readFile(filePath)
 		if err != nil {
 			p.logger.Warning("Failed to read file %s: %v", filePath, err)
 			continue
 		}
 
+		p.fileSpans = append(p.fileSpans, fileSpan{Path: filePath, Start: len(allContent), End: len(allContent) + len(content), Locations: locations})
 		allContent = append(allContent, content...)
 		allContent = append(allContent, '\n') // Add separator between files
 	}
@@ -217,20 +293,25 @@ func (p *Pipeline) readDirectory(dirPath string) ([]byte, error) {
 	return allContent, nil
 }
 
-func (p *Pipeline) readFile(filePath string) ([]byte, error) {
+// readFile extrait le texte d'un fichier ainsi que, si le parser la fournit, la carte de ses pages ou paragraphes
+func (p *Pipeline) readFile(filePath string) ([]byte, []parser.Location, error) {
 	ext := filepath.Ext(filePath)
-	parser, err := parser.GetParser(ext)
+	fileParser, err := parser.GetParser(ext)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get parser for file %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("failed to get parser for file %s: %w", filePath, err)
 	}
 
 	reader, err := p.storage.GetReader(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get reader for file %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("failed to get reader for file %s: %w", filePath, err)
 	}
 	defer reader.Close()
 
-	return parser.Parse(reader)
+	content, err := fileParser.Parse(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return content, parser.GetLocations(fileParser), nil
 }
 
 // mergeResultsWithDB fusionne les résultats précédents avec les nouveaux résultats en utilisant une base de données temporaire.
@@ -248,6 +329,24 @@ func (p *Pipeline) mergeResultsWithDB(previousResult string, newResults []string
 			p.logger.Error("Failed to insert new result %d: %v", i, err)
 			return "", err
 		}
+		if err := p.recordResultProvenance(result, i); err != nil {
+			p.logger.Warning("Failed to record provenance of result %d: %v", i, err)
+		}
+	}
+
+	return p.resolveMergedResults()
+}
+
+// resolveMergedResults applique la résolution locale des entités aux résultats insérés dans la base
+// et retourne l'ontologie fusionnée
+func (p *Pipeline) resolveMergedResults() (string, error) {
+	if p.config.EntityResolution {
+		records, err := p.resolveEntities()
+		if err != nil {
+			p.logger.Error("Failed to resolve entities: %v", err)
+			return "", err
+		}
+		p.logger.Info("Entity resolution merged %d entities", len(records))
 	}
 
 	mergedResult, err := p.getMergedResults(p.db)
@@ -268,6 +367,19 @@ func (p *Pipeline) mergeResultsWithDB(previousResult string, newResults []string
 // insertResults insère les résultats dans la base de données.
 func (p *Pipeline) insertResults(db *sql.DB, result string) error {
 	lines := strings.Split(result, "\n")
+
+	// Les alias sont enregistrés en premier afin que les mentions des entités les prennent en compte
+	for _, line := range lines {
+		parts := strings.Split(strings.TrimSpace(line), "\t")
+		if !isAliasLine(parts) {
+			continue
+		}
+		if err := UpsertAliases(db, strings.TrimSpace(parts[2]), []string{strings.TrimSpace(parts[0])}); err != nil {
+			p.logger.Error("Failed to upsert alias: %v", err)
+			return err
+		}
+	}
+
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if line == "" {
@@ -276,6 +388,9 @@ func (p *Pipeline) insertResults(db *sql.DB, result string) error {
 		parts := strings.Split(line, "\t")
 		p.logger.Debug("Processing line with %d parts: %v", len(parts), parts)
 
+		if isAliasLine(parts) {
+			continue // Déjà enregistré
+		}
 		if len(parts) >= 3 {
 			// Vérifier si c'est une relation ou une entité
 			if strings.Contains(parts[1], ":") {
@@ -324,11 +439,17 @@ func (p *Pipeline) insertResults(db *sql.DB, result string) error {
 				} else if len(parts) == 3 {
 					description = parts[2]
 				}
+				name := strings.TrimSpace(parts[0])
+				aliases, err := GetAliases(db, name)
+				if err != nil {
+					p.logger.Error("Failed to get aliases: %v", err)
+					return err
+				}
 				entity := &model.OntologyElement{
-					Name:        strings.TrimSpace(parts[0]),
+					Name:        name,
 					Type:        strings.TrimSpace(parts[1]),
 					Description: strings.TrimSpace(description),
-					Positions:   p.findPositions(strings.TrimSpace(parts[0]), string(p.fullContent)),
+					Mentions:    p.findMentions(name, aliases...),
 					CreatedAt:   time.Now(),
 					UpdatedAt:   time.Now(),
 				}
@@ -346,6 +467,62 @@ func (p *Pipeline) insertResults(db *sql.DB, result string) error {
 	return nil
 }
 
+// recordResultProvenance enregistre l'exécution, la passe, le segment et les fichiers sources des lignes d'un résultat
+func (p *Pipeline) recordResultProvenance(result string, segment int) error {
+	if p.runID == 0 {
+		return nil
+	}
+	sourceFiles := p.segmentSourceFiles(segment)
+	for _, line := range strings.Split(result, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "\t")
+		if len(parts) < 3 || isAliasLine(parts) {
+			continue
+		}
+		kind, item := provenanceEntity, strings.TrimSpace(parts[0])
+		if strings.Contains(parts[1], ":") {
+			relationType, _, _ := parseRelationType(parts[1])
+			kind = provenanceRelation
+			item = relationProvenanceKey(&model.Relation{
+				Source: strings.TrimSpace(parts[0]),
+				Type:   relationType,
+				Target: strings.TrimSpace(parts[2]),
+			})
+		}
+		if err := RecordProvenance(p.db, p.runID, p.currentPass, segment, kind, item, sourceFiles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileSpan situe un fichier source, ou un segment, dans le contenu concaténé d'une passe
+type fileSpan struct {
+	Path      string
+	Start     int
+	End       int
+	Locations []parser.Location // pages ou paragraphes du fichier, offsets relatifs à Start
+	Language  string            // langue du fichier, utilisée pour l'index inversé
+}
+
+// segmentSourceFiles retourne les fichiers sources couverts par un segment.
+// Sans découpage connu, tous les fichiers sources de l'exécution sont retenus.
+func (p *Pipeline) segmentSourceFiles(segment int) []string {
+	if segment < 0 || segment >= len(p.segmentSpans) || len(p.fileSpans) == 0 {
+		return p.sourceFiles
+	}
+	span := p.segmentSpans[segment]
+	var files []string
+	for _, file := range p.fileSpans {
+		if file.Start < span.End && span.Start < file.End {
+			files = append(files, file.Path)
+		}
+	}
+	if len(files) == 0 {
+		return p.sourceFiles
+	}
+	return files
+}
+
 // getMergedResults récupère les résultats fusionnés de la base de données.
 func (p *Pipeline) getMergedResults(db *sql.DB) (string, error) {
 	p.logger.Debug("Starting getMergedResults")
@@ -368,6 +545,9 @@ func (p *Pipeline) getMergedResults(db *sql.DB) (string, error) {
 			positionsStr)
 		result.WriteString(line)
 		p.logger.Debug("Added entity to result: %s", strings.TrimSpace(line))
+		for _, aliasLine := range formatAliasLines(entity) {
+			result.WriteString(aliasLine + "\n")
+		}
 	}
 
 	// Récupérer et écrire les relations
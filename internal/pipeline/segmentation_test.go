@@ -9,6 +9,7 @@ import (
 
 	"github.com/chrlesur/Ontology/internal/config"
 	"github.com/chrlesur/Ontology/internal/logger"
+	"github.com/chrlesur/Ontology/internal/model"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -22,9 +23,16 @@ func newTestPipeline() *Pipeline {
 		DefaultModel: "test-model",
 	}
 
+	db, err := initDB("")
+	if err != nil {
+		panic(err)
+	}
+
 	return &Pipeline{
-		logger: logger.GetLogger(),
-		config: cfg,
+		logger:   logger.GetLogger(),
+		config:   cfg,
+		db:       db,
+		ontology: model.NewOntology(),
 		// Ajoutez d'autres champs nécessaires pour le test
 	}
 }
@@ -0,0 +1,202 @@
+// structured_output.go
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/model"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// extractionSchemaName est le nom sous lequel le schéma d'extraction est déclaré auprès des LLM
+const extractionSchemaName = "ontology_extraction"
+
+var trailingCommaRegexp = regexp.MustCompile(`,\s*([}\]])`)
+
+// processSegmentStructured traite un segment en demandant au LLM une réponse JSON conforme au schéma d'extraction
+func (p *Pipeline) processSegmentStructured(enrichmentPrompt *prompt.PromptTemplate, values map[string]string, includePositions bool, offset int) (string, error) {
+	structuredPrompt := structuredPromptTemplate(enrichmentPrompt)
+
+	raw, err := p.requestStructuredOutput(offset, structuredPrompt, values)
+	if err != nil {
+		log.Error("Structured ontology enrichment failed: %v", err)
+		return "", fmt.Errorf("structured ontology enrichment failed: %w", err)
+	}
+
+	result, validationErr := parseExtractionResult(raw)
+	for attempt := 1; validationErr != nil && attempt <= p.config.StructuredOutputRetries; attempt++ {
+		log.Warning("Invalid structured output (repair attempt %d/%d): %v", attempt, p.config.StructuredOutputRetries, validationErr)
+		raw, err = p.repairStructuredOutput(offset, raw, validationErr)
+		if err != nil {
+			log.Error("Structured output repair failed: %v", err)
+			return "", fmt.Errorf("structured output repair failed: %w", err)
+		}
+		result, validationErr = parseExtractionResult(raw)
+	}
+	if validationErr != nil {
+		log.Error("Structured output still invalid after %d repair attempts: %v", p.config.StructuredOutputRetries, validationErr)
+		return "", fmt.Errorf("structured output still invalid after %d repair attempts: %w", p.config.StructuredOutputRetries, validationErr)
+	}
+
+	result.Normalize()
+	log.Info("Structured result: %d entities, %d relations", len(result.Entities), len(result.Relations))
+
+	p.enrichOntologyWithExtraction(result, includePositions)
+
+	return result.ToTSV(), nil
+}
+
+// structuredPromptTemplate ajoute au prompt d'enrichissement les instructions de réponse JSON
+func structuredPromptTemplate(enrichmentPrompt *prompt.PromptTemplate) *prompt.PromptTemplate {
+	return prompt.NewPromptTemplate(enrichmentPrompt.Template + prompt.StructuredOutputInstructions)
+}
+
+// requestStructuredOutput utilise le mode JSON natif du client s'il existe, sinon le prompt seul.
+// Seules les réponses conformes au contrat sont mises en cache, pour ne pas rejouer une réponse invalide.
+func (p *Pipeline) requestStructuredOutput(offset int, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, error) {
+	if structuredClient, ok := p.llm.(llm.StructuredClient); ok {
+		log.Debug("Calling LLM with native JSON schema support")
+		return p.callLLM(offset, promptTemplate, values, func(ctx context.Context) (string, llm.Usage, error) {
+			ctx = llm.WithValidator(ctx, validateExtractionResponse)
+			return structuredClient.ProcessWithPromptJSON(ctx, promptTemplate, values, extractionSchemaName, model.ExtractionSchema)
+		})
+	}
+	log.Debug("LLM client has no native JSON schema support, relying on prompt instructions")
+	return p.callLLM(offset, promptTemplate, values, func(ctx context.Context) (string, llm.Usage, error) {
+		return p.llm.ProcessWithPrompt(llm.WithValidator(ctx, validateExtractionResponse), promptTemplate, values)
+	})
+}
+
+// validateExtractionResponse vérifie qu'une réponse brute du LLM respecte le contrat d'extraction
+func validateExtractionResponse(raw string) error {
+	_, err := parseExtractionResult(raw)
+	return err
+}
+
+// repairStructuredOutput redemande au LLM de corriger une réponse invalide
+func (p *Pipeline) repairStructuredOutput(offset int, previousResponse string, validationErr error) (string, error) {
+	schemaJSON, err := json.MarshalIndent(model.ExtractionSchema, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal extraction schema: %w", err)
+	}
+
+	repairValues := map[string]string{
+		"previous_response": previousResponse,
+		"error":             validationErr.Error(),
+		"schema":            string(schemaJSON),
+	}
+	return p.requestStructuredOutput(offset, prompt.StructuredOutputRepairPrompt, repairValues)
+}
+
+// parseExtractionResult répare les défauts courants d'une réponse JSON puis la valide contre le contrat
+func parseExtractionResult(raw string) (*model.ExtractionResult, error) {
+	cleaned := repairJSON(raw)
+	if cleaned == "" {
+		return nil, fmt.Errorf("no JSON object found in response")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(cleaned)))
+	decoder.DisallowUnknownFields()
+
+	var result model.ExtractionResult
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+
+	if err := result.Validate(); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// repairJSON retire les blocs de code markdown, les commentaires autour de l'objet et les virgules finales
+func repairJSON(raw string) string {
+	text := strings.TrimSpace(raw)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start == -1 || end <= start {
+		return ""
+	}
+	text = text[start : end+1]
+
+	return trailingCommaRegexp.ReplaceAllString(text, "$1")
+}
+
+// enrichOntologyWithExtraction ajoute les entités et relations d'un résultat structuré à l'ontologie
+func (p *Pipeline) enrichOntologyWithExtraction(result *model.ExtractionResult, includePositions bool) {
+	log.Debug("Starting enrichOntologyWithExtraction")
+
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
+	rejected := make(map[string]bool) // entités dont le type est rejeté par le schéma
+	entities := result.Entities[:0]
+	for i := range result.Entities {
+		entity := &result.Entities[i]
+		elementType, ok := p.enforceEntityType(entity.Name, entity.Type)
+		if !ok {
+			rejected[normalizeElementKey(entity.Name)] = true
+			continue
+		}
+		entity.Type = elementType
+		element := p.upsertOntologyElement(entity.Name, entity.Type, entity.Description, includePositions)
+		for _, alias := range entity.Aliases {
+			p.addElementAlias(element, alias)
+		}
+		// Le résultat TSV reprend tous les alias connus de l'élément, sous son nom canonique
+		entity.Name, entity.Aliases = element.Name, element.Aliases
+		if includePositions && len(element.Aliases) > 0 {
+			element.Mentions = p.findMentions(element.Name, element.Aliases...)
+		}
+		entities = append(entities, *entity)
+	}
+	result.Entities = entities
+
+	now := time.Now()
+	relations := result.Relations[:0]
+	for i := range result.Relations {
+		rel := &result.Relations[i]
+		if p.dropRejectedRelation(rel.Source, rel.Type, rel.Target, rejected) {
+			continue
+		}
+		if source := p.resolveElement(rel.Source); source != nil {
+			p.addSurfaceForm(source, rel.Source)
+			rel.Source = source.Name
+		}
+		if target := p.resolveElement(rel.Target); target != nil {
+			p.addSurfaceForm(target, rel.Target)
+			rel.Target = target.Name
+		}
+		relation := &model.Relation{
+			Source:      rel.Source,
+			Type:        rel.Type,
+			Target:      rel.Target,
+			Description: rel.Description,
+			Weight:      rel.Weight,
+			Direction:   sql.NullString{String: rel.Direction, Valid: rel.Direction != ""},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+		p.checkRelationSchema(relation)
+		p.upsertOntologyRelation(relation)
+		rel.Type = relation.Type
+		relations = append(relations, *rel)
+	}
+	result.Relations = relations
+
+	log.Debug("Final ontology state - Elements: %d, Relations: %d",
+		len(p.ontology.Elements), len(p.ontology.Relations))
+}
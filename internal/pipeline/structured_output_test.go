@@ -0,0 +1,86 @@
+// pipeline/structured_output_test.go
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLLM renvoie les réponses prédéfinies dans l'ordre des appels
+type fakeLLM struct {
+	responses []string
+	calls     int
+	prompts   []string  // prompts rendus par ProcessWithPrompt
+	usage     llm.Usage // consommation retournée à chaque appel
+}
+
+func (f *fakeLLM) next() (string, llm.Usage, error) {
+	response := f.responses[min(f.calls, len(f.responses)-1)]
+	f.calls++
+	return response, f.usage, nil
+}
+
+func (f *fakeLLM) Translate(ctx context.Context, prompt string, systemContext string) (string, llm.Usage, error) {
+	return f.next()
+}
+
+func (f *fakeLLM) ProcessWithPrompt(ctx context.Context, promptTemplate *prompt.PromptTemplate, values map[string]string) (string, llm.Usage, error) {
+	if rendered, err := promptTemplate.Format(values); err == nil {
+		f.prompts = append(f.prompts, rendered)
+	}
+	return f.next()
+}
+
+func TestParseExtractionResultRepairsCommonDefects(t *testing.T) {
+	raw := "Voici le résultat :\n```json\n{\"entities\": [{\"name\": \"Conseil d'État\", \"type\": \"Institution\", \"description\": \"Juridiction  administrative\"},], \"relations\": []}\n```"
+
+	result, err := parseExtractionResult(raw)
+	assert.NoError(t, err)
+	assert.Len(t, result.Entities, 1)
+
+	result.Normalize()
+	assert.Equal(t, "Conseil_d'État", result.Entities[0].Name)
+	assert.Equal(t, "Conseil_d'État\tInstitution\tJuridiction administrative\n", result.ToTSV())
+}
+
+func TestParseExtractionResultRejectsInvalidPayloads(t *testing.T) {
+	_, err := parseExtractionResult("pas de JSON")
+	assert.Error(t, err)
+
+	_, err = parseExtractionResult(`{"entities": [{"name": "", "type": "Concept", "description": ""}], "relations": []}`)
+	assert.ErrorContains(t, err, "entities[0].name is empty")
+
+	_, err = parseExtractionResult(`{"entities": [], "relations": [{"source": "A", "type": "inclut", "target": "B", "description": "", "weight": 1, "direction": "sideways"}]}`)
+	assert.ErrorContains(t, err, "direction")
+
+	_, err = parseExtractionResult(`{"entities": [], "relations": [{"source": "A", "type": "inclut", "target": "B", "description": "", "weight": 0, "direction": "forward"}]}`)
+	assert.ErrorContains(t, err, "relations[0].weight must be positive")
+
+	_, err = parseExtractionResult(`{"entities": [], "relations": [], "comment": "inattendu"}`)
+	assert.Error(t, err)
+}
+
+func TestProcessSegmentStructuredReasksOnInvalidPayload(t *testing.T) {
+	p := newTestPipeline()
+	p.config.StructuredOutputRetries = 2
+	client := &fakeLLM{responses: []string{
+		`{"entities": [{"name": "PSSI"}], "relations": []`,
+		`{"entities": [{"name": "PSSI", "type": "Document", "description": "Politique de sécurité"}, {"name": "RSSI", "type": "Role", "description": "Responsable"}],
+		  "relations": [{"source": "RSSI", "type": "rédige", "target": "PSSI", "description": "", "weight": 3, "direction": "forward"}]}`,
+	}}
+	p.llm = client
+
+	result, err := p.processSegmentStructured(prompt.OntologyEnrichmentPrompt, map[string]string{"text": "texte"}, false, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.calls)
+	assert.Equal(t, "PSSI\tDocument\tPolitique de sécurité\nRSSI\tRole\tResponsable\nRSSI\trédige:3\tPSSI\t\n", result)
+	assert.Len(t, p.ontology.Elements, 2)
+	assert.Len(t, p.ontology.Relations, 1)
+	assert.Equal(t, "forward", p.ontology.Relations[0].Direction.String)
+}
@@ -0,0 +1,169 @@
+// two_stage.go
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// Modes de traitement des segments
+const (
+	ModeSingle   = "single"    // un seul appel au LLM avec le prompt d'enrichissement
+	ModeTwoStage = "two-stage" // extraction des entités, puis des relations entre ces entités
+)
+
+// Modes retourne les modes de traitement des segments reconnus
+func Modes() []string {
+	return []string{ModeSingle, ModeTwoStage}
+}
+
+// processSegmentTwoStage traite un segment en deux appels plus ciblés, mieux suivis par les petits modèles :
+// les entités sont d'abord extraites, puis les relations sont demandées entre les seules entités retenues
+func (p *Pipeline) processSegmentTwoStage(segment []byte, segmentContext string, includePositions bool, offset int) (string, error) {
+	entityPrompt, entityValues := p.entityExtractionRequest(segment, segmentContext)
+
+	log.Debug("Calling LLM with EntityExtractionPrompt")
+	entityResult, err := p.callLLM(offset, entityPrompt, entityValues, func(ctx context.Context) (string, llm.Usage, error) {
+		return p.llm.ProcessWithPrompt(ctx, entityPrompt, entityValues)
+	})
+	if err != nil {
+		log.Error("Entity extraction failed: %v", err)
+		return "", fmt.Errorf("entity extraction failed: %w", err)
+	}
+	entityLines := p.enrichOntologyWithPositions(keepEntityLines(normalizeTSV(entityResult)), includePositions, string(segment), offset)
+
+	entities := extractedEntityList(entityLines)
+	if len(entities) == 0 {
+		log.Info("No entities extracted from segment at offset %d, relation extraction skipped", offset)
+		return entityLines, nil
+	}
+
+	relationPrompt, relationValues := p.relationExtractionRequest(segment, entities)
+
+	log.Debug("Calling LLM with RelationExtractionPrompt for %d entities", len(entities))
+	relationResult, err := p.callLLM(offset, relationPrompt, relationValues, func(ctx context.Context) (string, llm.Usage, error) {
+		return p.llm.ProcessWithPrompt(ctx, relationPrompt, relationValues)
+	})
+	if err != nil {
+		log.Error("Relation extraction failed: %v", err)
+		return "", fmt.Errorf("relation extraction failed: %w", err)
+	}
+	relationLines := p.enrichOntologyWithPositions(p.keepRelationsBetween(normalizeTSV(relationResult), entities), includePositions, string(segment), offset)
+
+	log.Info("Two-stage result: %d entities, relations length %d", len(entities), len(relationLines))
+	return strings.TrimSpace(entityLines + "\n" + relationLines), nil
+}
+
+// entityExtractionRequest assemble le prompt d'extraction des entités d'un segment
+func (p *Pipeline) entityExtractionRequest(segment []byte, context string) (*prompt.PromptTemplate, map[string]string) {
+	entityValues := map[string]string{
+		"text":              string(segment),
+		"context":           context,
+		"additional_prompt": p.entityExtractionPrompt,
+	}
+	if p.schema != nil {
+		entityValues["entity_types"] = p.schemaEntityTypeList()
+	}
+	return p.applySeedVocabulary(prompt.EntityExtractionPrompt, entityValues), entityValues
+}
+
+// relationExtractionRequest assemble le prompt d'extraction des relations entre les entités extraites du segment
+func (p *Pipeline) relationExtractionRequest(segment []byte, entities []string) (*prompt.PromptTemplate, map[string]string) {
+	relationValues := map[string]string{
+		"text":              string(segment),
+		"entities":          strings.Join(entities, "\n"),
+		"additional_prompt": p.relationExtractionPrompt,
+	}
+	if p.schema != nil {
+		relationValues["relation_types"] = p.schemaRelationTypeList()
+	}
+	return p.applySeedVocabulary(prompt.RelationExtractionPrompt, relationValues), relationValues
+}
+
+// keepEntityLines ne garde de la réponse de l'extraction d'entités que les lignes à trois colonnes,
+// une ligne plus longue ne pouvant être prise pour une relation
+func keepEntityLines(result string) string {
+	var lines []string
+	for _, line := range strings.Split(result, "\n") {
+		parts := splitTSVLine(line)
+		if len(parts) < 3 {
+			continue
+		}
+		if len(parts) > 3 {
+			parts = []string{parts[0], parts[1], strings.Join(parts[2:], " ")}
+		}
+		lines = append(lines, strings.Join(parts, "\t"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// extractedEntityList retourne les entités du résultat canonique de l'extraction, au format Nom\tType
+func extractedEntityList(canonicalResult string) []string {
+	var entities []string
+	for _, line := range strings.Split(canonicalResult, "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 3 || isAliasLine(parts) {
+			continue
+		}
+		entities = append(entities, parts[0]+"\t"+parts[1])
+	}
+	return entities
+}
+
+// keepRelationsBetween écarte les lignes de la réponse qui ne relient pas deux entités de la liste fournie au LLM
+func (p *Pipeline) keepRelationsBetween(result string, entities []string) string {
+	allowed := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		allowed[strings.SplitN(entity, "\t", 2)[0]] = true
+	}
+
+	p.ontologyMu.Lock()
+	defer p.ontologyMu.Unlock()
+
+	var lines []string
+	for _, line := range strings.Split(result, "\n") {
+		parts := splitTSVLine(line)
+		if len(parts) < 4 {
+			continue
+		}
+		source := p.resolveElement(parts[0])
+		target := p.resolveElement(parts[2])
+		if source == nil || target == nil || !allowed[source.Name] || !allowed[target.Name] {
+			log.Debug("Relation outside the extracted entities dropped: %s", line)
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// schemaEntityTypeList écrit les types d'entités du schéma, un par ligne, pour l'extraction des entités
+func (p *Pipeline) schemaEntityTypeList() string {
+	var lines []string
+	for _, def := range p.schema.EntityTypes {
+		lines = append(lines, describeSchemaType(def.Name, def.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// schemaRelationTypeList écrit les relations du schéma, une par ligne, pour l'extraction des relations
+func (p *Pipeline) schemaRelationTypeList() string {
+	var lines []string
+	for _, def := range p.schema.RelationTypes {
+		lines = append(lines, describeSchemaType(def.Name, def.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeSchemaType écrit « - Nom : description », ou « - Nom » sans description
+func describeSchemaType(name, description string) string {
+	if description == "" {
+		return "- " + name
+	}
+	return fmt.Sprintf("- %s : %s", name, description)
+}
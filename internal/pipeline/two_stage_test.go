@@ -0,0 +1,52 @@
+// pipeline/two_stage_test.go
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessSegmentTwoStageConstrainsRelationsToExtractedEntities(t *testing.T) {
+	p := newTestPipeline()
+	p.config.Mode = ModeTwoStage
+	p.entityExtractionPrompt = "Ignorer les personnes"
+	client := &fakeLLM{responses: []string{
+		"PSSI\tDocument\tPolitique de sécurité\nRSSI\tRole\tResponsable\tde la sécurité",
+		"RSSI\trédige\tPSSI\tLe RSSI rédige la PSSI\nRSSI\tvalide\tDirection\tEntité non extraite",
+	}}
+	p.llm = client
+
+	result, err := p.processSegment([]byte("Le RSSI rédige la PSSI."), "", "", false, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, client.calls)
+	assert.Equal(t, "PSSI\tDocument\tPolitique de sécurité\nRSSI\tRole\tResponsable de la sécurité\nRSSI\trédige:1\tPSSI\tLe RSSI rédige la PSSI", result)
+	assert.Len(t, p.ontology.Elements, 2)
+	assert.Len(t, p.ontology.Relations, 1)
+
+	if assert.Len(t, client.prompts, 2) {
+		assert.Contains(t, client.prompts[0], "Additional instructions:\nIgnorer les personnes")
+		assert.NotContains(t, client.prompts[0], "Context from the preceding text")
+		assert.Contains(t, client.prompts[1], "Entities:\nPSSI\tDocument\nRSSI\tRole\n")
+		assert.NotContains(t, client.prompts[1], "Additional instructions")
+	}
+}
+
+func TestProcessSegmentTwoStagePassesSchemaTypes(t *testing.T) {
+	p := newTestPipeline()
+	p.config.Mode = ModeTwoStage
+	p.schema = newTestSchema()
+	client := &fakeLLM{responses: []string{""}}
+	p.llm = client
+
+	result, err := p.processSegment([]byte("texte"), "", "", false, 0)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	assert.Equal(t, 1, client.calls)
+	if assert.Len(t, client.prompts, 1) {
+		assert.Contains(t, client.prompts[0], "Use only the following entity types:\n- ")
+	}
+}
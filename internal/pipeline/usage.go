@@ -0,0 +1,248 @@
+// usage.go
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/metadata"
+	"github.com/chrlesur/Ontology/internal/prompt"
+)
+
+// ErrBudgetExceeded est retournée lorsqu'un appel au LLM risquerait de dépasser le budget de l'exécution
+var ErrBudgetExceeded = errors.New("LLM budget exceeded")
+
+// mergeOffset désigne les appels de fusion, qui ne relèvent d'aucun segment
+const mergeOffset = -1
+
+// usageTracker cumule les tokens et le coût des appels au LLM de l'exécution, par passe et par segment
+type usageTracker struct {
+	mu       sync.Mutex
+	total    metadata.TokenUsage
+	passes   map[int]*metadata.PassUsage
+	segments map[[2]int]*metadata.TokenUsage // clé : passe, numéro du segment
+	models   map[string]*metadata.TokenUsage // clé : fournisseur:modèle ayant répondu aux requêtes routées
+	reserved float64                         // coût estimé des appels en cours
+	exceeded error                           // premier refus d'appel pour dépassement du budget
+}
+
+// callLLM appelle le LLM en comptant les tokens et le coût de l'appel dans la passe en cours.
+// offset situe le segment traité dans le contenu de la passe, mergeOffset pour les appels de fusion.
+// Lorsqu'un budget est fixé, l'appel est refusé avant d'être envoyé si son coût estimé risque de le dépasser,
+// et chaque nouvelle tentative (retry ou repli sur un autre modèle) est réservée de la même façon avant d'être envoyée.
+// call reçoit le contexte de l'exécution, borné par request_timeout_seconds, qui porte la route de la requête.
+func (p *Pipeline) callLLM(offset int, promptTemplate *prompt.PromptTemplate, values map[string]string, call func(ctx context.Context) (string, llm.Usage, error)) (string, error) {
+	estimate, err := p.reserveBudget(promptTemplate, values)
+	if err != nil {
+		return "", err
+	}
+	reserved := estimate
+	ctx, cancel := p.requestContext()
+	defer cancel()
+	ctx = llm.WithRoute(ctx, requestRoute(offset, values))
+	if estimate > 0 {
+		ctx = llm.WithRetryHook(ctx, func() error {
+			if err := p.reserveCost(estimate); err != nil {
+				return err
+			}
+			reserved += estimate
+			return nil
+		})
+	}
+	result, usage, err := call(ctx)
+	p.recordUsage(offset, usage, reserved)
+	return result, err
+}
+
+// modelPricing retourne le prix d'un modèle, s'il est configuré
+func (p *Pipeline) modelPricing(model string) (config.ModelPricing, bool) {
+	pricing, ok := p.config.Pricing[model]
+	return pricing, ok
+}
+
+// usageCost calcule le coût d'un appel à partir du prix par million de tokens du modèle
+func (p *Pipeline) usageCost(model string, inputTokens, outputTokens int) float64 {
+	pricing, ok := p.modelPricing(model)
+	if !ok {
+		return 0
+	}
+	return (float64(inputTokens)*pricing.Input + float64(outputTokens)*pricing.Output) / 1e6
+}
+
+// reserveBudget estime le coût d'un appel, le prompt complet en entrée et max_tokens en sortie, au prix
+// du plus cher des modèles vers lesquels il peut être routé, et le réserve sur le budget restant.
+// Sans budget, aucune estimation n'est faite.
+func (p *Pipeline) reserveBudget(promptTemplate *prompt.PromptTemplate, values map[string]string) (float64, error) {
+	budget := p.config.Budget
+	if budget <= 0 {
+		return 0, nil
+	}
+	for _, model := range p.pricedModels() {
+		if _, ok := p.modelPricing(model); !ok {
+			return 0, fmt.Errorf("no price configured for model %s, the budget cannot be enforced", model)
+		}
+	}
+	formattedPrompt, err := promptTemplate.Format(values)
+	if err != nil {
+		// L'erreur de formatage est retournée par le client lors de l'appel
+		return 0, nil
+	}
+	countTokens, err := p.tokenCounterFunc()
+	if err != nil {
+		return 0, err
+	}
+	var estimate float64
+	promptTokens := countTokens(formattedPrompt)
+	for _, model := range p.pricedModels() {
+		estimate = math.Max(estimate, p.usageCost(model, promptTokens, p.config.MaxTokens))
+	}
+	if err := p.reserveCost(estimate); err != nil {
+		return 0, err
+	}
+	return estimate, nil
+}
+
+// reserveCost réserve le coût estimé d'un envoi au LLM, ou le refuse s'il risque de dépasser le budget
+func (p *Pipeline) reserveCost(estimate float64) error {
+	budget := p.config.Budget
+	p.usage.mu.Lock()
+	defer p.usage.mu.Unlock()
+	if p.usage.exceeded != nil {
+		return p.usage.exceeded
+	}
+	if spent := p.usage.total.Cost; spent+p.usage.reserved+estimate > budget {
+		p.usage.exceeded = fmt.Errorf("%w: %.4f %s spent, next call estimated at %.4f %s, budget %.4f %s",
+			ErrBudgetExceeded, spent, p.config.Currency, estimate, p.config.Currency, budget, p.config.Currency)
+		log.Error("%v", p.usage.exceeded)
+		return p.usage.exceeded
+	}
+	p.usage.reserved += estimate
+	return nil
+}
+
+// recordUsage enregistre la consommation d'un appel et libère le coût réservé pour toutes ses tentatives
+func (p *Pipeline) recordUsage(offset int, usage llm.Usage, estimate float64) {
+	tokenUsage := metadata.TokenUsage{
+		Requests:       usage.Requests,
+		CachedRequests: usage.CachedRequests,
+		InputTokens:    usage.InputTokens,
+		OutputTokens:   usage.OutputTokens,
+		Cost:           p.usageCost(p.answeringModel(usage), usage.InputTokens, usage.OutputTokens),
+		Estimated:      usage.Estimated,
+	}
+
+	p.usage.mu.Lock()
+	defer p.usage.mu.Unlock()
+	p.usage.reserved -= estimate
+	p.usage.total.Add(tokenUsage)
+
+	if p.usage.passes == nil {
+		p.usage.passes = make(map[int]*metadata.PassUsage)
+		p.usage.segments = make(map[[2]int]*metadata.TokenUsage)
+		p.usage.models = make(map[string]*metadata.TokenUsage)
+	}
+	if usage.Model != "" {
+		link := llm.Link{Provider: usage.Provider, Model: usage.Model}.String()
+		model, ok := p.usage.models[link]
+		if !ok {
+			model = &metadata.TokenUsage{}
+			p.usage.models[link] = model
+		}
+		model.Add(tokenUsage)
+	}
+	pass, ok := p.usage.passes[p.currentPass]
+	if !ok {
+		pass = &metadata.PassUsage{Pass: p.currentPass}
+		p.usage.passes[p.currentPass] = pass
+	}
+	if offset == mergeOffset {
+		pass.Merge.Add(tokenUsage)
+		return
+	}
+	pass.Segments.Add(tokenUsage)
+
+	key := [2]int{p.currentPass, p.segmentNumber(offset)}
+	segment, ok := p.usage.segments[key]
+	if !ok {
+		segment = &metadata.TokenUsage{}
+		p.usage.segments[key] = segment
+	}
+	segment.Add(tokenUsage)
+}
+
+// segmentNumber retourne le numéro, à partir de 1, du segment commençant à offset, 0 s'il est inconnu
+func (p *Pipeline) segmentNumber(offset int) int {
+	for i, span := range p.segmentSpans {
+		if span.Start == offset {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// budgetExceeded retourne l'erreur du premier appel refusé pour dépassement du budget, nil sinon
+func (p *Pipeline) budgetExceeded() error {
+	p.usage.mu.Lock()
+	defer p.usage.mu.Unlock()
+	return p.usage.exceeded
+}
+
+// UsageReport retourne la consommation de l'exécution, passe par passe et segment par segment
+func (p *Pipeline) UsageReport() *metadata.UsageReport {
+	p.usage.mu.Lock()
+	defer p.usage.mu.Unlock()
+
+	report := &metadata.UsageReport{
+		Provider: p.llmProvider,
+		Model:    p.llmModel,
+		Budget:   p.config.Budget,
+		Total:    p.usage.total,
+		Passes:   []metadata.PassUsage{},
+		Segments: []metadata.SegmentUsage{},
+	}
+	if _, ok := p.modelPricing(p.llmModel); ok {
+		report.Currency = p.config.Currency
+	}
+	for _, pass := range p.usage.passes {
+		report.Passes = append(report.Passes, *pass)
+	}
+	if len(p.usage.models) > 0 {
+		report.Models = make(map[string]metadata.TokenUsage, len(p.usage.models))
+		for link, usage := range p.usage.models {
+			report.Models[link] = *usage
+		}
+	}
+	sort.Slice(report.Passes, func(i, j int) bool {
+		return report.Passes[i].Pass < report.Passes[j].Pass
+	})
+	for key, usage := range p.usage.segments {
+		report.Segments = append(report.Segments, metadata.SegmentUsage{Pass: key[0], Segment: key[1], TokenUsage: *usage})
+	}
+	sort.Slice(report.Segments, func(i, j int) bool {
+		if report.Segments[i].Pass != report.Segments[j].Pass {
+			return report.Segments[i].Pass < report.Segments[j].Pass
+		}
+		return report.Segments[i].Segment < report.Segments[j].Segment
+	})
+	return report
+}
+
+// logUsageSummary journalise la consommation totale de l'exécution
+func (p *Pipeline) logUsageSummary() {
+	report := p.UsageReport()
+	total := report.Total
+	if report.Currency == "" {
+		log.Info("LLM usage: %d requests (%d cached), %d input tokens, %d output tokens, no price configured for model %s",
+			total.Requests, total.CachedRequests, total.InputTokens, total.OutputTokens, report.Model)
+		return
+	}
+	log.Info("LLM usage: %d requests (%d cached), %d input tokens, %d output tokens, cost %.4f %s",
+		total.Requests, total.CachedRequests, total.InputTokens, total.OutputTokens, total.Cost, report.Currency)
+}
@@ -0,0 +1,109 @@
+// pipeline/usage_test.go
+
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/config"
+	"github.com/chrlesur/Ontology/internal/llm"
+	"github.com/chrlesur/Ontology/internal/prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUsagePipeline() (*Pipeline, *fakeLLM) {
+	p := newTestPipeline()
+	p.llmProvider = "test-llm"
+	p.llmModel = "test-model"
+	p.config.Pricing = map[string]config.ModelPricing{"test-model": {Input: 2, Output: 10}}
+	p.config.Currency = "EUR"
+	p.currentPass = 1
+	p.segmentSpans = []fileSpan{{Start: 0, End: 20}, {Start: 20, End: 40}}
+	p.tokenCounter = func(text string) int { return len(strings.Fields(text)) }
+	client := &fakeLLM{
+		responses: []string{"PSSI\tDocument\tPolitique de sécurité"},
+		usage:     llm.Usage{InputTokens: 1000, OutputTokens: 100, Requests: 1},
+	}
+	p.llm = client
+	return p, client
+}
+
+func TestUsageReportPerPassAndSegment(t *testing.T) {
+	p, _ := newTestUsagePipeline()
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.NoError(t, err)
+	_, err = p.processSegment([]byte("La PSSI est validée."), "", "", false, 20)
+	assert.NoError(t, err)
+	_, err = p.mergeBatch([]string{"PSSI\tDocument\tPolitique", "RSSI\tRole\tResponsable"})
+	assert.NoError(t, err)
+
+	report := p.UsageReport()
+	assert.Equal(t, "test-model", report.Model)
+	assert.Equal(t, "EUR", report.Currency)
+	assert.Equal(t, 3, report.Total.Requests)
+	assert.Equal(t, 3000, report.Total.InputTokens)
+	assert.InDelta(t, 0.009, report.Total.Cost, 1e-9)
+	if assert.Len(t, report.Passes, 1) {
+		assert.Equal(t, 2, report.Passes[0].Segments.Requests)
+		assert.Equal(t, 1, report.Passes[0].Merge.Requests)
+	}
+	if assert.Len(t, report.Segments, 2) {
+		assert.Equal(t, 1, report.Segments[0].Segment)
+		assert.Equal(t, 2, report.Segments[1].Segment)
+		assert.InDelta(t, 0.003, report.Segments[1].Cost, 1e-9)
+	}
+}
+
+func TestBudgetRefusesCallsThatMayExceedIt(t *testing.T) {
+	p, client := newTestUsagePipeline()
+	// Chaque appel est estimé à un peu plus de 0,01 (1000 tokens de sortie au plus) et en coûte 0,003
+	p.config.Budget = 0.012
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.NoError(t, err)
+
+	_, err = p.processSegment([]byte("La PSSI est validée."), "", "", false, 20)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 1, client.calls)
+	assert.ErrorIs(t, p.budgetExceeded(), ErrBudgetExceeded)
+	assert.InDelta(t, 0.003, p.UsageReport().Total.Cost, 1e-9)
+}
+
+func TestBudgetReservesRetriesBeforeSendingThem(t *testing.T) {
+	p, _ := newTestUsagePipeline()
+	// Le premier envoi, estimé à un peu plus de 0,01, tient dans le budget, mais pas un second envoi
+	p.config.Budget = 0.015
+	client := &flakyLLM{failures: 1}
+	p.llm = llm.NewResilientClient(client, "budget-test", &config.Config{MaxRetries: 3})
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 1, client.calls)
+	assert.InDelta(t, 0, p.usage.reserved, 1e-9)
+}
+
+func TestBudgetReservesStructuredOutputRepairs(t *testing.T) {
+	p, client := newTestUsagePipeline()
+	p.config.Budget = 0.012
+	p.config.StructuredOutputRetries = 2
+	client.responses = []string{`{"entities": [{"name": "PSSI"}], "relations": []`}
+
+	_, err := p.processSegmentStructured(prompt.OntologyEnrichmentPrompt, map[string]string{"text": "La PSSI est rédigée."}, false, 0)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 1, client.calls)
+}
+
+func TestCachedResponsesAreNotBilled(t *testing.T) {
+	p, client := newTestUsagePipeline()
+	client.usage = llm.Usage{CachedRequests: 1}
+
+	_, err := p.processSegment([]byte("La PSSI est rédigée."), "", "", false, 0)
+	assert.NoError(t, err)
+
+	total := p.UsageReport().Total
+	assert.Equal(t, 0, total.Requests)
+	assert.Equal(t, 1, total.CachedRequests)
+	assert.Zero(t, total.Cost)
+}
@@ -1,10 +1,5 @@
 package prompt
 
-import (
-	"fmt"
-	"strings"
-)
-
 // PromptTemplate représente un template de prompt
 type PromptTemplate struct {
 	Template string
@@ -20,28 +15,19 @@ func NewCustomPromptTemplate(template string) *PromptTemplate {
     return &PromptTemplate{Template: template}
 }
 
-// Format remplit le template avec les valeurs fournies
-func (pt *PromptTemplate) Format(values map[string]string) string {
-    result := pt.Template
-    for key, value := range values {
-        result = strings.Replace(result, fmt.Sprintf("{%s}", key), value, -1)
-    }
-    
-    // Ajouter le prompt supplémentaire s'il existe
-    if additionalPrompt, ok := values["additional_prompt"]; ok && additionalPrompt != "" {
-        result += "\n\nAdditional instructions:\n" + additionalPrompt
-    }
-    
-    return result
-}
-
 // Définition des templates de prompts
 var (
 	EntityExtractionPrompt = NewPromptTemplate(`
 Analyze the following text and extract key entities (e.g., people, organizations, concepts) relevant to building an ontology:
 
 {text}
-
+{{if .context}}
+Context from the preceding text:
+{context}
+{{end}}{{if .entity_types}}
+Use only the following entity types:
+{entity_types}
+{{end}}
 For each entity, provide:
 1. Entity name
 2. Entity type (e.g., Person, Organization, Concept)
@@ -57,7 +43,10 @@ Your extractions are relevant to creating an ontology
 You avoid including irrelevant or trivial information
 You use the original document language
 You provide the output silently with no additional comments
-`)
+{{if .additional_prompt}}
+Additional instructions:
+{additional_prompt}
+{{end}}`)
 
 	RelationExtractionPrompt = NewPromptTemplate(`
 Based on the following text and the list of entities provided, identify relationships between these entities that would be relevant for an ontology:
@@ -67,7 +56,10 @@ Text:
 
 Entities:
 {entities}
-
+{{if .relation_types}}
+Use only the following relationship types:
+{relation_types}
+{{end}}
 For each relationship, provide:
 1. Source Entity
 2. Relationship Type
@@ -85,7 +77,11 @@ Your extractions are relevant to creating an ontology
 You avoid including irrelevant or trivial information
 You use the original document language
 You provide the output silently with no additional comments
-`)
+Use only entity names from the list above, written exactly as listed.
+{{if .additional_prompt}}
+Additional instructions:
+{additional_prompt}
+{{end}}`)
 
 	OntologyEnrichmentPrompt = NewPromptTemplate(`
 Vous êtes un expert en ontologies chargé d'enrichir et de raffiner une ontologie existante. Voici l'ontologie actuelle et de nouvelles informations à intégrer :
@@ -95,10 +91,10 @@ Ontologie actuelle :
 
 Nouveau texte à analyser :
 {text}
-
+{{if .context}}
 Contexte supplémentaire :
 {context}
-
+{{end}}
 Votre tâche :
 1. Analyser le nouveau texte et le contexte.
 2. Identifier les nouvelles entités et relations pertinentes.
@@ -109,6 +105,7 @@ Votre tâche :
 Fournissez l'ontologie enrichie et raffinée dans le format suivant :
 - Pour les entités : Nom_Entité\tType_Entité\tDescription
 - Pour les relations : Entité_Source\tType_Relation\tEntité_Cible\tDescription
+- Pour les autres noms d'une entité (sigle, variante, singulier ou pluriel) : Alias\talias\tNom_Entité
 
 Assurez-vous que :
 
@@ -132,6 +129,7 @@ Directives pour la fusion :
 1. Intégrez toutes les nouvelles entités et relations pertinentes de la nouvelle ontologie.
 2. En cas de conflit ou de duplication, identifie les concepts qui sont essentiellement identiques ou très proches sémantiquement. Pour chaque groupe de concepts similaires, choisis le nom le plus approprié et représentatif.
 Fusionne les descriptions en une seule, plus complète. Combine toutes les positions textuelles en une seule liste, sans doublons, triée par ordre croissant.
+Conserve chaque nom écarté comme alias de l'entité retenue, ainsi que les sigles et variantes de surface.
 3. Assurez-vous que les relations entre les entités restent cohérentes.
 4. Si une nouvelle information contredit une ancienne, privilégiez la nouvelle mais notez la contradiction si elle est significative.
 5. Maintenez la structure et le format de l'ontologie existante.
@@ -146,7 +144,47 @@ Format de sortie :
 Présentez l'ontologie fusionnée dans le même format que l'ontologie existante, avec une entité ou une relation par ligne.
 Pour les entités : Nom_Entité\tType_Entité\tDescription
 Pour les relations : Entité_Source\tType_Relation\tEntité_Cible\tDescription
+Pour les alias : Alias\talias\tNom_Entité
 
 Procédez à la fusion de manière silencieuse, sans ajouter de commentaires ou d'explications supplémentaires.
+{{if .additional_prompt}}
+Additional instructions:
+{additional_prompt}
+{{end}}`)
+
+	// StructuredOutputInstructions remplace les consignes de format TSV lorsque la sortie structurée est activée
+	StructuredOutputInstructions = `
+Format de sortie structuré :
+Ignorez les consignes de format TSV ci-dessus. Répondez uniquement avec un document JSON de la forme
+{"entities": [{"name": "...", "type": "...", "description": "...", "aliases": ["..."]}], "relations": [{"source": "...", "type": "...", "target": "...", "description": "...", "weight": 1, "direction": "forward"}]}
+"direction" vaut "forward", "backward" ou "bidirectional" et "weight" est un entier positif.
+"aliases" liste les autres noms de l'entité dans le texte : sigles, variantes, singulier ou pluriel ; il vaut [] lorsque l'entité n'en a pas.
+Les noms de "source" et "target" doivent correspondre à des entités de la liste "entities" ou de l'ontologie actuelle.
+`
+
+	// SeedVocabularyInstructions impose les classes et propriétés d'une ontologie existante importée
+	SeedVocabularyInstructions = `
+Ontologie de référence :
+Réutilisez exactement les noms de classes suivants comme types d'entités et les noms de propriétés suivants comme types de relations dès qu'ils s'appliquent.
+N'introduisez un nouveau nom que si aucun des noms existants ne convient.
+Classes :
+{seed_classes}
+Propriétés :
+{seed_properties}
+`
+
+	StructuredOutputRepairPrompt = NewPromptTemplate(`
+Votre réponse précédente n'est pas un document JSON valide pour le schéma attendu.
+
+Réponse précédente :
+{previous_response}
+
+Erreur détectée :
+{error}
+
+Schéma JSON attendu :
+{schema}
+
+Corrigez la réponse et renvoyez uniquement le document JSON corrigé, sans commentaire ni bloc de code.
 `)
 )
@@ -0,0 +1,198 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// placeholderRegexp reconnaît les emplacements {clé} ; seuls ceux entourés d'une unique accolade
+// sont convertis en actions {{.clé}}, les actions du moteur ({{if .context}}) restant inchangées
+var placeholderRegexp = regexp.MustCompile(`(\{+)([A-Za-z_][A-Za-z0-9_]*)(\}+)`)
+
+// templateFuncs sont les fonctions disponibles dans les prompts, notamment pour itérer sur une valeur
+// multiligne : {{range lines .seed_classes}}- {{.}}{{end}}
+var templateFuncs = template.FuncMap{
+	"lines": func(value string) []string {
+		var lines []string
+		for _, line := range strings.Split(value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	},
+	"split": func(separator, value string) []string {
+		return strings.Split(value, separator)
+	},
+	"join": func(separator string, values []string) string {
+		return strings.Join(values, separator)
+	},
+	"trim": strings.TrimSpace,
+}
+
+// PlaceholderError signale les variables attendues par un prompt qui n'ont pas été fournies
+type PlaceholderError struct {
+	Missing []string // variables obligatoires absentes
+	Unused  []string // valeurs fournies que le prompt n'utilise pas, souvent la cause d'un nom mal orthographié
+}
+
+func (e *PlaceholderError) Error() string {
+	message := "missing prompt variables: " + strings.Join(e.Missing, ", ")
+	if len(e.Unused) > 0 {
+		message += "; unused values: " + strings.Join(e.Unused, ", ")
+	}
+	return message
+}
+
+// parse convertit les emplacements {clé} en actions puis analyse le template
+func (pt *PromptTemplate) parse() (*template.Template, error) {
+	source := placeholderRegexp.ReplaceAllStringFunc(pt.Template, func(match string) string {
+		parts := placeholderRegexp.FindStringSubmatch(match)
+		if len(parts[1]) != 1 || len(parts[3]) != 1 {
+			return match
+		}
+		return "{{." + parts[2] + "}}"
+	})
+	parsed, err := template.New("prompt").Funcs(templateFuncs).Option("missingkey=zero").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return parsed, nil
+}
+
+// Variables retourne les variables utilisées par le prompt : obligatoires lorsqu'elles sont insérées,
+// facultatives lorsqu'elles servent de condition ({{if .context}}...{{end}}) ou d'itération
+func (pt *PromptTemplate) Variables() (required []string, optional []string, err error) {
+	parsed, err := pt.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+	inserted := make(map[string]bool)
+	guards := make(map[string]bool)
+	collectVariables(parsed.Tree.Root, inserted, guards, true)
+
+	for key := range inserted {
+		if !guards[key] {
+			required = append(required, key)
+		}
+	}
+	for key := range guards {
+		optional = append(optional, key)
+	}
+	sort.Strings(required)
+	sort.Strings(optional)
+	return required, optional, nil
+}
+
+// collectVariables parcourt l'arbre du template. Dans le corps d'un range ou d'un with, le point
+// désigne l'élément courant : seules les variables $.clé y désignent encore des valeurs fournies.
+func collectVariables(node parse.Node, inserted, guards map[string]bool, root bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, inserted, guards, root)
+		}
+	case *parse.ActionNode:
+		collectPipeVariables(n.Pipe, inserted, root)
+	case *parse.IfNode:
+		collectPipeVariables(n.Pipe, guards, root)
+		collectVariables(n.List, inserted, guards, root)
+		collectVariables(n.ElseList, inserted, guards, root)
+	case *parse.WithNode:
+		collectPipeVariables(n.Pipe, guards, root)
+		collectVariables(n.List, inserted, guards, false)
+		collectVariables(n.ElseList, inserted, guards, root)
+	case *parse.RangeNode:
+		collectPipeVariables(n.Pipe, guards, root)
+		collectVariables(n.List, inserted, guards, false)
+		collectVariables(n.ElseList, inserted, guards, root)
+	}
+}
+
+// collectPipeVariables relève les clés lues par les arguments d'un pipeline
+func collectPipeVariables(pipe *parse.PipeNode, keys map[string]bool, root bool) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				if root {
+					keys[a.Ident[0]] = true
+				}
+			case *parse.VariableNode:
+				if a.Ident[0] == "$" && len(a.Ident) > 1 {
+					keys[a.Ident[1]] = true
+				}
+			case *parse.PipeNode:
+				collectPipeVariables(a, keys, root)
+			}
+		}
+	}
+}
+
+// Check vérifie que les clés fournies couvrent toutes les variables obligatoires du prompt.
+// L'erreur liste les variables manquantes et les clés fournies mais inutilisées.
+func (pt *PromptTemplate) Check(keys []string) error {
+	required, optional, err := pt.Variables()
+	if err != nil {
+		return err
+	}
+	supplied := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		supplied[key] = true
+	}
+	var missing []string
+	for _, key := range required {
+		if !supplied[key] {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	used := make(map[string]bool, len(required)+len(optional))
+	for _, key := range append(required, optional...) {
+		used[key] = true
+	}
+	var unused []string
+	for _, key := range keys {
+		if !used[key] {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return &PlaceholderError{Missing: missing, Unused: unused}
+}
+
+// Format remplit le template avec les valeurs fournies. Les emplacements {clé} sont remplacés par
+// leur valeur ; les conditions et boucles suivent la syntaxe de text/template.
+// Une erreur *PlaceholderError est retournée si une variable obligatoire n'est pas fournie.
+func (pt *PromptTemplate) Format(values map[string]string) (string, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	if err := pt.Check(keys); err != nil {
+		return "", err
+	}
+	parsed, err := pt.parse()
+	if err != nil {
+		return "", err
+	}
+	var result bytes.Buffer
+	if err := parsed.Execute(&result, values); err != nil {
+		return "", fmt.Errorf("failed to render prompt: %w", err)
+	}
+	return result.String(), nil
+}
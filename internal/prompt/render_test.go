@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRendersConditionalsAndLoops(t *testing.T) {
+	template := NewPromptTemplate(`Texte : {text}{{if .context}}
+Contexte : {context}{{end}}
+{{range lines .terms}}- {{.}}
+{{end}}JSON : {"name": "..."}`)
+
+	result, err := template.Format(map[string]string{"text": "abc", "context": "", "terms": "Classe_A\n\nClasse_B"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Texte : abc\n- Classe_A\n- Classe_B\nJSON : {\"name\": \"...\"}", result)
+
+	result, err = template.Format(map[string]string{"text": "abc", "context": "précédent"})
+	assert.NoError(t, err)
+	assert.Contains(t, result, "Contexte : précédent")
+}
+
+func TestFormatReportsMissingAndUnusedVariables(t *testing.T) {
+	template := NewPromptTemplate("Ontologie : {previous_result}\nTexte : {texte}{{if .context}}\n{context}{{end}}")
+
+	required, optional, err := template.Variables()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"previous_result", "texte"}, required)
+	assert.Equal(t, []string{"context"}, optional)
+
+	_, err = template.Format(map[string]string{"text": "abc", "previous_result": "", "context": ""})
+	var placeholderErr *PlaceholderError
+	if assert.ErrorAs(t, err, &placeholderErr) {
+		assert.Equal(t, []string{"texte"}, placeholderErr.Missing)
+		assert.Equal(t, []string{"text"}, placeholderErr.Unused)
+	}
+	assert.EqualError(t, err, "missing prompt variables: texte; unused values: text")
+}
+
+func TestBuiltInPromptsOnlyExpectSuppliedVariables(t *testing.T) {
+	assert.NoError(t, OntologyEnrichmentPrompt.Check(EnrichmentVariables))
+	assert.NoError(t, OntologyMergePrompt.Check([]string{"previous_ontology", "new_ontology"}))
+
+	result, err := OntologyMergePrompt.Format(map[string]string{"previous_ontology": "A", "new_ontology": "B", "additional_prompt": ""})
+	assert.NoError(t, err)
+	assert.NotContains(t, result, "Additional instructions")
+}
+
+func TestTemplateValidateReportsMisnamedPlaceholder(t *testing.T) {
+	template, err := ParseTemplate("Analyse :\n{texte}\n\nOntologie :\n{previous_result}\n")
+	assert.NoError(t, err)
+	assert.ErrorContains(t, template.Validate(), "prompt has no {text} placeholder")
+
+	template, err = ParseTemplate("Analyse :\n{text}\n\nOntologie :\n{resultat_precedent}\n")
+	assert.NoError(t, err)
+	assert.ErrorContains(t, template.Validate(), "missing prompt variables: resultat_precedent")
+}
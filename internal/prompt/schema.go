@@ -0,0 +1,181 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/chrlesur/Ontology/internal/model"
+)
+
+var (
+	// 1. Politique_Sécurité:  ou  13. Unité_Oeuvre [UO] :
+	typeHeadingRegexp = regexp.MustCompile(`^\s*\d+\.\s*([\p{L}\p{N}_'’-]+)(?:\s*\[[^\]]*\])?\s*:?\s*$`)
+	// - Sous-types : contrôle technique, contrôle organisationnel
+	typeFieldRegexp = regexp.MustCompile(`^\s*-\s*([^:]+?)\s*:\s*(.*)$`)
+	// • uo_compute (vCPU, RAM)
+	bulletRegexp = regexp.MustCompile(`^\s*[•*]\s*(.+)$`)
+	// - protège : sécurise un actif  ou  - garantit (force 3)
+	relationItemRegexp = regexp.MustCompile(`^\s*-\s*(\p{Ll}[\p{L}\p{N}_'’-]*)\s*(?:\([^)]*\))?\s*(?::\s*(.*))?$`)
+	// (force 2-3), (Force 1-3)
+	forceRegexp     = regexp.MustCompile(`\s*\((?i:force)[^)]*\)`)
+	parentheticalRe = regexp.MustCompile(`\([^)]*\)`)
+)
+
+// Champs d'une définition de type reconnus dans les templates
+const (
+	fieldDefinition = "definition"
+	fieldSubTypes   = "sub_types"
+	fieldAttributes = "attributes"
+	fieldRelations  = "relations"
+)
+
+// ParseSchema extrait d'un template d'ontologie (.ont) les types d'entités et les relations autorisés.
+// Un type est un titre numéroté suivi de ses champs Définition, Sous-types, Attributs et Relations ;
+// les relations sont les éléments « - nom : description » des sections RELATIONS.
+// Les relations possibles d'un type en définissent le domaine. Le schéma est vide si le template n'en déclare pas.
+func ParseSchema(template string) *model.Schema {
+	schema := model.NewSchema()
+
+	var current *model.EntityTypeDef
+	var fields int
+	var lastField string
+	var lastIndent int
+	inRelations := false
+
+	flush := func() {
+		if current != nil && fields > 0 {
+			schema.AddEntityType(*current)
+		}
+		current, fields, lastField, lastIndent = nil, 0, "", 0
+	}
+
+	for _, line := range strings.Split(template, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+
+		if isSectionHeading(trimmed) {
+			flush()
+			inRelations = strings.Contains(strings.ToUpper(trimmed), "RELATION")
+			continue
+		}
+
+		if matches := typeHeadingRegexp.FindStringSubmatch(line); matches != nil {
+			flush()
+			current = &model.EntityTypeDef{Name: matches[1]}
+			continue
+		}
+
+		if current != nil {
+			if matches := typeFieldRegexp.FindStringSubmatch(line); matches != nil {
+				indent := len(line) - len(strings.TrimLeft(line, " \t"))
+				if field := typeField(matches[1]); field != "" {
+					addTypeField(current, field, matches[2])
+					fields++
+					lastField, lastIndent = field, indent
+					continue
+				}
+				// Sous-liste plus indentée d'un champ dont la valeur est détaillée sur les lignes suivantes
+				if indent > lastIndent && lastField != "" && lastField != fieldDefinition {
+					addTypeField(current, lastField, matches[2])
+					continue
+				}
+				// Champ non reconnu (Exemples : ...), ignoré
+				if fields > 0 {
+					continue
+				}
+			}
+			if matches := bulletRegexp.FindStringSubmatch(line); matches != nil && lastField != "" {
+				addTypeField(current, lastField, matches[1])
+				continue
+			}
+			if fields == 0 {
+				current = nil
+			} else {
+				flush()
+			}
+		}
+
+		if inRelations {
+			if matches := relationItemRegexp.FindStringSubmatch(line); matches != nil {
+				description := strings.TrimSpace(forceRegexp.ReplaceAllString(matches[2], ""))
+				schema.AddRelationType(model.RelationTypeDef{Name: matches[1], Description: description})
+			}
+		}
+	}
+	flush()
+
+	// Les relations possibles d'un type en font une relation autorisée dont il est le domaine
+	for _, entityType := range schema.EntityTypes {
+		for _, relation := range entityType.Relations {
+			schema.AddRelationType(model.RelationTypeDef{Name: relation, Domain: []string{entityType.Name}})
+		}
+	}
+	return schema
+}
+
+// isSectionHeading reconnaît les titres de section écrits en majuscules (TYPES D'ENTITÉS AUTORISÉS :, # RELATIONS AUTORISÉES)
+func isSectionHeading(line string) bool {
+	line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+	if line == "" || unicode.IsDigit([]rune(line)[0]) || strings.HasPrefix(line, "-") {
+		return false
+	}
+	line = strings.TrimSpace(strings.TrimSuffix(parentheticalRe.ReplaceAllString(line, ""), ":"))
+	letters := 0
+	for _, r := range line {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters > 3 && strings.Contains(line, " ")
+}
+
+// typeField identifie le champ d'une définition de type à partir de son libellé
+func typeField(label string) string {
+	label = strings.ToLower(label)
+	switch {
+	case strings.HasPrefix(label, "définition"), strings.HasPrefix(label, "definition"):
+		return fieldDefinition
+	case strings.HasPrefix(label, "sous-type"), strings.HasPrefix(label, "sous type"):
+		return fieldSubTypes
+	case strings.HasPrefix(label, "attribut"):
+		return fieldAttributes
+	case strings.HasPrefix(label, "relation"):
+		return fieldRelations
+	}
+	return ""
+}
+
+// addTypeField ajoute la valeur d'un champ à la définition d'un type
+func addTypeField(def *model.EntityTypeDef, field, value string) {
+	if field == fieldDefinition {
+		def.Description = strings.TrimSpace(value)
+		return
+	}
+	values := splitList(parentheticalRe.ReplaceAllString(value, ""))
+	switch field {
+	case fieldSubTypes:
+		def.SubTypes = append(def.SubTypes, values...)
+	case fieldAttributes:
+		def.Attributes = append(def.Attributes, values...)
+	case fieldRelations:
+		def.Relations = append(def.Relations, values...)
+	}
+}
+
+// splitList découpe une liste séparée par des virgules
+func splitList(value string) []string {
+	var values []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(item), "."))
+		if item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
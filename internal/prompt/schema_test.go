@@ -0,0 +1,50 @@
+package prompt
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchemaFromSMSITemplate(t *testing.T) {
+	content, err := os.ReadFile("../../templates/smsi.ont")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	schema := ParseSchema(string(content))
+	var names []string
+	for _, entityType := range schema.EntityTypes {
+		names = append(names, entityType.Name)
+	}
+	assert.Equal(t, []string{"Politique_Sécurité", "Controle_Sécurité", "Actif_Sécurité", "Risque_Sécurité",
+		"Procedure_Sécurité", "Role_Sécurité", "Exigence_Sécurité"}, names)
+
+	role := schema.EntityType("role_sécurité")
+	if assert.NotNil(t, role) {
+		assert.Equal(t, "Fonction ou responsabilité dans le SMSI", role.Description)
+		assert.Equal(t, []string{"RSSI", "administrateur", "utilisateur", "auditeur"}, role.SubTypes)
+		assert.Equal(t, []string{"périmètre", "responsabilités", "droits"}, role.Attributes)
+	}
+
+	// Relation de la section RELATIONS AUTORISÉES, citée par un type qui en devient le domaine
+	protege := schema.RelationType("protège")
+	if assert.NotNil(t, protege) {
+		assert.Equal(t, "sécurise un actif", protege.Description)
+		assert.Equal(t, []string{"Controle_Sécurité"}, protege.Domain)
+	}
+	// Relation de la section sans type déclarant : aucun domaine imposé
+	if inclut := schema.RelationType("inclut"); assert.NotNil(t, inclut) {
+		assert.Empty(t, inclut.Domain)
+	}
+	// Relation citée uniquement par des types
+	if impose := schema.RelationType("impose"); assert.NotNil(t, impose) {
+		assert.Equal(t, []string{"Politique_Sécurité", "Exigence_Sécurité"}, impose.Domain)
+	}
+}
+
+func TestParseSchemaIgnoresFreeFormPrompts(t *testing.T) {
+	schema := ParseSchema("Analyse le texte suivant et extrais les entités :\n1. Nom de l'entité:\n- Utiliser les termes exacts\n{text}")
+	assert.True(t, schema.IsEmpty())
+}
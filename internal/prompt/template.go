@@ -0,0 +1,345 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/chrlesur/Ontology/internal/model"
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateFormatVersion est la version du format structuré des templates d'ontologie comprise par ce loader
+const TemplateFormatVersion = 1
+
+// EnrichmentVariables sont les valeurs fournies au prompt d'enrichissement pour chaque segment
+var EnrichmentVariables = []string{"previous_result", "text", "context"}
+
+// frontMatterDelimiter encadre l'en-tête YAML d'un template structuré
+const frontMatterDelimiter = "---"
+
+// TemplateEntityType déclare un type d'entité dans l'en-tête d'un template
+type TemplateEntityType struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description,omitempty"`
+	SubTypes    []string `yaml:"sub_types" json:"sub_types,omitempty"`
+	Attributes  []string `yaml:"attributes" json:"attributes,omitempty"`
+	Relations   []string `yaml:"relations" json:"relations,omitempty"` // relations dont le type peut être la source
+}
+
+// TemplateRelationType déclare une relation, son groupe d'affichage et les types qu'elle relie
+type TemplateRelationType struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description,omitempty"`
+	Group       string   `yaml:"group" json:"group,omitempty"`
+	Domain      []string `yaml:"domain" json:"domain,omitempty"`
+	Range       []string `yaml:"range" json:"range,omitempty"`
+}
+
+// TemplateFragments sont les passages de prompt rédigés librement, insérés autour des sections générées
+type TemplateFragments struct {
+	Preamble     string `yaml:"preamble" json:"preamble,omitempty"`         // avant les règles de nommage
+	Instructions string `yaml:"instructions" json:"instructions,omitempty"` // après les relations autorisées
+	Notes        string `yaml:"notes" json:"notes,omitempty"`               // après le format de sortie
+	Closing      string `yaml:"closing" json:"closing,omitempty"`           // après le texte à analyser
+}
+
+// Template est un template d'ontologie : un en-tête YAML versionné qui déclare les types, les relations,
+// les règles de nommage et les fragments du prompt, suivi d'instructions libres.
+// Un fichier .ont sans en-tête est un template libre, utilisé tel quel comme prompt.
+type Template struct {
+	FormatVersion int                    `yaml:"format_version" json:"format_version"`
+	Name          string                 `yaml:"name" json:"name"`
+	Version       string                 `yaml:"version" json:"version,omitempty"`
+	Description   string                 `yaml:"description" json:"description,omitempty"`
+	NamingRules   []string               `yaml:"naming_rules" json:"naming_rules,omitempty"`
+	EntityTypes   []TemplateEntityType   `yaml:"entity_types" json:"entity_types"`
+	RelationTypes []TemplateRelationType `yaml:"relation_types" json:"relation_types,omitempty"`
+	Prompt        TemplateFragments      `yaml:"prompt" json:"prompt"`
+
+	Body       string `yaml:"-" json:"body,omitempty"` // texte qui suit l'en-tête, ou tout le fichier d'un template libre
+	Structured bool   `yaml:"-" json:"structured"`
+}
+
+// LoadTemplate lit un template d'ontologie depuis le système de fichiers local
+func LoadTemplate(path string) (*Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	return ParseTemplate(string(content))
+}
+
+// ParseTemplate sépare l'en-tête YAML d'un template de ses instructions libres.
+// Sans en-tête, le template est libre et son contenu entier sert de prompt.
+func ParseTemplate(content string) (*Template, error) {
+	header, body, ok := splitFrontMatter(content)
+	if !ok {
+		return &Template{Body: content}, nil
+	}
+
+	template := &Template{}
+	if err := yaml.UnmarshalStrict([]byte(header), template); err != nil {
+		return nil, fmt.Errorf("failed to parse template front matter: %w", err)
+	}
+	template.Body = strings.TrimSpace(body)
+	template.Structured = true
+	return template, nil
+}
+
+// splitFrontMatter retourne l'en-tête compris entre deux lignes --- en tête de fichier, et le texte qui le suit
+func splitFrontMatter(content string) (string, string, bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontMatterDelimiter {
+		return "", "", false
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == frontMatterDelimiter {
+			return strings.Join(lines[1:i], ""), strings.Join(lines[i+1:], ""), true
+		}
+	}
+	return "", "", false
+}
+
+// Validate vérifie qu'un template structuré est complet et cohérent, et que le prompt n'attend que les variables fournies à l'enrichissement
+func (t *Template) Validate() error {
+	var problems []string
+	if !t.Structured {
+		if err := t.CheckVariables(); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("invalid template: %s", strings.Join(problems, "; "))
+		}
+		return nil
+	}
+
+	switch {
+	case t.FormatVersion == 0:
+		problems = append(problems, "format_version is missing")
+	case t.FormatVersion > TemplateFormatVersion:
+		problems = append(problems, fmt.Sprintf("format_version %d is not supported (latest is %d)", t.FormatVersion, TemplateFormatVersion))
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		problems = append(problems, "name is empty")
+	}
+	if len(t.EntityTypes) == 0 {
+		problems = append(problems, "no entity_types declared")
+	}
+
+	declared := make(map[string]bool)
+	for i, entityType := range t.EntityTypes {
+		name := strings.TrimSpace(entityType.Name)
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("entity_types[%d].name is empty", i))
+		case strings.ContainsAny(name, " \t"):
+			problems = append(problems, fmt.Sprintf("entity_types[%d].name %q contains spaces", i, name))
+		case declared[strings.ToLower(name)]:
+			problems = append(problems, fmt.Sprintf("entity_types[%d].name %q is declared twice", i, name))
+		}
+		declared[strings.ToLower(name)] = true
+	}
+
+	relations := make(map[string]bool)
+	for i, relationType := range t.RelationTypes {
+		name := strings.TrimSpace(relationType.Name)
+		switch {
+		case name == "":
+			problems = append(problems, fmt.Sprintf("relation_types[%d].name is empty", i))
+		case strings.ContainsAny(name, " \t:"):
+			problems = append(problems, fmt.Sprintf("relation_types[%d].name %q contains spaces or colons", i, name))
+		case relations[strings.ToLower(name)]:
+			problems = append(problems, fmt.Sprintf("relation_types[%d].name %q is declared twice", i, name))
+		}
+		relations[strings.ToLower(name)] = true
+		for _, domain := range relationType.Domain {
+			if !declared[strings.ToLower(domain)] {
+				problems = append(problems, fmt.Sprintf("relation_types[%d].domain references unknown entity type %q", i, domain))
+			}
+		}
+		for _, target := range relationType.Range {
+			if !declared[strings.ToLower(target)] {
+				problems = append(problems, fmt.Sprintf("relation_types[%d].range references unknown entity type %q", i, target))
+			}
+		}
+	}
+
+	if err := t.CheckVariables(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid template %s: %s", t.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// CheckVariables vérifie que le prompt assemblé insère le texte à analyser et n'attend
+// aucune autre variable que celles fournies à l'enrichissement (EnrichmentVariables)
+func (t *Template) CheckVariables() error {
+	promptTemplate := t.PromptTemplate()
+	required, optional, err := promptTemplate.Variables()
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(required, "text") && !slices.Contains(optional, "text") {
+		return fmt.Errorf("prompt has no {text} placeholder")
+	}
+	return promptTemplate.Check(EnrichmentVariables)
+}
+
+// Schema retourne les types et relations du template. Ceux d'un template libre sont lus dans sa prose.
+func (t *Template) Schema() *model.Schema {
+	if !t.Structured {
+		return ParseSchema(t.Body)
+	}
+	schema := model.NewSchema()
+	for _, entityType := range t.EntityTypes {
+		schema.AddEntityType(model.EntityTypeDef{
+			Name:        entityType.Name,
+			Description: entityType.Description,
+			SubTypes:    entityType.SubTypes,
+			Attributes:  entityType.Attributes,
+			Relations:   entityType.Relations,
+		})
+	}
+	for _, relationType := range t.RelationTypes {
+		schema.AddRelationType(model.RelationTypeDef{
+			Name:        relationType.Name,
+			Description: relationType.Description,
+			Domain:      relationType.Domain,
+			Range:       relationType.Range,
+		})
+	}
+	// Les relations possibles d'un type en font une relation autorisée dont il est le domaine
+	for _, entityType := range t.EntityTypes {
+		for _, relation := range entityType.Relations {
+			schema.AddRelationType(model.RelationTypeDef{Name: relation, Domain: []string{entityType.Name}})
+		}
+	}
+	return schema
+}
+
+// PromptTemplate assemble le prompt d'enrichissement : fragments, règles de nommage, types et relations
+// déclarés, format de sortie et emplacements du texte. Un template libre est utilisé tel quel.
+func (t *Template) PromptTemplate() *PromptTemplate {
+	if !t.Structured {
+		return NewCustomPromptTemplate(t.Body)
+	}
+
+	var sections []string
+	if preamble := strings.TrimSpace(t.Prompt.Preamble); preamble != "" {
+		sections = append(sections, preamble)
+	}
+
+	if len(t.NamingRules) > 0 {
+		var builder strings.Builder
+		builder.WriteString("RÈGLES DE NOMMAGE :\n")
+		for _, rule := range t.NamingRules {
+			builder.WriteString("- " + strings.TrimSpace(rule) + "\n")
+		}
+		sections = append(sections, strings.TrimSuffix(builder.String(), "\n"))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("TYPES D'ENTITÉS AUTORISÉS :\n")
+	for i, entityType := range t.EntityTypes {
+		builder.WriteString(fmt.Sprintf("\n%d. %s :\n", i+1, entityType.Name))
+		if entityType.Description != "" {
+			builder.WriteString("   - Définition : " + entityType.Description + "\n")
+		}
+		if len(entityType.SubTypes) > 0 {
+			builder.WriteString("   - Sous-types : " + strings.Join(entityType.SubTypes, ", ") + "\n")
+		}
+		if len(entityType.Attributes) > 0 {
+			builder.WriteString("   - Attributs : " + strings.Join(entityType.Attributes, ", ") + "\n")
+		}
+		if len(entityType.Relations) > 0 {
+			builder.WriteString("   - Relations possibles : " + strings.Join(entityType.Relations, ", ") + "\n")
+		}
+	}
+	sections = append(sections, strings.TrimSuffix(builder.String(), "\n"))
+
+	if len(t.RelationTypes) > 0 {
+		sections = append(sections, t.formatRelationTypes())
+	}
+
+	for _, instructions := range []string{t.Prompt.Instructions, t.Body} {
+		if instructions = strings.TrimSpace(instructions); instructions != "" {
+			sections = append(sections, instructions)
+		}
+	}
+
+	sections = append(sections, `FORMAT DE SORTIE :
+
+### ENTITÉS
+NomEntité\tType\tDescription
+
+### RELATIONS
+EntitéSource\tTypeRelation:Force(1-3)\tEntitéCible\tDescription
+
+### ALIAS
+Alias\talias\tNomEntité`)
+
+	if notes := strings.TrimSpace(t.Prompt.Notes); notes != "" {
+		sections = append(sections, notes)
+	}
+
+	sections = append(sections, `Ontologie actuelle :
+{previous_result}
+
+Nouveau texte à analyser :
+{text}{{if .context}}
+
+Contexte supplémentaire :
+{context}{{end}}`)
+
+	if closing := strings.TrimSpace(t.Prompt.Closing); closing != "" {
+		sections = append(sections, closing)
+	}
+
+	return NewPromptTemplate(strings.Join(sections, "\n\n") + "\n")
+}
+
+// formatRelationTypes écrit les relations autorisées par groupe, dans l'ordre de leur première apparition
+func (t *Template) formatRelationTypes() string {
+	var groups []string
+	byGroup := make(map[string][]TemplateRelationType)
+	for _, relationType := range t.RelationTypes {
+		group := relationType.Group
+		if group == "" {
+			group = "Autres relations"
+		}
+		if _, ok := byGroup[group]; !ok {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], relationType)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("RELATIONS AUTORISÉES :\n")
+	for i, group := range groups {
+		builder.WriteString(fmt.Sprintf("\n%d. %s :\n", i+1, group))
+		for _, relationType := range byGroup[group] {
+			line := "   - " + relationType.Name
+			if relationType.Description != "" {
+				line += " : " + relationType.Description
+			}
+			var constraints []string
+			if len(relationType.Domain) > 0 {
+				constraints = append(constraints, "source : "+strings.Join(relationType.Domain, ", "))
+			}
+			if len(relationType.Range) > 0 {
+				constraints = append(constraints, "cible : "+strings.Join(relationType.Range, ", "))
+			}
+			if len(constraints) > 0 {
+				line += " (" + strings.Join(constraints, " ; ") + ")"
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
@@ -0,0 +1,74 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadStructuredTemplate(t *testing.T) {
+	template, err := LoadTemplate("../../templates/secnumcloud.ont")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.True(t, template.Structured)
+	assert.NoError(t, template.Validate())
+	assert.Equal(t, "secnumcloud", template.Name)
+	assert.Equal(t, 1, template.FormatVersion)
+
+	schema := template.Schema()
+	assert.Len(t, schema.EntityTypes, 9)
+	// Aucun type d'entité ne cite chiffre : la relation n'a ni domaine ni portée
+	if chiffre := schema.RelationType("chiffre"); assert.NotNil(t, chiffre) {
+		assert.Empty(t, chiffre.Domain)
+		assert.Empty(t, chiffre.Range)
+	}
+	if protege := schema.RelationType("protège"); assert.NotNil(t, protege) {
+		assert.Equal(t, []string{"Sécurité_Service", "Zone_Hébergement", "Données_Service"}, protege.Domain)
+	}
+
+	// Le prompt assemblé reprend les placeholders et se relit comme un template libre
+	text := template.PromptTemplate().Template
+	for _, placeholder := range []string{"{text}", "{previous_result}", "{context}"} {
+		assert.Contains(t, text, placeholder)
+	}
+	assert.True(t, strings.HasPrefix(text, "OBJECTIF : Analyser les documents relatifs à SecNumCloud"))
+	reparsed := ParseSchema(text)
+	assert.Len(t, reparsed.EntityTypes, len(schema.EntityTypes))
+	assert.Len(t, reparsed.RelationTypes, len(schema.RelationTypes))
+}
+
+func TestFreeFormTemplateIsUsedAsIs(t *testing.T) {
+	content := "Analyse le texte :\n{text}\n"
+	template, err := ParseTemplate(content)
+	assert.NoError(t, err)
+	assert.False(t, template.Structured)
+	assert.NoError(t, template.Validate())
+	assert.Equal(t, content, template.PromptTemplate().Template)
+}
+
+func TestValidateTemplateListsProblems(t *testing.T) {
+	template, err := ParseTemplate(`---
+name: incomplet
+entity_types:
+  - name: Actif
+  - name: Actif
+relation_types:
+  - name: protège
+    range: [Donnée]
+---
+`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = template.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "format_version is missing")
+		assert.Contains(t, err.Error(), `entity_types[1].name "Actif" is declared twice`)
+		assert.Contains(t, err.Error(), `relation_types[0].range references unknown entity type "Donnée"`)
+	}
+
+	_, err = ParseTemplate("---\nformat_version: 1\nentity_type: []\n---\n")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -16,6 +17,7 @@ import (
 type LocalStorage struct {
 	basePath string
 	logger   *logger.Logger
+	ctx      context.Context // les opérations échouent une fois ce contexte annulé, nil pour aucun
 }
 
 type localFileInfo struct {
@@ -29,7 +31,25 @@ func NewLocalStorage(basePath string, logger *logger.Logger) *LocalStorage {
 	}
 }
 
+// WithContext retourne une copie du stockage dont les opérations échouent une fois ctx annulé
+func (ls *LocalStorage) WithContext(ctx context.Context) Storage {
+	bound := *ls
+	bound.ctx = ctx
+	return &bound
+}
+
+// checkContext retourne l'erreur du contexte s'il a été annulé
+func (ls *LocalStorage) checkContext() error {
+	if ls.ctx == nil {
+		return nil
+	}
+	return ls.ctx.Err()
+}
+
 func (ls *LocalStorage) Read(path string) ([]byte, error) {
+	if err := ls.checkContext(); err != nil {
+		return nil, err
+	}
 	fullPath := ls.getFullPath(path)
 	ls.logger.Debug("Full path for reading: %s", fullPath)
 
@@ -51,6 +71,9 @@ func (ls *LocalStorage) readDirectory(dirPath string) ([]byte, error) {
 		if err != nil {
 			return err
 		}
+		if err := ls.checkContext(); err != nil {
+			return err
+		}
 		if !info.IsDir() {
 			fileContent, err := ioutil.ReadFile(path)
 			if err != nil {
@@ -68,6 +91,9 @@ func (ls *LocalStorage) readDirectory(dirPath string) ([]byte, error) {
 }
 
 func (ls *LocalStorage) Write(path string, data []byte) error {
+	if err := ls.checkContext(); err != nil {
+		return err
+	}
 	ls.logger.Debug("Writing file: %s", path)
 	fullPath := ls.getFullPath(path)
 	ls.logger.Debug("Full path for writing: %s", fullPath)
@@ -94,6 +120,9 @@ func (ls *LocalStorage) List(prefix string) ([]string, error) {
 
 	fullPath := ls.getFullPath(prefix)
 	ls.logger.Debug("Full path for listing: %s", fullPath)
+	if err := ls.checkContext(); err != nil {
+		return nil, err
+	}
 
 	var files []string
 	err := filepath.Walk(fullPath, func(path string, info os.FileInfo, err error) error {
@@ -162,6 +191,9 @@ func (ls *LocalStorage) IsDirectory(path string) (bool, error) {
 
 func (ls *LocalStorage) GetReader(path string) (io.ReadCloser, error) {
 	ls.logger.Debug("Getting reader for local file: %s", path)
+	if err := ls.checkContext(); err != nil {
+		return nil, err
+	}
 	fullPath := ls.getFullPath(path)
 	return os.Open(fullPath)
 }
@@ -0,0 +1,32 @@
+// internal/storage/local_test.go
+
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/chrlesur/Ontology/internal/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorageWithContext(t *testing.T) {
+	dir := t.TempDir()
+	localStorage := NewLocalStorage(dir, logger.GetLogger())
+	path := filepath.Join(dir, "out.tsv")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	bound := WithContext(ctx, localStorage)
+	assert.NoError(t, bound.Write(path, []byte("PSSI\tDocument")))
+
+	cancel()
+	assert.ErrorIs(t, bound.Write(path, []byte("RSSI\tRole")), context.Canceled)
+	_, err := bound.Read(path)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// Le stockage d'origine n'est pas lié au contexte annulé
+	content, err := localStorage.Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "PSSI\tDocument", string(content))
+}
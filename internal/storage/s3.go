@@ -35,6 +35,7 @@ type S3Storage struct {
 	client S3ClientInterface
 	bucket string
 	logger Logger
+	ctx    context.Context // contexte des requêtes, context.Background() si nil
 }
 
 type s3FileInfo struct {
@@ -76,6 +77,20 @@ func NewS3Storage(region, endpoint, accessKeyID, secretAccessKey string, logger
 	}, nil
 }
 
+// WithContext retourne une copie du stockage dont les requêtes en cours sont annulées avec ctx
+func (s *S3Storage) WithContext(ctx context.Context) Storage {
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+func (s *S3Storage) requestContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
 func (s *S3Storage) Read(path string) ([]byte, error) {
 	s.logger.Debug("Reading from S3: %s", path)
 	bucket, key, err := ParseS3URI(path)
@@ -85,7 +100,7 @@ func (s *S3Storage) Read(path string) ([]byte, error) {
 
 	s.logger.Debug("Parsed S3 path - Bucket: %s, Key: %s", bucket, key)
 
-	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+	result, err := s.client.GetObject(s.requestContext(), &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -113,7 +128,7 @@ func (s *S3Storage) Write(path string, data []byte) error {
 		return fmt.Errorf("failed to parse S3 URI: %w", err)
 	}
 
-	_, err = s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+	_, err = s.client.PutObject(s.requestContext(), &s3.PutObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(data),
@@ -146,7 +161,7 @@ func (s *S3Storage) List(prefix string) ([]string, error) {
 	domain := strings.Join(domainParts[:3], "/")
 
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+		page, err := paginator.NextPage(s.requestContext())
 		if err != nil {
 			s.logger.Error("Failed to list files in S3: %v", err)
 			return nil, fmt.Errorf("failed to list files in S3: %w", err)
@@ -164,7 +179,7 @@ func (s *S3Storage) List(prefix string) ([]string, error) {
 func (s *S3Storage) Delete(path string) error {
 	s.logger.Debug("Deleting file from S3: %s", path)
 
-	_, err := s.client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+	_, err := s.client.DeleteObject(s.requestContext(), &s3.DeleteObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path),
 	})
@@ -179,7 +194,7 @@ func (s *S3Storage) Delete(path string) error {
 func (s *S3Storage) Exists(path string) (bool, error) {
 	s.logger.Debug("Checking if file exists in S3: %s", path)
 
-	_, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+	_, err := s.client.HeadObject(s.requestContext(), &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path),
 	})
@@ -211,7 +226,7 @@ func (s *S3Storage) IsDirectory(path string) (bool, error) {
 
 	s.logger.Debug("Checking key %s on bucket %s for path %s", key, bucket, path)
 
-	result, err := s.client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+	result, err := s.client.ListObjectsV2(s.requestContext(), &s3.ListObjectsV2Input{
 		Bucket:    aws.String(bucket),
 		Prefix:    aws.String(key),
 		Delimiter: aws.String("/"),
@@ -228,7 +243,7 @@ func (s *S3Storage) IsDirectory(path string) (bool, error) {
 func (s *S3Storage) Stat(path string) (FileInfo, error) {
 	s.logger.Debug("Getting file info from S3: %s", path)
 
-	result, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+	result, err := s.client.HeadObject(s.requestContext(), &s3.HeadObjectInput{
 		Bucket: aws.String(s.bucket),
 		Key:    aws.String(path),
 	})
@@ -251,7 +266,7 @@ func (s *S3Storage) Stat(path string) (FileInfo, error) {
 func (s *S3Storage) ReadFromBucket(bucket, key string) ([]byte, error) {
 	s.logger.Debug("Reading file from S3: bucket=%s, key=%s", bucket, key)
 
-	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+	result, err := s.client.GetObject(s.requestContext(), &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -267,7 +282,7 @@ func (s *S3Storage) ReadFromBucket(bucket, key string) ([]byte, error) {
 func (s *S3Storage) StatObject(bucket, key string) (os.FileInfo, error) {
 	s.logger.Debug("Getting file info from S3 - Bucket: %s, Key: %s", bucket, key)
 
-	result, err := s.client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+	result, err := s.client.HeadObject(s.requestContext(), &s3.HeadObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -316,7 +331,7 @@ func (s *S3Storage) readS3Directory(bucket, prefix string) ([]byte, error) {
 	})
 
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(context.TODO())
+		page, err := paginator.NextPage(s.requestContext())
 		if err != nil {
 			return nil, fmt.Errorf("failed to list S3 objects: %w", err)
 		}
@@ -342,7 +357,7 @@ func (s *S3Storage) readS3Directory(bucket, prefix string) ([]byte, error) {
 }
 
 func (s *S3Storage) getS3ObjectContent(bucket, key string) ([]byte, error) {
-	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+	result, err := s.client.GetObject(s.requestContext(), &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
@@ -361,7 +376,7 @@ func (s *S3Storage) GetReader(path string) (io.ReadCloser, error) {
 		return nil, fmt.Errorf("failed to parse S3 URI: %w", err)
 	}
 
-	result, err := s.client.GetObject(context.TODO(), &s3.GetObjectInput{
+	result, err := s.client.GetObject(s.requestContext(), &s3.GetObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 	})
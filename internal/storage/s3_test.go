@@ -251,3 +251,29 @@ func TestS3StorageStat(t *testing.T) {
 	mockClient.AssertExpectations(t)
 	mockLogger.AssertExpectations(t)
 }
+
+func TestS3StorageWithContext(t *testing.T) {
+	mockClient := new(MockS3Client)
+	mockLogger := new(MockLogger)
+	mockLogger.On("Debug", mock.Anything, mock.Anything)
+	s3Storage := &S3Storage{
+		client: mockClient,
+		bucket: "test-bucket",
+		logger: mockLogger,
+	}
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "job")
+	mockClient.On("GetObject", mock.MatchedBy(func(requestCtx context.Context) bool {
+		return requestCtx.Value(key{}) == "job"
+	}), mock.Anything, mock.Anything).Return(
+		&s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader([]byte("test content")))},
+		nil,
+	)
+
+	content, err := WithContext(ctx, s3Storage).Read("s3://s3.example.com/test-bucket/test-file.txt")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("test content"), content)
+	mockClient.AssertExpectations(t)
+}
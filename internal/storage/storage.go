@@ -3,6 +3,7 @@
 package storage
 
 import (
+	"context"
 	"github.com/chrlesur/Ontology/internal/logger"
 	"os"
 	"io"
@@ -56,6 +57,22 @@ type Storage interface {
 
 }
 
+// ContextStorage est implémentée par les stockages dont les opérations peuvent être liées à un contexte
+type ContextStorage interface {
+	Storage
+
+	// WithContext retourne une copie du stockage dont les opérations sont annulées avec ctx
+	WithContext(ctx context.Context) Storage
+}
+
+// WithContext lie les opérations d'un stockage à ctx lorsqu'il le permet, et le retourne tel quel sinon
+func WithContext(ctx context.Context, s Storage) Storage {
+	if contextStorage, ok := s.(ContextStorage); ok {
+		return contextStorage.WithContext(ctx)
+	}
+	return s
+}
+
 // Constantes pour les types de stockage
 const (
 	LocalStorageType = "local"